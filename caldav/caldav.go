@@ -0,0 +1,395 @@
+// Package caldav reads and writes the XML of WebDAV (RFC 4918) and CalDAV
+// (RFC 4791): PROPFIND, PROPPATCH and REPORT request bodies and multistatus
+// responses. Which resources exist and what their properties are is up to
+// the caller.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// Properties and other elements the server uses.
+var (
+	ResourceType           = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	DisplayName            = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	GetETag                = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	GetContentType         = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	GetLastModified        = xml.Name{Space: NamespaceDAV, Local: "getlastmodified"}
+	CurrentUserPrincipal   = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	CurrentUserPrivileges  = xml.Name{Space: NamespaceDAV, Local: "current-user-privilege-set"}
+	PrincipalURL           = xml.Name{Space: NamespaceDAV, Local: "principal-URL"}
+	Owner                  = xml.Name{Space: NamespaceDAV, Local: "owner"}
+	SupportedReportSet     = xml.Name{Space: NamespaceDAV, Local: "supported-report-set"}
+	Collection             = xml.Name{Space: NamespaceDAV, Local: "collection"}
+	Principal              = xml.Name{Space: NamespaceDAV, Local: "principal"}
+	SupportedReport        = xml.Name{Space: NamespaceDAV, Local: "supported-report"}
+	CalendarHomeSet        = xml.Name{Space: NamespaceCalDAV, Local: "calendar-home-set"}
+	CalendarUserAddressSet = xml.Name{Space: NamespaceCalDAV, Local: "calendar-user-address-set"}
+	SupportedComponentSet  = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	CalendarData           = xml.Name{Space: NamespaceCalDAV, Local: "calendar-data"}
+	Calendar               = xml.Name{Space: NamespaceCalDAV, Local: "calendar"}
+	CalendarMultiget       = xml.Name{Space: NamespaceCalDAV, Local: "calendar-multiget"}
+	CalendarQuery          = xml.Name{Space: NamespaceCalDAV, Local: "calendar-query"}
+	ValidCalendarData      = xml.Name{Space: NamespaceCalDAV, Local: "valid-calendar-data"}
+	NoUIDConflict          = xml.Name{Space: NamespaceCalDAV, Local: "no-uid-conflict"}
+	GetCTag                = xml.Name{Space: NamespaceCalendarServer, Local: "getctag"}
+)
+
+var prefixes = map[string]string{
+	NamespaceDAV:            "d",
+	NamespaceCalDAV:         "c",
+	NamespaceCalendarServer: "cs",
+}
+
+// node is a parsed XML element.
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []node     `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+func (n node) child(name xml.Name) (node, bool) {
+	for _, child := range n.Children {
+		if child.XMLName == name {
+			return child, true
+		}
+	}
+	return node{}, false
+}
+
+func (n node) attr(local string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func (n node) names() []xml.Name {
+	names := make([]xml.Name, len(n.Children))
+	for i, child := range n.Children {
+		names[i] = child.XMLName
+	}
+	return names
+}
+
+func parse(body []byte) (node, error) {
+	var root node
+	if err := xml.NewDecoder(bytes.NewReader(body)).Decode(&root); err != nil {
+		return node{}, fmt.Errorf("invalid XML: %w", err)
+	}
+	return root, nil
+}
+
+// Propfind is a PROPFIND request: all properties, only their names, or the
+// listed ones.
+type Propfind struct {
+	AllProp  bool
+	PropName bool
+	Props    []xml.Name
+}
+
+// ParsePropfind reads a PROPFIND body. An empty body asks for all
+// properties.
+func ParsePropfind(body []byte) (Propfind, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return Propfind{AllProp: true}, nil
+	}
+	root, err := parse(body)
+	if err != nil {
+		return Propfind{}, err
+	}
+	if root.XMLName != (xml.Name{Space: NamespaceDAV, Local: "propfind"}) {
+		return Propfind{}, errors.New("expected a propfind element")
+	}
+	if _, ok := root.child(xml.Name{Space: NamespaceDAV, Local: "propname"}); ok {
+		return Propfind{PropName: true}, nil
+	}
+	if prop, ok := root.child(xml.Name{Space: NamespaceDAV, Local: "prop"}); ok {
+		return Propfind{Props: prop.names()}, nil
+	}
+	return Propfind{AllProp: true}, nil
+}
+
+// ParseProppatch returns the names of the properties a PROPPATCH sets or
+// removes.
+func ParseProppatch(body []byte) ([]xml.Name, error) {
+	root, err := parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if root.XMLName != (xml.Name{Space: NamespaceDAV, Local: "propertyupdate"}) {
+		return nil, errors.New("expected a propertyupdate element")
+	}
+	var names []xml.Name
+	for _, update := range root.Children {
+		if prop, ok := update.child(xml.Name{Space: NamespaceDAV, Local: "prop"}); ok {
+			names = append(names, prop.names()...)
+		}
+	}
+	return names, nil
+}
+
+// Report is a REPORT request. Name is the report asked for; only
+// calendar-multiget and calendar-query are read further.
+type Report struct {
+	Name   xml.Name
+	Props  []xml.Name
+	Hrefs  []string
+	Filter Filter
+}
+
+// Filter is the part of a calendar-query filter a task list can answer: the
+// component wanted, a time range and whether completed tasks are wanted.
+// Other conditions are ignored, so results may include more than asked for.
+type Filter struct {
+	Component  string
+	Start, End time.Time
+	Completed  *bool
+}
+
+// ParseReport reads a REPORT body.
+func ParseReport(body []byte) (Report, error) {
+	root, err := parse(body)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Name: root.XMLName}
+	if prop, ok := root.child(xml.Name{Space: NamespaceDAV, Local: "prop"}); ok {
+		report.Props = prop.names()
+	}
+
+	switch root.XMLName {
+	case CalendarMultiget:
+		for _, child := range root.Children {
+			if child.XMLName == (xml.Name{Space: NamespaceDAV, Local: "href"}) {
+				report.Hrefs = append(report.Hrefs, strings.TrimSpace(child.Text))
+			}
+		}
+	case CalendarQuery:
+		filter, _ := root.child(xml.Name{Space: NamespaceCalDAV, Local: "filter"})
+		calendar, ok := filter.child(xml.Name{Space: NamespaceCalDAV, Local: "comp-filter"})
+		if !ok {
+			break
+		}
+		component, ok := calendar.child(xml.Name{Space: NamespaceCalDAV, Local: "comp-filter"})
+		if !ok {
+			break
+		}
+		if report.Filter, err = parseComponentFilter(component); err != nil {
+			return Report{}, err
+		}
+	}
+	return report, nil
+}
+
+func parseComponentFilter(component node) (Filter, error) {
+	filter := Filter{Component: strings.ToUpper(component.attr("name"))}
+	for _, child := range component.Children {
+		switch child.XMLName {
+		case xml.Name{Space: NamespaceCalDAV, Local: "time-range"}:
+			for _, bound := range []struct {
+				attr string
+				dest *time.Time
+			}{{"start", &filter.Start}, {"end", &filter.End}} {
+				if value := child.attr(bound.attr); value != "" {
+					t, err := time.Parse("20060102T150405Z", value)
+					if err != nil {
+						return Filter{}, fmt.Errorf("invalid time-range %s %q", bound.attr, value)
+					}
+					*bound.dest = t
+				}
+			}
+		case xml.Name{Space: NamespaceCalDAV, Local: "prop-filter"}:
+			if completed, ok := completedFilter(child); ok {
+				filter.Completed = &completed
+			}
+		}
+	}
+	return filter, nil
+}
+
+// completedFilter recognises the two ways clients ask for open or completed
+// tasks: whether COMPLETED is defined, and matching STATUS against
+// COMPLETED.
+func completedFilter(prop node) (bool, bool) {
+	switch strings.ToUpper(prop.attr("name")) {
+	case "COMPLETED":
+		_, undefined := prop.child(xml.Name{Space: NamespaceCalDAV, Local: "is-not-defined"})
+		return !undefined, true
+	case "STATUS":
+		match, ok := prop.child(xml.Name{Space: NamespaceCalDAV, Local: "text-match"})
+		if !ok || !strings.EqualFold(strings.TrimSpace(match.Text), "COMPLETED") {
+			return false, false
+		}
+		return !strings.EqualFold(match.attr("negate-condition"), "yes"), true
+	}
+	return false, false
+}
+
+// Property is a property and its value as XML content, already escaped.
+type Property struct {
+	Name  xml.Name
+	Value string
+}
+
+// Response is one resource in a multistatus. A non-zero Status reports the
+// resource itself as failed, such as 404 for an unknown href; otherwise its
+// properties are listed as found, not found or forbidden.
+type Response struct {
+	Href      string
+	Status    int
+	Found     []Property
+	NotFound  []xml.Name
+	Forbidden []xml.Name
+}
+
+// Select answers a PROPFIND for a resource with the given properties.
+// Properties listed in hidden are only returned when asked for by name.
+func Select(href string, props map[xml.Name]string, find Propfind, hidden ...xml.Name) Response {
+	response := Response{Href: href}
+	if !find.AllProp && !find.PropName {
+		for _, name := range find.Props {
+			if value, ok := props[name]; ok {
+				response.Found = append(response.Found, Property{Name: name, Value: value})
+			} else {
+				response.NotFound = append(response.NotFound, name)
+			}
+		}
+		return response
+	}
+
+	names := make([]xml.Name, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Space != names[j].Space {
+			return names[i].Space < names[j].Space
+		}
+		return names[i].Local < names[j].Local
+	})
+	for _, name := range names {
+		switch {
+		case find.PropName:
+			response.Found = append(response.Found, Property{Name: name})
+		case !isHidden(name, hidden):
+			response.Found = append(response.Found, Property{Name: name, Value: props[name]})
+		}
+	}
+	return response
+}
+
+func isHidden(name xml.Name, hidden []xml.Name) bool {
+	for _, h := range hidden {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteMultistatus writes a 207 Multi-Status response.
+func WriteMultistatus(w http.ResponseWriter, responses []Response) error {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + NamespaceCalDAV + `" xmlns:cs="` + NamespaceCalendarServer + `">`)
+	for _, response := range responses {
+		b.WriteString("<d:response>")
+		b.WriteString(Href(response.Href))
+		if response.Status != 0 {
+			b.WriteString(status(response.Status))
+		} else {
+			writePropstat(&b, response.Found, http.StatusOK)
+			writePropstat(&b, properties(response.NotFound), http.StatusNotFound)
+			writePropstat(&b, properties(response.Forbidden), http.StatusForbidden)
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>\n")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func properties(names []xml.Name) []Property {
+	props := make([]Property, len(names))
+	for i, name := range names {
+		props[i] = Property{Name: name}
+	}
+	return props
+}
+
+func writePropstat(b *bytes.Buffer, props []Property, code int) {
+	if len(props) == 0 {
+		return
+	}
+	b.WriteString("<d:propstat><d:prop>")
+	for _, prop := range props {
+		b.WriteString(Element(prop.Name, prop.Value))
+	}
+	b.WriteString("</d:prop>")
+	b.WriteString(status(code))
+	b.WriteString("</d:propstat>")
+}
+
+func status(code int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
+
+// WriteError writes a precondition or postcondition failure. message, if
+// set, is put inside the condition element for people reading the response.
+func WriteError(w http.ResponseWriter, code int, condition xml.Name, message string) error {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	_, err := io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+		`<d:error xmlns:d="DAV:" xmlns:c="`+NamespaceCalDAV+`" xmlns:cs="`+NamespaceCalendarServer+`">`+
+		Element(condition, Escape(message))+"</d:error>\n")
+	return err
+}
+
+// Element renders an element with already escaped content.
+func Element(name xml.Name, content string) string {
+	start, end := name.Local, name.Local
+	if prefix, ok := prefixes[name.Space]; ok {
+		start = prefix + ":" + name.Local
+		end = start
+	} else if name.Space != "" {
+		start = "x:" + name.Local + ` xmlns:x="` + Escape(name.Space) + `"`
+		end = "x:" + name.Local
+	}
+	if content == "" {
+		return "<" + start + "/>"
+	}
+	return "<" + start + ">" + content + "</" + end + ">"
+}
+
+// Href renders a DAV:href.
+func Href(href string) string {
+	return Element(xml.Name{Space: NamespaceDAV, Local: "href"}, Escape(href))
+}
+
+// Escape escapes text content.
+func Escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
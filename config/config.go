@@ -1,32 +1,134 @@
+// Package config holds the application settings. Load reads them once at
+// startup, from a YAML or TOML file, then environment variables, then
+// command-line flags, each overriding the last, and validates the result.
+// The same name is used everywhere: DB_HOST is the environment variable,
+// db_host in a file and -db-host on the command line.
 package config
 
-import "os"
+import "time"
+
+// Development is the APP_ENV in which insecure defaults are allowed.
+const Development = "development"
+
+// DefaultJWTSecret is the JWT_SECRET used when none is configured. Anyone can
+// forge tokens signed with it, so it is refused outside development.
+const DefaultJWTSecret = "secret_key"
 
 type Config struct {
-	PORT        string
-	DB_HOST     string
-	DB_PORT     string
-	DB_USER     string
-	DB_PASSWORD string
-	DB_NAME     string
-	JWT_SECRET  string
+	// APP_ENV is development or the name of a deployment, such as
+	// production. Outside development insecure defaults are refused.
+	APP_ENV string
+	PORT    string
+	// HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT and
+	// HTTP_IDLE_TIMEOUT bound how long a client may take to send a
+	// request, how long a response may take, and how long an idle
+	// keep-alive connection stays open. Event streams are exempt.
+	HTTP_READ_HEADER_TIMEOUT time.Duration
+	HTTP_READ_TIMEOUT        time.Duration
+	HTTP_WRITE_TIMEOUT       time.Duration
+	HTTP_IDLE_TIMEOUT        time.Duration
+	// METRICS_ADDR is the address, such as :9090, of a separate listener
+	// serving Prometheus metrics on /metrics. If it is empty and
+	// METRICS_TOKEN is set, they are served on PORT instead. Either way
+	// scrapers must send METRICS_TOKEN, if set, as a bearer token.
+	METRICS_ADDR  string
+	METRICS_TOKEN string
+	// TRACING_EXPORTER sends OpenTelemetry traces to an OTLP/HTTP collector
+	// at TRACING_OTLP_ENDPOINT, such as http://collector:4318, when otlp,
+	// or prints them when stdout. Empty turns tracing off.
+	// TRACING_SAMPLE_RATIO is the share of new traces kept, from 0 to 1.
+	TRACING_EXPORTER      string
+	TRACING_OTLP_ENDPOINT string
+	TRACING_SAMPLE_RATIO  float64
+	TRACING_SERVICE_NAME  string
+	// SHUTDOWN_TIMEOUT is how long SIGTERM or SIGINT waits for requests
+	// in flight and background work to finish before exiting anyway.
+	SHUTDOWN_TIMEOUT time.Duration
+	DB_HOST          string
+	DB_PORT          string
+	DB_USER          string
+	DB_PASSWORD      string
+	DB_NAME          string
+	// DB_DRIVER is postgres, configured by the DB_* settings above, or
+	// sqlite, a single file at DB_PATH.
+	DB_DRIVER string
+	DB_PATH   string
+	// DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS bound the connection pool;
+	// DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME close connections that
+	// are older or idle for longer. 0 is no limit.
+	DB_MAX_OPEN_CONNS     int
+	DB_MAX_IDLE_CONNS     int
+	DB_CONN_MAX_LIFETIME  time.Duration
+	DB_CONN_MAX_IDLE_TIME time.Duration
+	// DB_CONNECT_TIMEOUT is how long startup keeps retrying while the
+	// database is not accepting connections.
+	DB_CONNECT_TIMEOUT time.Duration
+	// DB_STATEMENT_TIMEOUT cancels statements that run longer; statements
+	// made for a request are also cancelled when the client goes away.
+	DB_STATEMENT_TIMEOUT time.Duration
+	// DB_READ_REPLICAS are host[:port] Postgres replicas, sharing the
+	// primary's credentials, that serve task lists and searches. As a
+	// single value it is comma-separated.
+	DB_READ_REPLICAS []string
+	JWT_SECRET       string
+	SMTP_HOST        string
+	SMTP_PORT        string
+	SMTP_USER        string
+	SMTP_PASSWORD    string
+	SMTP_FROM        string
+	EVENT_BROKER     string
+	// STREAM_ALLOWED_ORIGINS are the origins, such as
+	// https://app.example.com, of web pages besides the API's own that may
+	// open the task WebSocket. As a single value it is comma-separated.
+	STREAM_ALLOWED_ORIGINS []string
+	// IDEMPOTENCY_TTL is how long responses to requests with an
+	// Idempotency-Key are kept for replay.
+	IDEMPOTENCY_TTL time.Duration
+	// AUTO_MIGRATE applies pending schema migrations at startup. Turn it
+	// off to run `migrate up` as a separate deployment step instead.
+	AUTO_MIGRATE bool
 }
 
-func LoadConfig() *Config {
-	return &Config{
-		PORT:        getEnv("PORT", "8080"),
-		DB_HOST:     getEnv("DB_HOST", "localhost"),
-		DB_PORT:     getEnv("DB_PORT", "5432"),
-		DB_USER:     getEnv("DB_USER", "postgres"),
-		DB_PASSWORD: getEnv("DB_PASSWORD", "postgres"),
-		DB_NAME:     getEnv("DB_NAME", "db"),
-		JWT_SECRET:  getEnv("JWT_SECRET", "secret_key"),
-	}
+// secrets are the settings that may also be read from a file named by
+// <NAME>_FILE, as Docker and Kubernetes mount secrets.
+var secrets = map[string]bool{
+	"DB_PASSWORD":   true,
+	"JWT_SECRET":    true,
+	"METRICS_TOKEN": true,
+	"SMTP_PASSWORD": true,
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Default returns the settings used where nothing overrides them.
+func Default() *Config {
+	return &Config{
+		APP_ENV:                  "production",
+		PORT:                     "8080",
+		HTTP_READ_HEADER_TIMEOUT: 10 * time.Second,
+		HTTP_READ_TIMEOUT:        time.Minute,
+		HTTP_WRITE_TIMEOUT:       time.Minute,
+		HTTP_IDLE_TIMEOUT:        2 * time.Minute,
+		METRICS_ADDR:             ":9090",
+		TRACING_SAMPLE_RATIO:     1,
+		TRACING_SERVICE_NAME:     "to_do_api",
+		SHUTDOWN_TIMEOUT:         30 * time.Second,
+		DB_HOST:                  "localhost",
+		DB_PORT:                  "5432",
+		DB_USER:                  "postgres",
+		DB_PASSWORD:              "postgres",
+		DB_NAME:                  "db",
+		DB_DRIVER:                "postgres",
+		DB_PATH:                  "to_do.db",
+		DB_MAX_OPEN_CONNS:        25,
+		DB_MAX_IDLE_CONNS:        10,
+		DB_CONN_MAX_LIFETIME:     30 * time.Minute,
+		DB_CONN_MAX_IDLE_TIME:    5 * time.Minute,
+		DB_CONNECT_TIMEOUT:       time.Minute,
+		DB_STATEMENT_TIMEOUT:     30 * time.Second,
+		JWT_SECRET:               DefaultJWTSecret,
+		SMTP_PORT:                "587",
+		SMTP_FROM:                "no-reply@localhost",
+		EVENT_BROKER:             "memory",
+		IDEMPOTENCY_TTL:          24 * time.Hour,
+		AUTO_MIGRATE:             true,
 	}
-	return defaultValue
 }
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from args, the command line without the
+// program name, and getenv, usually os.Getenv, and validates it. It returns
+// the arguments left after the flags, such as a subcommand. The settings
+// file is named by -config or CONFIG_FILE; environment variables that are
+// empty count as unset.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	flagValues := map[string]string{}
+	flags := flag.NewFlagSet("to_do_api", flag.ContinueOnError)
+	path := flags.String("config", getenv("CONFIG_FILE"), "read settings from this YAML or TOML `file`")
+	for _, name := range names() {
+		flags.Var(&flagValue{name: name, values: flagValues}, flagName(name), "overrides `"+name+"`")
+		if secrets[name] {
+			flags.Var(&flagValue{name: name + "_FILE", values: flagValues}, flagName(name+"_FILE"), "reads "+name+" from this `file`")
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		values, err := readFile(*path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cfg.apply(*path, values); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	env := map[string]string{}
+	for _, name := range names() {
+		keys := []string{name}
+		if secrets[name] {
+			keys = append(keys, name+"_FILE")
+		}
+		for _, key := range keys {
+			if value := getenv(key); value != "" {
+				env[key] = value
+			}
+		}
+	}
+	if err := cfg.apply("environment", env); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.apply("command line", flagValues); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// names lists the settings, which are the fields of Config.
+func names() []string {
+	configType := reflect.TypeOf(Config{})
+	names := make([]string, configType.NumField())
+	for i := range names {
+		names[i] = configType.Field(i).Name
+	}
+	return names
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// flagValue collects a flag's value so flags can be applied after the file
+// and environment they override.
+type flagValue struct {
+	name   string
+	values map[string]string
+}
+
+func (f *flagValue) String() string {
+	return ""
+}
+
+func (f *flagValue) Set(value string) error {
+	f.values[f.name] = value
+	return nil
+}
+
+// IsBoolFlag lets -auto-migrate stand for -auto-migrate=true.
+func (f *flagValue) IsBoolFlag() bool {
+	field, ok := reflect.TypeOf(Config{}).FieldByName(f.name)
+	return ok && field.Type.Kind() == reflect.Bool
+}
+
+// apply sets the settings in values, which come from source. A secret's
+// <NAME>_FILE key is replaced by the contents of the file it names.
+func (c *Config) apply(source string, values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, value := key, values[key]
+		if base, ok := strings.CutSuffix(key, "_FILE"); ok && secrets[base] {
+			if _, both := values[base]; both {
+				return fmt.Errorf("%s: set %s or %s, not both", source, base, key)
+			}
+			contents, err := os.ReadFile(value)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", source, key, err)
+			}
+			name, value = base, strings.TrimRight(string(contents), "\r\n")
+		}
+		if err := c.set(name, value); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// set parses value into the setting name.
+func (c *Config) set(name, value string) error {
+	if _, ok := reflect.TypeOf(Config{}).FieldByName(name); !ok {
+		return fmt.Errorf("unknown setting %s", name)
+	}
+
+	field := reflect.ValueOf(c).Elem().FieldByName(name)
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: want a whole number", name, value)
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: want a number", name, value)
+		}
+		field.SetFloat(f)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: want true or false", name, value)
+		}
+		field.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: want a duration such as 30s", name, value)
+		}
+		field.SetInt(int64(d))
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	}
+	return nil
+}
+
+// readFile reads a flat YAML or TOML settings file, chosen by extension,
+// whose keys are the setting names in lower case. Lists become
+// comma-separated values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%s: settings file must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		switch value := value.(type) {
+		case nil:
+			values[name] = ""
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("%s: %s must be a single value or a list", path, key)
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted outside
+// development: 32 bytes, the size of the HS256 key.
+const minJWTSecretLength = 32
+
+// Validate reports every setting that is invalid, or insecure outside
+// development.
+func (c *Config) Validate() error {
+	var problems []error
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.APP_ENV == "" {
+		problem("APP_ENV must be set")
+	}
+	if port, err := strconv.Atoi(c.PORT); err != nil || port < 1 || port > 65535 {
+		problem("invalid PORT %q: want a port number", c.PORT)
+	}
+	if c.HTTP_READ_HEADER_TIMEOUT < 0 || c.HTTP_READ_TIMEOUT < 0 || c.HTTP_WRITE_TIMEOUT < 0 || c.HTTP_IDLE_TIMEOUT < 0 {
+		problem("HTTP_* timeouts cannot be negative")
+	}
+	if c.METRICS_ADDR != "" && c.METRICS_ADDR == ":"+c.PORT {
+		problem("METRICS_ADDR must differ from PORT; to serve metrics on PORT, unset it and set METRICS_TOKEN")
+	}
+	switch c.TRACING_EXPORTER {
+	case "", "otlp", "stdout":
+	default:
+		problem("unknown TRACING_EXPORTER %q: use otlp or stdout, or leave it empty", c.TRACING_EXPORTER)
+	}
+	if c.TRACING_SAMPLE_RATIO < 0 || c.TRACING_SAMPLE_RATIO > 1 {
+		problem("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	if c.SHUTDOWN_TIMEOUT <= 0 {
+		problem("SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	switch c.DB_DRIVER {
+	case "postgres":
+	case "sqlite":
+		if c.DB_PATH == "" {
+			problem("DB_DRIVER=sqlite needs DB_PATH")
+		}
+		if len(c.DB_READ_REPLICAS) > 0 {
+			problem("DB_READ_REPLICAS needs DB_DRIVER=postgres")
+		}
+	default:
+		problem("unknown DB_DRIVER %q: use postgres or sqlite", c.DB_DRIVER)
+	}
+	if c.DB_MAX_OPEN_CONNS < 0 || c.DB_MAX_IDLE_CONNS < 0 {
+		problem("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS cannot be negative")
+	}
+	if c.DB_CONN_MAX_LIFETIME < 0 || c.DB_CONN_MAX_IDLE_TIME < 0 || c.DB_CONNECT_TIMEOUT < 0 || c.DB_STATEMENT_TIMEOUT < 0 {
+		problem("DB_* durations cannot be negative")
+	}
+
+	switch c.EVENT_BROKER {
+	case "memory":
+	case "postgres":
+		if c.DB_DRIVER != "postgres" {
+			problem("EVENT_BROKER=postgres needs DB_DRIVER=postgres")
+		}
+	default:
+		problem("unknown EVENT_BROKER %q: use memory or postgres", c.EVENT_BROKER)
+	}
+	for _, origin := range c.STREAM_ALLOWED_ORIGINS {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			problem("invalid STREAM_ALLOWED_ORIGINS entry %q: want an origin such as https://app.example.com", origin)
+		}
+	}
+	if c.IDEMPOTENCY_TTL <= 0 {
+		problem("IDEMPOTENCY_TTL must be positive")
+	}
+
+	if c.JWT_SECRET == "" {
+		problem("JWT_SECRET must be set")
+	}
+	if c.APP_ENV != Development {
+		if c.JWT_SECRET == DefaultJWTSecret {
+			problem("JWT_SECRET is the built-in default, which anyone can use to forge tokens; set a random secret, or APP_ENV=%s", Development)
+		} else if c.JWT_SECRET != "" && len(c.JWT_SECRET) < minJWTSecretLength {
+			problem("JWT_SECRET must be at least %d bytes outside development", minJWTSecretLength)
+		}
+		if c.DB_DRIVER == "postgres" && c.DB_PASSWORD == Default().DB_PASSWORD {
+			problem("DB_PASSWORD is the built-in default; set it, or APP_ENV=%s", Development)
+		}
+	}
+
+	return errors.Join(problems...)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"to_do_api/auth"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateAppPassword(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		var input struct {
+			Name string `json:"name" binding:"required,max=100"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		password, err := generateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		appPassword := models.AppPassword{
+			UserID:       userID,
+			Name:         input.Name,
+			PasswordHash: auth.HashSecret(password),
+		}
+		if err := db.Create(&appPassword).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create app password"})
+			return
+		}
+
+		// Like a webhook secret, the password is only ever shown once.
+		c.JSON(http.StatusCreated, gin.H{
+			"app_password": appPassword,
+			"password":     password,
+		})
+	}
+}
+
+func ListAppPasswords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		var appPasswords []models.AppPassword
+		if err := db.Where("user_id = ?", userID).Order("created_at").Find(&appPasswords).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch app passwords"})
+			return
+		}
+
+		c.JSON(http.StatusOK, appPasswords)
+	}
+}
+
+func DeleteAppPassword(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid app password ID"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.AppPassword{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete app password"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "App password not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "App password deleted successfully"})
+	}
+}
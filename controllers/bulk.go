@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxBulkTasks = 1000
+
+	bulkCreate = "create"
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+
+	bulkStatusOK         = "ok"
+	bulkStatusError      = "error"
+	bulkStatusSkipped    = "skipped"
+	bulkStatusRolledBack = "rolled_back"
+)
+
+// errDryRun rolls back a transaction that ran only to report its effects.
+var errDryRun = errors.New("dry run")
+
+type bulkRequest struct {
+	// Atomic runs every operation in one transaction; the first failure
+	// rolls back the whole batch. Otherwise each operation commits on its
+	// own and failures are reported per item.
+	Atomic     *bool           `json:"atomic"`
+	DryRun     bool            `json:"dry_run"`
+	Operations []bulkOperation `json:"operations" binding:"required,min=1,max=100"`
+}
+
+// bulkFilterKeys are the ListTasks query parameters a filter may use.
+var bulkFilterKeys = []string{"status", "due_before", "due_after", "has_due_date", "q"}
+
+// bulkOperation targets tasks by IDs, by a filter using the ListTasks query
+// parameters, e.g. {"status": "false"}, or, with All, every task. A filter
+// needs at least one criterion, so that a mistyped one cannot select every
+// task.
+type bulkOperation struct {
+	Op     string                     `json:"op" binding:"required,oneof=create update delete"`
+	Task   *service.TaskInput         `json:"task"`
+	IDs    []uuid.UUID                `json:"ids"`
+	Filter map[string]string          `json:"filter"`
+	All    bool                       `json:"all"`
+	Set    map[string]json.RawMessage `json:"set"`
+}
+
+type bulkResult struct {
+	Index   int         `json:"index"`
+	Op      string      `json:"op"`
+	Status  string      `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	TaskIDs []uuid.UUID `json:"task_ids"`
+	// Changes is reported for dry runs.
+	Changes []bulkChange `json:"changes,omitempty"`
+}
+
+// bulkChange is what an operation did, or in a dry run would do, to one
+// task.
+type bulkChange struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Change string    `json:"change"`
+	// Task is the task a create makes or a delete removes.
+	Task *models.Task `json:"task,omitempty"`
+	// Fields holds each field an update changes.
+	Fields map[string]bulkFieldChange `json:"fields,omitempty"`
+}
+
+type bulkFieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// bulkOutcome is what running one operation produced.
+type bulkOutcome struct {
+	taskIDs []uuid.UUID
+	events  []models.TaskEvent
+	changes []bulkChange
+}
+
+func BulkTasks(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input bulkRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		atomic := input.Atomic == nil || *input.Atomic
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		results := make([]bulkResult, len(input.Operations))
+		for i, op := range input.Operations {
+			results[i] = bulkResult{Index: i, Op: op.Op, Status: bulkStatusSkipped, TaskIDs: []uuid.UUID{}}
+		}
+
+		var recorded []models.TaskEvent
+		failed := false
+
+		if atomic {
+			err := tasks.Transaction(c.Request.Context(), func(ctx context.Context) error {
+				for i, op := range input.Operations {
+					outcome, err := runBulkOperation(ctx, tasks, userID, op)
+					if err != nil {
+						results[i].Status = bulkStatusError
+						results[i].Error = err.Error()
+						return err
+					}
+					results[i].Status = bulkStatusOK
+					results[i].TaskIDs = outcome.taskIDs
+					if input.DryRun {
+						results[i].Changes = outcome.changes
+					}
+					recorded = append(recorded, outcome.events...)
+				}
+				if input.DryRun {
+					return errDryRun
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errDryRun) {
+				failed = true
+				recorded = nil
+				for i := range results {
+					if results[i].Status == bulkStatusOK {
+						results[i].Status = bulkStatusRolledBack
+					}
+				}
+			}
+		} else {
+			for i, op := range input.Operations {
+				var opEvents []models.TaskEvent
+				err := tasks.Transaction(c.Request.Context(), func(ctx context.Context) error {
+					outcome, err := runBulkOperation(ctx, tasks, userID, op)
+					if err != nil {
+						return err
+					}
+					results[i].TaskIDs = outcome.taskIDs
+					opEvents = outcome.events
+					if input.DryRun {
+						results[i].Changes = outcome.changes
+						return errDryRun
+					}
+					return nil
+				})
+				if err != nil && !errors.Is(err, errDryRun) {
+					results[i].Status = bulkStatusError
+					results[i].Error = err.Error()
+					failed = true
+					continue
+				}
+				results[i].Status = bulkStatusOK
+				recorded = append(recorded, opEvents...)
+			}
+		}
+
+		if !input.DryRun {
+			publishTaskEvents(c, broker, recorded...)
+		}
+
+		status := http.StatusOK
+		if failed && atomic {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"atomic":  atomic,
+			"dry_run": input.DryRun,
+			"results": results,
+		})
+	}
+}
+
+func runBulkOperation(ctx context.Context, tasks *service.TaskService, userID uuid.UUID, op bulkOperation) (bulkOutcome, error) {
+	var outcome bulkOutcome
+	if op.Op == bulkCreate {
+		if op.Task == nil || strings.TrimSpace(op.Task.Title) == "" {
+			return outcome, errors.New("create requires a task with a title")
+		}
+		if err := op.Task.Normalize(); err != nil {
+			return outcome, err
+		}
+		task, recorded, err := tasks.Insert(ctx, service.NewTask(userID, *op.Task))
+		if err != nil {
+			return outcome, err
+		}
+		outcome.taskIDs = []uuid.UUID{task.ID}
+		outcome.events = recorded
+		outcome.changes = []bulkChange{{TaskID: task.ID, Change: bulkCreate, Task: &task}}
+		return outcome, nil
+	}
+
+	targets, err := selectBulkTargets(ctx, tasks, userID, op)
+	if err != nil {
+		return outcome, err
+	}
+
+	if op.Op == bulkDelete {
+		targets = subtasksFirst(targets)
+	}
+
+	var set func(*service.TaskInput)
+	if op.Op == bulkUpdate {
+		if set, err = parseBulkSet(op.Set); err != nil {
+			return outcome, err
+		}
+	}
+
+	outcome.taskIDs = make([]uuid.UUID, 0, len(targets))
+	for _, task := range targets {
+		switch op.Op {
+		case bulkUpdate:
+			input := service.InputFromTask(task)
+			set(&input)
+			if err := input.Normalize(); err != nil {
+				return outcome, err
+			}
+			updated, taskEvents, err := tasks.Update(ctx, userID, task, input)
+			if err != nil {
+				return outcome, err
+			}
+			outcome.events = append(outcome.events, taskEvents...)
+			outcome.changes = append(outcome.changes, bulkChange{TaskID: task.ID, Change: bulkUpdate, Fields: changedFields(task, updated)})
+		case bulkDelete:
+			taskEvents, err := tasks.Delete(ctx, userID, task)
+			if err != nil {
+				return outcome, err
+			}
+			removed := task
+			outcome.events = append(outcome.events, taskEvents...)
+			outcome.changes = append(outcome.changes, bulkChange{TaskID: task.ID, Change: bulkDelete, Task: &removed})
+		}
+		outcome.taskIDs = append(outcome.taskIDs, task.ID)
+	}
+	return outcome, nil
+}
+
+// subtasksFirst orders targets so that each comes after its subtasks among
+// them, since a task cannot be deleted before its subtasks are.
+func subtasksFirst(targets []models.Task) []models.Task {
+	byID := make(map[uuid.UUID]models.Task, len(targets))
+	for _, task := range targets {
+		byID[task.ID] = task
+	}
+	depth := func(task models.Task) int {
+		n := 0
+		for task.ParentID != nil {
+			parent, ok := byID[*task.ParentID]
+			if !ok {
+				break
+			}
+			task = parent
+			n++
+		}
+		return n
+	}
+
+	ordered := slices.Clone(targets)
+	slices.SortStableFunc(ordered, func(a, b models.Task) int { return depth(b) - depth(a) })
+	return ordered
+}
+
+// changedFields compares the client-writable fields of a task before and
+// after an update. No labels and an empty list of them are the same.
+func changedFields(before, after models.Task) map[string]bulkFieldChange {
+	var from, to map[string]json.RawMessage
+	beforeJSON, _ := json.Marshal(before)
+	afterJSON, _ := json.Marshal(after)
+	_ = json.Unmarshal(beforeJSON, &from)
+	_ = json.Unmarshal(afterJSON, &to)
+
+	fields := map[string]bulkFieldChange{}
+	for field, value := range to {
+		if slices.Contains(readOnlyTaskFields, field) || bytes.Equal(emptyList(from[field]), emptyList(value)) {
+			continue
+		}
+		fields[field] = bulkFieldChange{From: from[field], To: value}
+	}
+	return fields
+}
+
+func emptyList(value json.RawMessage) json.RawMessage {
+	if string(value) == "null" {
+		return json.RawMessage("[]")
+	}
+	return value
+}
+
+func selectBulkTargets(ctx context.Context, tasks *service.TaskService, userID uuid.UUID, op bulkOperation) ([]models.Task, error) {
+	selectors := 0
+	for _, given := range []bool{len(op.IDs) > 0, len(op.Filter) > 0, op.All} {
+		if given {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, fmt.Errorf("%s requires exactly one of ids, a filter with at least one criterion, or \"all\": true", op.Op)
+	}
+
+	var filter repository.TaskFilter
+	switch {
+	case len(op.IDs) > 0:
+		if len(op.IDs) > maxBulkTasks {
+			return nil, fmt.Errorf("at most %d ids per operation", maxBulkTasks)
+		}
+		filter.IDs = op.IDs
+	case len(op.Filter) > 0:
+		params := url.Values{}
+		for key, value := range op.Filter {
+			if !slices.Contains(bulkFilterKeys, key) {
+				return nil, fmt.Errorf("unknown filter %q; filters are %s", key, strings.Join(bulkFilterKeys, ", "))
+			}
+			if strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("filter %q has no value", key)
+			}
+			params.Set(key, value)
+		}
+		var err error
+		if filter, err = parseTaskFilter(params); err != nil {
+			return nil, err
+		}
+	}
+	filter.Limit = maxBulkTasks + 1
+
+	targets, err := tasks.List(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) > maxBulkTasks {
+		return nil, fmt.Errorf("operation matches more than %d tasks", maxBulkTasks)
+	}
+
+	if len(op.IDs) > 0 {
+		found := make(map[uuid.UUID]bool, len(targets))
+		for _, task := range targets {
+			found[task.ID] = true
+		}
+		for _, id := range op.IDs {
+			if !found[id] {
+				return nil, fmt.Errorf("task %s not found", id)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// parseBulkSet validates the fields an update assigns. description,
+// due_date, project and recurrence accept null to clear them. add_labels and
+// remove_labels edit the existing labels instead of replacing them.
+func parseBulkSet(set map[string]json.RawMessage) (func(*service.TaskInput), error) {
+	if len(set) == 0 {
+		return nil, errors.New("update requires at least one field in set")
+	}
+	if _, ok := set["labels"]; ok && (set["add_labels"] != nil || set["remove_labels"] != nil) {
+		return nil, errors.New("labels cannot be combined with add_labels or remove_labels")
+	}
+
+	var apply []func(*service.TaskInput)
+	for field, raw := range set {
+		isNull := string(raw) == "null"
+		switch field {
+		case "title":
+			var title string
+			if err := json.Unmarshal(raw, &title); err != nil || strings.TrimSpace(title) == "" {
+				return nil, errors.New("title must be a non-empty string")
+			}
+			apply = append(apply, func(input *service.TaskInput) { input.Title = title })
+		case "description":
+			var description string
+			if !isNull {
+				if err := json.Unmarshal(raw, &description); err != nil {
+					return nil, errors.New("description must be a string or null")
+				}
+			}
+			apply = append(apply, func(input *service.TaskInput) { input.Description = description })
+		case "status":
+			var status bool
+			if err := json.Unmarshal(raw, &status); err != nil || isNull {
+				return nil, errors.New("status must be a boolean")
+			}
+			apply = append(apply, func(input *service.TaskInput) { input.Status = status })
+		case "due_date":
+			var dueDate *time.Time
+			if err := json.Unmarshal(raw, &dueDate); err != nil {
+				return nil, errors.New("due_date must be an RFC 3339 time or null")
+			}
+			apply = append(apply, func(input *service.TaskInput) { input.DueDate = dueDate })
+		case "priority":
+			var priority int
+			if err := json.Unmarshal(raw, &priority); err != nil || isNull {
+				return nil, errors.New("priority must be an integer")
+			}
+			apply = append(apply, func(input *service.TaskInput) { input.Priority = priority })
+		case "project", "recurrence":
+			var value string
+			if !isNull {
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, fmt.Errorf("%s must be a string or null", field)
+				}
+			}
+			if field == "project" {
+				apply = append(apply, func(input *service.TaskInput) { input.Project = value })
+			} else {
+				apply = append(apply, func(input *service.TaskInput) { input.Recurrence = value })
+			}
+		case "labels", "add_labels", "remove_labels":
+			var labels []string
+			if err := json.Unmarshal(raw, &labels); err != nil {
+				return nil, fmt.Errorf("%s must be an array of strings", field)
+			}
+			switch field {
+			case "labels":
+				apply = append(apply, func(input *service.TaskInput) { input.Labels = labels })
+			case "add_labels":
+				apply = append(apply, func(input *service.TaskInput) {
+					input.Labels = append(append([]string{}, input.Labels...), labels...)
+				})
+			default:
+				apply = append(apply, func(input *service.TaskInput) { input.Labels = withoutLabels(input.Labels, labels) })
+			}
+		default:
+			return nil, fmt.Errorf("field %q cannot be set", field)
+		}
+	}
+
+	return func(input *service.TaskInput) {
+		for _, fn := range apply {
+			fn(input)
+		}
+	}, nil
+}
+
+func withoutLabels(labels, remove []string) []string {
+	removed := make(map[string]bool, len(remove))
+	for _, label := range remove {
+		removed[strings.ToLower(strings.TrimPrefix(label, "#"))] = true
+	}
+	kept := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if !removed[label] {
+			kept = append(kept, label)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,814 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"to_do_api/caldav"
+	"to_do_api/database"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/service"
+	"to_do_api/transfer"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CalDAV serves tasks to CalDAV clients (RFC 4791). Each project is a
+// calendar collection of VTODO resources, with an Inbox for tasks without a
+// project:
+//
+//	/caldav/                       service root
+//	/caldav/principal/             the signed-in user
+//	/caldav/calendars/             calendar home
+//	/caldav/calendars/inbox/       tasks without a project
+//	/caldav/calendars/<project>/   a project, base64url-encoded
+//	/caldav/calendars/<c>/<name>   a task, <task id>.ics unless the client
+//	                               named it when creating it
+//
+// Writes are validated and stored like JSON API writes. Projects exist while
+// they have tasks, so collections cannot be created or deleted over CalDAV.
+// A PUT keeps only what maps onto task fields; alarms and other properties
+// are dropped, so PUT responses carry no ETag and clients fetch the stored
+// version instead.
+
+const (
+	caldavRoot      = "/caldav/"
+	caldavPrincipal = caldavRoot + "principal/"
+	caldavHome      = caldavRoot + "calendars/"
+	caldavInbox     = "inbox"
+
+	caldavContentType = "text/calendar; charset=utf-8; component=vtodo"
+	maxCalDAVBody     = 1 << 20
+)
+
+// CalDAVMethods are the methods CalDAV serves.
+var CalDAVMethods = []string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+	"PROPFIND", "PROPPATCH", "REPORT",
+}
+
+type davKind int
+
+const (
+	davRoot davKind = iota
+	davPrincipal
+	davHome
+	davCollection
+	davObject
+)
+
+type davTarget struct {
+	kind    davKind
+	project string
+	name    string
+}
+
+type davResource struct {
+	href  string
+	props map[xml.Name]string
+}
+
+// CalDAVWellKnown points clients looking up /.well-known/caldav at the
+// service root.
+func CalDAVWellKnown() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, caldavRoot)
+	}
+}
+
+func CalDAV(db *gorm.DB, tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		c.Header("DAV", "1, 3, calendar-access")
+		target, ok := parseDAVPath(c.Param("path"))
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		switch c.Request.Method {
+		case http.MethodOptions:
+			c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+			c.Status(http.StatusOK)
+		case "PROPFIND":
+			caldavPropfind(c, db, userID, target)
+		case "PROPPATCH":
+			caldavProppatch(c)
+		case "REPORT":
+			caldavReport(c, db, userID, target)
+		case http.MethodGet, http.MethodHead:
+			caldavGet(c, db, userID, target)
+		case http.MethodPut:
+			caldavPut(c, db, tasks, broker, userID, target)
+		case http.MethodDelete:
+			caldavDelete(c, db, tasks, broker, userID, target)
+		default:
+			c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+			c.Status(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func parseDAVPath(path string) (davTarget, bool) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return davTarget{kind: davRoot}, true
+	}
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "principal":
+		return davTarget{kind: davPrincipal}, true
+	case parts[0] != "calendars" || len(parts) > 3:
+		return davTarget{}, false
+	case len(parts) == 1:
+		return davTarget{kind: davHome}, true
+	}
+
+	project, ok := collectionProject(parts[1])
+	if !ok {
+		return davTarget{}, false
+	}
+	if len(parts) == 2 {
+		return davTarget{kind: davCollection, project: project}, true
+	}
+	if parts[2] == "" {
+		return davTarget{}, false
+	}
+	return davTarget{kind: davObject, project: project, name: parts[2]}, true
+}
+
+// collectionSegment names a project's collection. base64url never produces
+// five characters, so it cannot collide with the Inbox.
+func collectionSegment(project string) string {
+	if project == "" {
+		return caldavInbox
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(project))
+}
+
+func collectionProject(segment string) (string, bool) {
+	if segment == caldavInbox {
+		return "", true
+	}
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil || len(data) == 0 || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+func collectionHref(project string) string {
+	return caldavHome + collectionSegment(project) + "/"
+}
+
+func objectHref(project, name string) string {
+	return collectionHref(project) + url.PathEscape(name)
+}
+
+func readDAVBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalDAVBody+1))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Failed to read body")
+		return nil, false
+	}
+	if len(body) > maxCalDAVBody {
+		c.String(http.StatusRequestEntityTooLarge, "Body too large")
+		return nil, false
+	}
+	return body, true
+}
+
+// caldavPropfind answers PROPFIND. Depth: infinity, the default, is served
+// as Depth: 1.
+func caldavPropfind(c *gin.Context, db *gorm.DB, userID uuid.UUID, target davTarget) {
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	find, err := caldav.ParsePropfind(body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resources, found, err := davResources(db, userID, target, c.GetHeader("Depth") != "0")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to read tasks")
+		return
+	}
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	responses := make([]caldav.Response, len(resources))
+	for i, resource := range resources {
+		// calendar-data is the whole resource, so only listing it by name
+		// returns it.
+		responses[i] = caldav.Select(resource.href, resource.props, find, caldav.CalendarData)
+	}
+	caldav.WriteMultistatus(c.Writer, responses)
+}
+
+// davResources returns the target and, with children, its members.
+func davResources(db *gorm.DB, userID uuid.UUID, target davTarget, children bool) ([]davResource, bool, error) {
+	switch target.kind {
+	case davRoot:
+		return []davResource{{href: caldavRoot, props: map[xml.Name]string{
+			caldav.ResourceType:         caldav.Element(caldav.Collection, ""),
+			caldav.CurrentUserPrincipal: caldav.Href(caldavPrincipal),
+			caldav.CalendarHomeSet:      caldav.Href(caldavHome),
+		}}}, true, nil
+
+	case davPrincipal:
+		var user models.User
+		if err := db.Select("email").Where("id = ?", userID).First(&user).Error; err != nil {
+			return nil, false, err
+		}
+		return []davResource{{href: caldavPrincipal, props: map[xml.Name]string{
+			caldav.ResourceType:           caldav.Element(caldav.Collection, "") + caldav.Element(caldav.Principal, ""),
+			caldav.DisplayName:            caldav.Escape(user.Email),
+			caldav.CurrentUserPrincipal:   caldav.Href(caldavPrincipal),
+			caldav.PrincipalURL:           caldav.Href(caldavPrincipal),
+			caldav.CalendarHomeSet:        caldav.Href(caldavHome),
+			caldav.CalendarUserAddressSet: caldav.Href("mailto:" + user.Email),
+		}}}, true, nil
+
+	case davHome:
+		resources := []davResource{{href: caldavHome, props: map[xml.Name]string{
+			caldav.ResourceType:          caldav.Element(caldav.Collection, ""),
+			caldav.DisplayName:           "Tasks",
+			caldav.CurrentUserPrincipal:  caldav.Href(caldavPrincipal),
+			caldav.CurrentUserPrivileges: davPrivileges(),
+		}}}
+		if !children {
+			return resources, true, nil
+		}
+		ctag, err := davCTag(db, userID)
+		if err != nil {
+			return nil, false, err
+		}
+		var projects []string
+		err = db.Model(&models.Task{}).Where("user_id = ? AND project <> ''", userID).
+			Distinct("project").Order("project").Pluck("project", &projects).Error
+		if err != nil {
+			return nil, false, err
+		}
+		for _, project := range append([]string{""}, projects...) {
+			resources = append(resources, collectionResource(project, ctag))
+		}
+		return resources, true, nil
+
+	case davCollection:
+		tasks, err := collectionTasks(db, userID, target.project)
+		if err != nil {
+			return nil, false, err
+		}
+		if target.project != "" && len(tasks) == 0 {
+			return nil, false, nil
+		}
+		ctag, err := davCTag(db, userID)
+		if err != nil {
+			return nil, false, err
+		}
+		resources := []davResource{collectionResource(target.project, ctag)}
+		if !children {
+			return resources, true, nil
+		}
+		objects, err := objectResources(db, userID, tasks, nil)
+		return append(resources, objects...), true, err
+	}
+
+	task, found, err := findDAVTask(db, userID, target.name)
+	if err != nil || !found || task.Project != target.project {
+		return nil, false, err
+	}
+	objects, err := objectResources(db, userID, []models.Task{task}, []string{target.name})
+	return objects, err == nil, err
+}
+
+func collectionResource(project, ctag string) davResource {
+	name := project
+	if name == "" {
+		name = "Inbox"
+	}
+	principal := caldav.Href(caldavPrincipal)
+	return davResource{href: collectionHref(project), props: map[xml.Name]string{
+		caldav.ResourceType:          caldav.Element(caldav.Collection, "") + caldav.Element(caldav.Calendar, ""),
+		caldav.DisplayName:           caldav.Escape(name),
+		caldav.SupportedComponentSet: `<c:comp name="VTODO"/>`,
+		caldav.SupportedReportSet:    davSupportedReports(),
+		caldav.GetCTag:               caldav.Escape(ctag),
+		caldav.Owner:                 principal,
+		caldav.CurrentUserPrincipal:  principal,
+		caldav.CurrentUserPrivileges: davPrivileges(),
+	}}
+}
+
+// objectResources describes tasks as resources. names, if given, are the
+// names they were requested by, in the same order.
+func objectResources(db *gorm.DB, userID uuid.UUID, tasks []models.Task, names []string) ([]davResource, error) {
+	resources, err := loadDAVNames(db, userID, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]davResource, len(tasks))
+	for i, task := range tasks {
+		var data bytes.Buffer
+		if err := transfer.WriteICalendarTask(&data, task, resources.uid(task.ID), resources.parentUID(task)); err != nil {
+			return nil, err
+		}
+		name := resources.name(task.ID)
+		if names != nil {
+			name = names[i]
+		}
+		objects[i] = davResource{href: objectHref(task.Project, name), props: map[xml.Name]string{
+			caldav.ResourceType:    "",
+			caldav.GetETag:         caldav.Escape(taskETag(task)),
+			caldav.GetContentType:  caldavContentType,
+			caldav.GetLastModified: task.UpdatedAt.UTC().Format(http.TimeFormat),
+			caldav.CalendarData:    caldav.Escape(data.String()),
+		}}
+	}
+	return objects, nil
+}
+
+func davPrivileges() string {
+	var b strings.Builder
+	for _, privilege := range []string{"read", "write", "write-content", "bind", "unbind", "read-current-user-privilege-set"} {
+		b.WriteString(caldav.Element(xml.Name{Space: caldav.NamespaceDAV, Local: "privilege"},
+			caldav.Element(xml.Name{Space: caldav.NamespaceDAV, Local: privilege}, "")))
+	}
+	return b.String()
+}
+
+func davSupportedReports() string {
+	var b strings.Builder
+	for _, report := range []xml.Name{caldav.CalendarMultiget, caldav.CalendarQuery} {
+		b.WriteString(caldav.Element(caldav.SupportedReport,
+			caldav.Element(xml.Name{Space: caldav.NamespaceDAV, Local: "report"}, caldav.Element(report, ""))))
+	}
+	return b.String()
+}
+
+// davCTag changes whenever any of the user's tasks does. It is shared by
+// every collection, so a change in one makes clients recheck them all.
+func davCTag(db *gorm.DB, userID uuid.UUID) (string, error) {
+	var cursor uint64
+	err := db.Model(&models.TaskEvent{}).Where("user_id = ?", userID).Select("COALESCE(MAX(id), 0)").Scan(&cursor).Error
+	return formatSyncToken(cursor), err
+}
+
+func collectionTasks(db *gorm.DB, userID uuid.UUID, project string) ([]models.Task, error) {
+	var tasks []models.Task
+	err := db.Where("user_id = ? AND COALESCE(project, '') = ?", userID, project).Order("created_at, id").Find(&tasks).Error
+	return tasks, err
+}
+
+// davNames holds the client-chosen names and UIDs of some tasks. Tasks
+// without one are <task id>.ics with the task ID as UID.
+type davNames map[uuid.UUID]models.CalDAVResource
+
+// loadDAVNames loads the names of tasks and their parents.
+func loadDAVNames(db *gorm.DB, userID uuid.UUID, tasks []models.Task) (davNames, error) {
+	names := davNames{}
+	var ids []uuid.UUID
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+		if task.ParentID != nil {
+			ids = append(ids, *task.ParentID)
+		}
+	}
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var resources []models.CalDAVResource
+	if err := db.Where("user_id = ? AND task_id IN ?", userID, ids).Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		names[resource.TaskID] = resource
+	}
+	return names, nil
+}
+
+func (n davNames) name(id uuid.UUID) string {
+	if resource, ok := n[id]; ok {
+		return resource.Name
+	}
+	return id.String() + ".ics"
+}
+
+func (n davNames) uid(id uuid.UUID) string {
+	if resource, ok := n[id]; ok {
+		return resource.UID
+	}
+	return id.String()
+}
+
+func (n davNames) parentUID(task models.Task) string {
+	if task.ParentID == nil {
+		return ""
+	}
+	return n.uid(*task.ParentID)
+}
+
+// findDAVTask finds the task a resource name refers to, in any collection.
+func findDAVTask(db *gorm.DB, userID uuid.UUID, name string) (models.Task, bool, error) {
+	var resources []models.CalDAVResource
+	if err := db.Where("user_id = ? AND name = ?", userID, name).Limit(1).Find(&resources).Error; err != nil {
+		return models.Task{}, false, err
+	}
+	if len(resources) > 0 {
+		return findDAVTaskByID(db, userID, resources[0].TaskID)
+	}
+
+	base, _ := strings.CutSuffix(name, ".ics")
+	id, err := uuid.Parse(base)
+	if err != nil || id.String() != base {
+		return models.Task{}, false, nil
+	}
+	return findUnnamedDAVTask(db, userID, id)
+}
+
+// findDAVTaskByUID finds the task with a UID, in any collection.
+func findDAVTaskByUID(db *gorm.DB, userID uuid.UUID, uid string) (models.Task, bool, error) {
+	var resources []models.CalDAVResource
+	if err := db.Where("user_id = ? AND uid = ?", userID, uid).Limit(1).Find(&resources).Error; err != nil {
+		return models.Task{}, false, err
+	}
+	if len(resources) > 0 {
+		return findDAVTaskByID(db, userID, resources[0].TaskID)
+	}
+
+	id, err := uuid.Parse(uid)
+	if err != nil || id.String() != uid {
+		return models.Task{}, false, nil
+	}
+	return findUnnamedDAVTask(db, userID, id)
+}
+
+// findUnnamedDAVTask finds a task by the default name or UID its ID gives
+// it, which it only has if a client has not named it.
+func findUnnamedDAVTask(db *gorm.DB, userID, id uuid.UUID) (models.Task, bool, error) {
+	var named int64
+	if err := db.Model(&models.CalDAVResource{}).Where("task_id = ?", id).Count(&named).Error; err != nil || named > 0 {
+		return models.Task{}, false, err
+	}
+	return findDAVTaskByID(db, userID, id)
+}
+
+func findDAVTaskByID(db *gorm.DB, userID, id uuid.UUID) (models.Task, bool, error) {
+	var tasks []models.Task
+	if err := db.Where("id = ? AND user_id = ?", id, userID).Limit(1).Find(&tasks).Error; err != nil || len(tasks) == 0 {
+		return models.Task{}, false, err
+	}
+	return tasks[0], true, nil
+}
+
+func caldavGet(c *gin.Context, db *gorm.DB, userID uuid.UUID, target davTarget) {
+	if target.kind != davObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, PROPPATCH, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	task, found, err := findDAVTask(db, userID, target.name)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch task")
+		return
+	}
+	if !found || task.Project != target.project {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	etag := taskETag(task)
+	if notModified(c, etag) {
+		return
+	}
+	names, err := loadDAVNames(db, userID, []models.Task{task})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch task")
+		return
+	}
+	var data bytes.Buffer
+	if err := transfer.WriteICalendarTask(&data, task, names.uid(task.ID), names.parentUID(task)); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to write task")
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", task.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, caldavContentType, data.Bytes())
+}
+
+// caldavPut creates or replaces the task a resource holds. The collection it
+// is put in sets the task's project, so putting a task in another collection
+// moves it there.
+func caldavPut(c *gin.Context, db *gorm.DB, tasks *service.TaskService, broker events.Broker, userID uuid.UUID, target davTarget) {
+	if target.kind != davObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, PROPPATCH, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	object, err := transfer.ReadICalendarTask(body, time.UTC)
+	if err != nil {
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.ValidCalendarData, err.Error())
+		return
+	}
+
+	task, found, err := findDAVTask(db, userID, target.name)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch task")
+		return
+	}
+	etag := ""
+	if found {
+		etag = taskETag(task)
+	}
+	if header := c.GetHeader("If-None-Match"); header != "" && found && etagListContains(header, etag, true) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+	if header := c.GetHeader("If-Match"); header != "" && (!found || !etagListContains(header, etag, false)) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	// A resource keeps its UID, and no two resources share one.
+	if found {
+		names, err := loadDAVNames(db, userID, []models.Task{task})
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to fetch task")
+			return
+		}
+		if names.uid(task.ID) != object.UID {
+			caldav.WriteError(c.Writer, http.StatusForbidden, caldav.NoUIDConflict, "the UID of a resource cannot change")
+			return
+		}
+	} else {
+		other, conflict, err := findDAVTaskByUID(db, userID, object.UID)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to fetch task")
+			return
+		}
+		if conflict {
+			names, _ := loadDAVNames(db, userID, []models.Task{other})
+			caldav.WriteError(c.Writer, http.StatusForbidden, caldav.NoUIDConflict, objectHref(other.Project, names.name(other.ID)))
+			return
+		}
+	}
+
+	var parentID *uuid.UUID
+	if object.ParentUID != "" {
+		parent, ok, err := findDAVTaskByUID(db, userID, object.ParentUID)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to fetch task")
+			return
+		}
+		if ok {
+			parentID = &parent.ID
+		}
+	}
+
+	input := service.TaskInput{
+		Title:       object.Title,
+		Description: object.Description,
+		Status:      object.Status,
+		DueDate:     object.DueDate,
+		Priority:    object.Priority,
+		Project:     target.project,
+		Labels:      object.Labels,
+		Recurrence:  object.Recurrence,
+		ParentID:    parentID,
+	}
+	if strings.TrimSpace(input.Title) == "" {
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.ValidCalendarData, "title is required")
+		return
+	}
+	if err := input.Normalize(); err != nil {
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.ValidCalendarData, err.Error())
+		return
+	}
+
+	var recorded []models.TaskEvent
+	err = tasks.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		if found {
+			var err error
+			_, recorded, err = tasks.Update(ctx, userID, task, input)
+			return err
+		}
+
+		task, taskEvents, err := tasks.Insert(ctx, newDAVTask(ctx, tasks, userID, object.UID, input))
+		if err != nil {
+			return err
+		}
+		recorded = taskEvents
+		if target.name == task.ID.String()+".ics" && object.UID == task.ID.String() {
+			return nil
+		}
+		return database.Conn(ctx, db).Create(&models.CalDAVResource{TaskID: task.ID, UserID: userID, Name: target.name, UID: object.UID}).Error
+	})
+	if errors.Is(err, errVersionMismatch) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, errInvalidParent) {
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.ValidCalendarData, err.Error())
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to save task")
+		return
+	}
+	publishTaskEvents(c, broker, recorded...)
+
+	if found {
+		c.Status(http.StatusNoContent)
+	} else {
+		c.Status(http.StatusCreated)
+	}
+}
+
+// newDAVTask builds a task for a new resource. A UID that is an unused UUID
+// becomes the task ID, so the task needs no CalDAVResource.
+func newDAVTask(ctx context.Context, tasks *service.TaskService, userID uuid.UUID, uid string, input service.TaskInput) models.Task {
+	task := service.NewTask(userID, input)
+	if id, err := uuid.Parse(uid); err == nil && id.String() == uid {
+		if _, err := tasks.Get(ctx, userID, id); errors.Is(err, service.ErrNotFound) {
+			task.ID = id
+		}
+	}
+	return task
+}
+
+func caldavDelete(c *gin.Context, db *gorm.DB, tasks *service.TaskService, broker events.Broker, userID uuid.UUID, target davTarget) {
+	if target.kind != davObject {
+		c.String(http.StatusForbidden, "Only tasks can be deleted")
+		return
+	}
+
+	task, found, err := findDAVTask(db, userID, target.name)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch task")
+		return
+	}
+	if !found || task.Project != target.project {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if header := c.GetHeader("If-Match"); header != "" && !etagListContains(header, taskETag(task), false) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	recorded, err := tasks.Delete(c.Request.Context(), userID, task)
+	if errors.Is(err, errVersionMismatch) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+	if errors.Is(err, errHasSubtasks) {
+		c.String(http.StatusConflict, "Task has subtasks")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to delete task")
+		return
+	}
+	publishTaskEvents(c, broker, recorded...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// caldavProppatch refuses every change: collection names and colours come
+// from projects, which cannot be edited over CalDAV.
+func caldavProppatch(c *gin.Context) {
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	names, err := caldav.ParseProppatch(body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	caldav.WriteMultistatus(c.Writer, []caldav.Response{{Href: c.Request.URL.Path, Forbidden: names}})
+}
+
+func caldavReport(c *gin.Context, db *gorm.DB, userID uuid.UUID, target davTarget) {
+	if target.kind != davCollection {
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.SupportedReport, "")
+		return
+	}
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	report, err := caldav.ParseReport(body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	find := caldav.Propfind{Props: report.Props, AllProp: len(report.Props) == 0}
+
+	var tasks []models.Task
+	var names []string
+	var responses []caldav.Response
+	switch report.Name {
+	case caldav.CalendarMultiget:
+		for _, href := range report.Hrefs {
+			task, name, found, err := findReportTask(db, userID, target.project, href)
+			if err != nil {
+				c.String(http.StatusInternalServerError, "Failed to fetch tasks")
+				return
+			}
+			if !found {
+				responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			tasks = append(tasks, task)
+			names = append(names, name)
+		}
+	case caldav.CalendarQuery:
+		if report.Filter.Component != "" && report.Filter.Component != "VTODO" {
+			break
+		}
+		all, err := collectionTasks(db, userID, target.project)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to fetch tasks")
+			return
+		}
+		for _, task := range all {
+			if matchesDAVFilter(task, report.Filter) {
+				tasks = append(tasks, task)
+			}
+		}
+	default:
+		caldav.WriteError(c.Writer, http.StatusForbidden, caldav.SupportedReport, "")
+		return
+	}
+
+	objects, err := objectResources(db, userID, tasks, names)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch tasks")
+		return
+	}
+	for _, object := range objects {
+		responses = append(responses, caldav.Select(object.href, object.props, find, caldav.CalendarData))
+	}
+	caldav.WriteMultistatus(c.Writer, responses)
+}
+
+// findReportTask finds the task an href in a multiget names, which must be
+// in the collection the report was sent to.
+func findReportTask(db *gorm.DB, userID uuid.UUID, project, href string) (models.Task, string, bool, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return models.Task{}, "", false, nil
+	}
+	name, ok := strings.CutPrefix(u.Path, collectionHref(project))
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return models.Task{}, "", false, nil
+	}
+	task, found, err := findDAVTask(db, userID, name)
+	if err != nil || !found || task.Project != project {
+		return models.Task{}, "", false, err
+	}
+	return task, name, true, nil
+}
+
+// matchesDAVFilter applies a calendar-query filter. A task without a due
+// date has no time to fall outside a time range, so it always matches one.
+func matchesDAVFilter(task models.Task, filter caldav.Filter) bool {
+	if filter.Completed != nil && task.Status != *filter.Completed {
+		return false
+	}
+	if task.DueDate != nil {
+		if !filter.Start.IsZero() && task.DueDate.Before(filter.Start) {
+			return false
+		}
+		if !filter.End.IsZero() && !task.DueDate.Before(filter.End) {
+			return false
+		}
+	}
+	return true
+}
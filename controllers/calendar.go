@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"to_do_api/auth"
+	"to_do_api/models"
+	"to_do_api/service"
+	"to_do_api/transfer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// calendarFeedPath is where feeds are served; the token follows it.
+const calendarFeedPath = "/calendar/feed/"
+
+// CreateCalendarFeed creates the user's iCalendar feed. If there already is
+// one its token is replaced, so the old URL stops working.
+func CreateCalendarFeed(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		token, err := generateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		feed := models.CalendarFeed{UserID: userID, TokenHash: auth.HashSecret(token)}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&feed).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+			return
+		}
+
+		// Like a webhook secret, the token is only ever shown once.
+		c.JSON(http.StatusCreated, gin.H{
+			"feed":  feed,
+			"token": token,
+			"url":   calendarFeedPath + token + ".ics",
+		})
+	}
+}
+
+func GetCalendarFeed(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		var feed models.CalendarFeed
+		if err := db.Where("user_id = ?", userID).First(&feed).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, feed)
+	}
+}
+
+func DeleteCalendarFeed(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		result := db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+	}
+}
+
+// ServeCalendarFeed serves a feed to calendar apps, which cannot send a JWT,
+// so the token in the URL is the only credential. The ListTasks filters
+// narrow the feed, and ?events=true adds a VEVENT at each due date for apps
+// that ignore VTODOs.
+func ServeCalendarFeed(db *gorm.DB, tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+		var feed models.CalendarFeed
+		err := db.Where("token_hash = ?", auth.HashSecret(token)).First(&feed).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar feed"})
+			return
+		}
+
+		withEvents := false
+		if value := c.Query("events"); value != "" {
+			if withEvents, err = strconv.ParseBool(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid events"})
+				return
+			}
+		}
+
+		filter, err := parseTaskFilter(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		encoder := transfer.NewICalendarEncoder(c.Writer, withEvents)
+		disposition := `inline; filename="` + transfer.Filename(transfer.FormatICalendar) + `"`
+		streamTasks(c, tasks, feed.UserID, filter, encoder, transfer.ContentType(transfer.FormatICalendar), disposition)
+	}
+}
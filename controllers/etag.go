@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"to_do_api/models"
+	"to_do_api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errVersionMismatch is returned from inside a transaction when a conditional
+// write finds the task no longer at the version the request was checked
+// against.
+var errVersionMismatch = repository.ErrVersionConflict
+
+func taskETag(task models.Task) string {
+	return fmt.Sprintf(`"%s-%d"`, task.ID, task.Version)
+}
+
+// tasksETag changes whenever any task in the list is added, removed or
+// modified.
+func tasksETag(tasks []models.Task) string {
+	hash := sha256.New()
+	for _, task := range tasks {
+		fmt.Fprintf(hash, "%s:%d;", task.ID, task.Version)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+// checkIfMatch enforces an If-Match precondition, writing 412 itself when it
+// fails. A request without If-Match always passes.
+func checkIfMatch(c *gin.Context, current string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || etagListContains(header, current, false) {
+		return true
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has been modified", "etag": current})
+	return false
+}
+
+// notModified answers 304 when If-None-Match already names the current
+// representation.
+func notModified(c *gin.Context, current string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" || !etagListContains(header, current, true) {
+		return false
+	}
+	c.Header("ETag", current)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagListContains implements the comparison rules of RFC 9110: If-Match uses
+// strong comparison, If-None-Match weak comparison, and "*" matches anything.
+func etagListContains(header, current string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == current {
+			return true
+		}
+	}
+	return false
+}
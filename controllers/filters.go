@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"to_do_api/repository"
+	"to_do_api/taskquery"
+
+	"gorm.io/gorm"
+)
+
+// parseTaskFilter reads the ListTasks query parameters:
+//
+//	status=true|false        completed or not
+//	due_before=<RFC 3339>    due strictly before the given time
+//	due_after=<RFC 3339>     due strictly after the given time
+//	has_due_date=true|false  whether a due date is set
+//	q=<query>                a taskquery expression, e.g. due:<7d AND NOT status:done
+//
+// The same parameters are accepted wherever tasks are selected by filter.
+func parseTaskFilter(params url.Values) (repository.TaskFilter, error) {
+	var filter repository.TaskFilter
+
+	if value := params.Get("status"); value != "" {
+		status, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid status filter %q", value)
+		}
+		filter.Status = &status
+	}
+
+	if value := params.Get("due_before"); value != "" {
+		before, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid due_before filter %q", value)
+		}
+		filter.DueBefore = &before
+	}
+
+	if value := params.Get("due_after"); value != "" {
+		after, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid due_after filter %q", value)
+		}
+		filter.DueAfter = &after
+	}
+
+	if value := params.Get("has_due_date"); value != "" {
+		hasDueDate, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid has_due_date filter %q", value)
+		}
+		filter.HasDueDate = &hasDueDate
+	}
+
+	if value := params.Get("q"); value != "" {
+		node, err := taskquery.Parse(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Query = node
+	}
+
+	return filter, nil
+}
+
+// applyTaskFilters narrows a task query by the parameters parseTaskFilter
+// reads, for handlers that build further on the query.
+func applyTaskFilters(query *gorm.DB, params url.Values) (*gorm.DB, error) {
+	filter, err := parseTaskFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	return repository.ApplyTaskFilter(query, filter), nil
+}
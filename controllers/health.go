@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds all of a readiness probe's checks together, so a
+// hung dependency fails the probe instead of stalling it.
+const readinessTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+// Healthz is the liveness probe: it answers as long as the process can
+// serve requests at all, and checks no dependencies, so an outage of one
+// does not get every instance restarted.
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// Readyz is the readiness probe. It runs every check and answers 503,
+// naming the failures, unless all of them pass.
+func Readyz(checks map[string]HealthCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+
+		status, code := "ready", http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status, code = "unavailable", http.StatusServiceUnavailable
+				results[name] = err.Error()
+			} else {
+				results[name] = "ok"
+			}
+		}
+
+		c.JSON(code, gin.H{"status": status, "checks": results})
+	}
+}
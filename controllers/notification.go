@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func ListNotifications(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		page, pageSize, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		query := db.Model(&models.Notification{}).Where("user_id = ?", userID)
+		if c.Query("unread") == "true" {
+			query = query.Where("read_at IS NULL")
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		var notifications []models.Notification
+		err := query.Order("created_at DESC").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&notifications).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"notifications": notifications,
+			"page":          page,
+			"page_size":     pageSize,
+			"total":         total,
+		})
+	}
+}
+
+func MarkNotificationRead(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		notificationID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+			return
+		}
+
+		var notification models.Notification
+		if err := db.First(&notification, notificationID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		if notification.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this notification"})
+			return
+		}
+
+		if notification.ReadAt == nil {
+			now := time.Now().UTC()
+			if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, notification)
+	}
+}
+
+func MarkAllNotificationsRead(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		result := db.Model(&models.Notification{}).
+			Where("user_id = ? AND read_at IS NULL", userID).
+			Update("read_at", time.Now().UTC())
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+	}
+}
+
+func GetNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		preferences, err := notificationPreferences(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+			return
+		}
+
+		c.JSON(http.StatusOK, preferences)
+	}
+}
+
+// UpdateNotificationPreferences takes a map of event type to enabled, e.g.
+// {"reminder": false}. Types not mentioned keep their current setting.
+func UpdateNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		var input map[string]bool
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		known := make(map[string]bool, len(models.NotificationTypes))
+		for _, eventType := range models.NotificationTypes {
+			known[eventType] = true
+		}
+		for eventType := range input {
+			if !known[eventType] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type: " + eventType})
+				return
+			}
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for eventType, enabled := range input {
+				preference := models.NotificationPreference{UserID: userID, EventType: eventType, Enabled: enabled}
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+					DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+				}).Create(&preference).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+			return
+		}
+
+		preferences, err := notificationPreferences(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+			return
+		}
+
+		c.JSON(http.StatusOK, preferences)
+	}
+}
+
+func notificationPreferences(db *gorm.DB, userID uuid.UUID) (map[string]bool, error) {
+	var stored []models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, eventType := range models.NotificationTypes {
+		preferences[eventType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.EventType] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+// parsePagination reads ?page= and ?page_size=, writing a 400 itself when
+// either is not a positive integer.
+func parsePagination(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return 0, 0, false
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size"})
+		return 0, 0, false
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return page, pageSize, true
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"to_do_api/events"
+	"to_do_api/service"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// readOnlyTaskFields may appear in a patched document but must come out
+// unchanged.
+var readOnlyTaskFields = []string{"id", "user_id", "version", "created_at", "updated_at"}
+
+// PatchTask applies a JSON Merge Patch (RFC 7396) or, with Content-Type
+// application/json-patch+json, a JSON Patch (RFC 6902). In a merge patch a
+// null removes the field, which resets it: "due_date": null clears the due
+// date. Plain application/json is treated as a merge patch.
+func PatchTask(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := findOwnedTask(c, tasks, "Not authorized to update this task")
+		if !ok {
+			return
+		}
+
+		if !checkIfMatch(c, taskETag(task)) {
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+
+		original, err := json.Marshal(task)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		var patched []byte
+		switch mediaType {
+		case contentTypeJSONPatch:
+			patch, err := jsonpatch.DecodePatch(body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON Patch: " + err.Error()})
+				return
+			}
+			patched, err = patch.Apply(original)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to apply patch: " + err.Error()})
+				return
+			}
+		case contentTypeMergePatch, "application/json", "":
+			patched, err = jsonpatch.MergePatch(original, body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge patch: " + err.Error()})
+				return
+			}
+		default:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch format"})
+			return
+		}
+
+		input, err := decodePatchedTask(original, patched)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		saveTask(c, tasks, broker, task, input)
+	}
+}
+
+// decodePatchedTask checks that a patch left the server-owned fields alone
+// and turns the result back into a service.TaskInput.
+func decodePatchedTask(original, patched []byte) (service.TaskInput, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return service.TaskInput{}, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return service.TaskInput{}, errors.New("patched document is not an object")
+	}
+
+	for _, field := range readOnlyTaskFields {
+		if !bytes.Equal(before[field], after[field]) {
+			return service.TaskInput{}, errors.New(field + " is read-only")
+		}
+		delete(after, field)
+	}
+
+	writable, err := json.Marshal(after)
+	if err != nil {
+		return service.TaskInput{}, err
+	}
+
+	var input service.TaskInput
+	decoder := json.NewDecoder(bytes.NewReader(writable))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		return service.TaskInput{}, errors.New("invalid task: " + err.Error())
+	}
+	if strings.TrimSpace(input.Title) == "" {
+		return service.TaskInput{}, errors.New("title is required")
+	}
+	if err := input.Normalize(); err != nil {
+		return service.TaskInput{}, err
+	}
+	return input, nil
+}
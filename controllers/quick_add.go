@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"to_do_api/events"
+	"to_do_api/quickadd"
+	"to_do_api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type quickAddInput struct {
+	Text string `json:"text" binding:"required,max=1000"`
+	// Timezone is the IANA zone that "tomorrow" and "5pm" are relative
+	// to. It defaults to UTC.
+	Timezone string `json:"timezone"`
+	// Preview only reports the interpretation, for autocomplete.
+	Preview bool `json:"preview"`
+}
+
+// QuickAddTask creates a task from one line of text, responding with the
+// parsed interpretation alongside the task.
+func QuickAddTask(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input quickAddInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		location := time.UTC
+		if input.Timezone != "" {
+			var err error
+			if location, err = time.LoadLocation(input.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone: " + input.Timezone})
+				return
+			}
+		}
+
+		parsed := quickadd.Parse(input.Text, time.Now().In(location))
+		if input.Preview {
+			c.JSON(http.StatusOK, gin.H{"parsed": parsed})
+			return
+		}
+
+		if strings.TrimSpace(parsed.Title) == "" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Text has no title left after parsing", "parsed": parsed})
+			return
+		}
+		taskFields := service.TaskInput{
+			Title:      parsed.Title,
+			DueDate:    parsed.DueDate,
+			Priority:   parsed.Priority,
+			Project:    parsed.Project,
+			Labels:     parsed.Labels,
+			Recurrence: parsed.Recurrence,
+		}
+		if err := taskFields.Normalize(); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "parsed": parsed})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		task, recorded, err := tasks.Insert(c.Request.Context(), service.NewTask(userID, taskFields))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+			return
+		}
+		publishTaskEvents(c, broker, recorded...)
+
+		c.Header("ETag", taskETag(task))
+		c.JSON(http.StatusCreated, gin.H{"parsed": parsed, "task": task})
+	}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"to_do_api/models"
+	"to_do_api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type reminderInput struct {
+	RemindAt      *time.Time `json:"remind_at"`
+	OffsetMinutes *int       `json:"offset_minutes"`
+	Channel       string     `json:"channel" binding:"required,oneof=email webhook in_app"`
+	WebhookURL    string     `json:"webhook_url" binding:"omitempty,url"`
+}
+
+func CreateReminder(db *gorm.DB, tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		task, ok := findOwnedTask(c, tasks, "Not authorized to add reminders to this task")
+		if !ok {
+			return
+		}
+
+		var input reminderInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if (input.RemindAt == nil) == (input.OffsetMinutes == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of remind_at or offset_minutes is required"})
+			return
+		}
+		if input.OffsetMinutes != nil && *input.OffsetMinutes < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset_minutes must not be negative"})
+			return
+		}
+		if input.OffsetMinutes != nil && task.DueDate == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Task has no due date to offset from"})
+			return
+		}
+		if input.Channel == models.ReminderChannelWebhook && input.WebhookURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "webhook_url is required for the webhook channel"})
+			return
+		}
+
+		reminder := models.Reminder{
+			TaskID:        task.ID,
+			UserID:        task.UserID,
+			OffsetMinutes: input.OffsetMinutes,
+			Channel:       input.Channel,
+			WebhookURL:    input.WebhookURL,
+			Status:        models.ReminderStatusPending,
+		}
+		if input.RemindAt != nil {
+			remindAt := input.RemindAt.UTC()
+			reminder.RemindAt = &remindAt
+		}
+		reminder.FireAt = reminder.FireTime(task)
+
+		if err := db.Create(&reminder).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reminder"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, reminder)
+	}
+}
+
+func ListReminders(db *gorm.DB, tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		task, ok := findOwnedTask(c, tasks, "Not authorized to view reminders for this task")
+		if !ok {
+			return
+		}
+
+		var reminders []models.Reminder
+		if err := db.Where("task_id = ?", task.ID).Order("fire_at").Find(&reminders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reminders)
+	}
+}
+
+func DeleteReminder(db *gorm.DB, tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		task, ok := findOwnedTask(c, tasks, "Not authorized to delete reminders for this task")
+		if !ok {
+			return
+		}
+
+		reminderID, err := uuid.Parse(c.Param("reminder_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reminder ID"})
+			return
+		}
+
+		result := db.Where("id = ? AND task_id = ?", reminderID, task.ID).Delete(&models.Reminder{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reminder"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Reminder deleted successfully"})
+	}
+}
+
+// findOwnedTask loads the task named by the :id path parameter and checks it
+// belongs to the current user, writing the error response itself on failure.
+func findOwnedTask(c *gin.Context, tasks *service.TaskService, forbiddenMessage string) (models.Task, bool) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return models.Task{}, false
+	}
+
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	task, err := tasks.Get(c.Request.Context(), userID, taskID)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": forbiddenMessage})
+		return task, false
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return task, false
+	}
+
+	return task, true
+}
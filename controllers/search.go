@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"to_do_api/database"
+	"to_do_api/models"
+	"to_do_api/search"
+	"to_do_api/taskquery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SearchTasks finds the user's tasks matching ?q=, narrowed by the same
+// filters as ListTasks. q is a task query as for ListTasks, whose bare
+// words outside OR and NOT are also searched for, so it must have some.
+// Results are ordered by rank, best first. Like ListTasks it reads from a
+// read replica when one is configured.
+func SearchTasks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := database.ReadReplica(db.WithContext(c.Request.Context()))
+		if strings.TrimSpace(c.Query("q")) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one word"})
+			return
+		}
+		node, err := taskquery.Parse(c.Query("q"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		text := strings.Join(taskquery.Words(node), " ")
+		if len(search.Terms(text)) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one word outside OR and NOT"})
+			return
+		}
+
+		page, pageSize, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		query, err := applyTaskFilters(db.Model(&models.Task{}).Where("tasks.user_id = ?", userID), c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		results, err := search.For(db).Search(query, text, pageSize, (page-1)*pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search tasks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"results":   results,
+			"page":      page,
+			"page_size": pageSize,
+		})
+	}
+}
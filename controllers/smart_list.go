@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"to_do_api/models"
+	"to_do_api/notify"
+	"to_do_api/service"
+	"to_do_api/taskquery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type smartListInput struct {
+	Name  string `json:"name" binding:"required"`
+	Query string `json:"query" binding:"required"`
+}
+
+func CreateSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		var input smartListInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := taskquery.Parse(input.Query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		list := models.SmartList{UserID: userID, Name: input.Name, Query: input.Query}
+		if err := db.Create(&list).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create smart list"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, list)
+	}
+}
+
+// ListSmartLists returns the user's own smart lists followed by those shared
+// with them.
+func ListSmartLists(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		var lists []models.SmartList
+		err := db.Where("user_id = ?", userID).
+			Or("id IN (?)", db.Model(&models.SmartListShare{}).Select("smart_list_id").Where("user_id = ?", userID)).
+			Order(clause.OrderBy{Expression: clause.Expr{SQL: "user_id = ? DESC, created_at, id", Vars: []interface{}{userID}}}).
+			Find(&lists).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch smart lists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+func GetSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, false)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func UpdateSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, true)
+		if !ok {
+			return
+		}
+
+		var input smartListInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := taskquery.Parse(input.Query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := db.Model(&list).Updates(models.SmartList{Name: input.Name, Query: input.Query}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update smart list"})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func DeleteSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, true)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("smart_list_id = ?", list.ID).Delete(&models.SmartListShare{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&list).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete smart list"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Smart list deleted successfully"})
+	}
+}
+
+// ShareSmartList gives another user, identified by email, access to the
+// list and notifies them.
+// ShareSmartList answers the same whether or not anyone is registered with
+// the email, so it cannot be used to find out who is.
+func ShareSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, true)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		shared := gin.H{"message": "Smart list shared with " + input.Email + " if they have an account"}
+		var recipient models.User
+		err := db.Where("email = ?", input.Email).First(&recipient).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusAccepted, shared)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share smart list"})
+			return
+		}
+		if recipient.ID == list.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot share a smart list with yourself"})
+			return
+		}
+
+		share := models.SmartListShare{SmartListID: list.ID, UserID: recipient.ID}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&share)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return notify.Publish(tx, models.Notification{
+				UserID: recipient.ID,
+				Type:   models.NotificationTypeShare,
+				Title:  "Smart list shared with you: " + list.Name,
+				Body:   list.Query,
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share smart list"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, shared)
+	}
+}
+
+func ListSmartListShares(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, true)
+		if !ok {
+			return
+		}
+
+		var shares []models.SmartListShare
+		if err := db.Where("smart_list_id = ?", list.ID).Order("created_at").Find(&shares).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+			return
+		}
+
+		c.JSON(http.StatusOK, shares)
+	}
+}
+
+func UnshareSmartList(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, true)
+		if !ok {
+			return
+		}
+
+		recipientID, err := uuid.Parse(c.Param("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		if err := db.Where("smart_list_id = ? AND user_id = ?", list.ID, recipientID).Delete(&models.SmartListShare{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove share"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Share removed successfully"})
+	}
+}
+
+// EvaluateSmartList returns the caller's tasks matching the list's query,
+// further narrowed by any ListTasks filters on the request.
+func EvaluateSmartList(db *gorm.DB, tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		list, ok := findSmartList(c, db, false)
+		if !ok {
+			return
+		}
+
+		node, err := taskquery.Parse(list.Query)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter, err := parseTaskFilter(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.Query != nil {
+			node = taskquery.And(node, filter.Query)
+		}
+		filter.Query = node
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		matched, err := tasks.List(c.Request.Context(), userID, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+
+		etag := tasksETag(matched)
+		if notModified(c, etag) {
+			return
+		}
+
+		c.Header("ETag", etag)
+		c.JSON(http.StatusOK, matched)
+	}
+}
+
+// findSmartList loads the list named by the :id parameter. The owner always
+// has access; users it is shared with only when ownerOnly is false.
+func findSmartList(c *gin.Context, db *gorm.DB, ownerOnly bool) (models.SmartList, bool) {
+	var list models.SmartList
+
+	listID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart list ID"})
+		return list, false
+	}
+
+	if err := db.First(&list, listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Smart list not found"})
+		return list, false
+	}
+
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	if list.UserID == userID {
+		return list, true
+	}
+
+	if !ownerOnly {
+		err := db.Where("smart_list_id = ? AND user_id = ?", list.ID, userID).First(&models.SmartListShare{}).Error
+		if err == nil {
+			return list, true
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch smart list"})
+			return list, false
+		}
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to access this smart list"})
+	return list, false
+}
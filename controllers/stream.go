@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/auth"
+	"to_do_api/events"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	heartbeatInterval = 25 * time.Second
+	replayBatchSize   = 500
+	wsWriteTimeout    = 10 * time.Second
+	streamTicketTTL   = time.Minute
+)
+
+type streamMessage struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	TaskID    string          `json:"task_id"`
+	Task      json.RawMessage `json:"task"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func newStreamMessage(event models.TaskEvent) streamMessage {
+	return streamMessage{
+		ID:        event.ID,
+		Type:      event.Type,
+		TaskID:    event.TaskID.String(),
+		Task:      json.RawMessage(event.Payload),
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+// CreateStreamTicket issues a ticket for opening an event stream with
+// ?ticket=, for clients that cannot send an Authorization header there.
+// Clients fetch one right before connecting; it works once.
+func CreateStreamTicket(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		ticket, err := generateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate ticket"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		now := time.Now().UTC()
+		stored := models.StreamTicket{TicketHash: auth.HashSecret(ticket), UserID: userID, ExpiresAt: now.Add(streamTicketTTL)}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Tickets that were never used are cleared out as new ones are issued.
+			if err := tx.Where("expires_at <= ?", now).Delete(&models.StreamTicket{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&stored).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_at": stored.ExpiresAt})
+	}
+}
+
+// checkOrigin lets pages on the API's own origin or on allowedOrigins open a
+// WebSocket; otherwise any site a user visits could. Clients other than
+// browsers send no Origin and are let through.
+func checkOrigin(allowedOrigins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, allowed := range allowedOrigins {
+			if strings.EqualFold(origin, allowed) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// StreamTasks pushes the user's task events as Server-Sent Events. Clients
+// resume after a disconnect by sending the standard Last-Event-ID header.
+func StreamTasks(db *gorm.DB, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lastID, ok := parseLastEventID(c)
+		if !ok {
+			return
+		}
+
+		keepOpen(c)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		send := func(event models.TaskEvent) error {
+			data, err := json.Marshal(newStreamMessage(event))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+		heartbeat := func() error {
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+
+		c.Writer.Flush()
+		streamTaskEvents(c.Request.Context(), db, broker, c.GetString("user_id"), lastID, send, heartbeat)
+	}
+}
+
+// keepOpen lifts the server's read and write timeouts for a stream, which
+// is meant to outlast them. WebSocket upgrades clear them on their own.
+func keepOpen(c *gin.Context) {
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+}
+
+// StreamTasksWebSocket carries the same events as StreamTasks over a
+// WebSocket, one JSON message per event. Browsers cannot set headers on the
+// handshake, so the resume point is taken from ?last_event_id= as well.
+// Pages may connect from allowedOrigins besides the API's own origin.
+func StreamTasksWebSocket(db *gorm.DB, broker events.Broker, allowedOrigins []string) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(allowedOrigins)}
+	return func(c *gin.Context) {
+		lastID, ok := parseLastEventID(c)
+		if !ok {
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		// The client never sends anything we act on, but reading is needed to
+		// process control frames and notice when it goes away.
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func(event models.TaskEvent) error {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			return conn.WriteJSON(newStreamMessage(event))
+		}
+		heartbeat := func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+
+		streamTaskEvents(ctx, db, broker, c.GetString("user_id"), lastID, send, heartbeat)
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteTimeout))
+	}
+}
+
+// streamTaskEvents sends the user's events after lastID, or from now if it
+// is 0. Live events only signal that there is something new: the log is
+// read back in ID order, which is commit order, so an event is not skipped
+// even if its notification arrives after a later one's.
+func streamTaskEvents(ctx context.Context, db *gorm.DB, broker events.Broker, userID string, lastID uint64, send func(models.TaskEvent) error, heartbeat func() error) {
+	db = db.WithContext(ctx)
+	if lastID == 0 {
+		latest, err := events.Latest(db, userID)
+		if err != nil {
+			return
+		}
+		lastID = latest
+	}
+
+	// Subscribe before catching up so nothing committed in between is missed.
+	live := broker.Subscribe(ctx, userID)
+	catchUp := func() bool {
+		for {
+			replay, err := events.Since(db, userID, lastID, replayBatchSize)
+			if err != nil {
+				return false
+			}
+			for _, event := range replay {
+				if err := send(event); err != nil {
+					return false
+				}
+				lastID = event.ID
+			}
+			if len(replay) < replayBatchSize {
+				return true
+			}
+		}
+	}
+	if !catchUp() {
+		return
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-live:
+			if !ok {
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			if !catchUp() {
+				return
+			}
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseLastEventID(c *gin.Context) (uint64, bool) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw == "" {
+		return 0, true
+	}
+
+	lastID, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+		return 0, false
+	}
+	return lastID, true
+}
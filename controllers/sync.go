@@ -0,0 +1,473 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Delta sync for offline clients.
+//
+// A sync token is an opaque cursor into the task event log. GET /sync returns
+// every task changed since the token, plus tombstones for deleted ones, and a
+// new token to send next time. Without a token it returns a full snapshot.
+// Each response is read from one consistent snapshot of the database.
+//
+// Projects and labels are names on tasks rather than records of their own.
+// Every response lists the user's current ones, and a delta also lists the
+// names its changed tasks carried at the client's token that no task carries
+// any more, so a renamed project shows up as its new name in projects and
+// its old one in deleted_projects.
+//
+// POST /sync applies a batch of client mutations. A task mutation carries
+// the base_version of the task it was made to, as last pulled, or 0 for a
+// task the client created. It is a conflict if the task has moved on from
+// that version, or was deleted, and conflicts come back with the server's
+// copy so the client can rebase. Projects and labels are renamed or deleted
+// across all of the user's tasks by name; deleting a project or label keeps
+// its tasks.
+
+const (
+	syncTokenPrefix = "v1:"
+	syncBatchSize   = 1000
+
+	mutationUpsert        = "upsert"
+	mutationDelete        = "delete"
+	mutationRenameProject = "rename_project"
+	mutationDeleteProject = "delete_project"
+	mutationRenameLabel   = "rename_label"
+	mutationDeleteLabel   = "delete_label"
+
+	mutationApplied  = "applied"
+	mutationConflict = "conflict"
+	mutationRejected = "rejected"
+)
+
+type syncMutation struct {
+	Op string `json:"op" binding:"required,oneof=upsert delete rename_project delete_project rename_label delete_label"`
+	// ID and BaseVersion name the task an upsert or delete changes.
+	ID          uuid.UUID `json:"id"`
+	BaseVersion int       `json:"base_version" binding:"min=0"`
+	// Name and NewName name the project or label the other ops change.
+	Name        string     `json:"name"`
+	NewName     string     `json:"new_name"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      bool       `json:"status"`
+	DueDate     *time.Time `json:"due_date"`
+	Priority    int        `json:"priority"`
+	Project     string     `json:"project"`
+	Labels      []string   `json:"labels"`
+	Recurrence  string     `json:"recurrence"`
+	ParentID    *uuid.UUID `json:"parent_id"`
+}
+
+type syncResult struct {
+	ID     *uuid.UUID   `json:"id,omitempty"`
+	Name   string       `json:"name,omitempty"`
+	Status string       `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Task   *models.Task `json:"task,omitempty"`
+}
+
+var errSyncConflict = errors.New("sync conflict")
+
+// syncNames are the projects and labels a pull reports.
+type syncNames struct {
+	Projects        []string `json:"projects"`
+	Labels          []string `json:"labels"`
+	DeletedProjects []string `json:"deleted_projects"`
+	DeletedLabels   []string `json:"deleted_labels"`
+}
+
+func PullSync(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		since, err := parseSyncToken(c.Query("sync_token"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync token"})
+			return
+		}
+
+		var cursor uint64
+		var changes []models.TaskEvent
+		tasks := []models.Task{}
+		deleted := []uuid.UUID{}
+		var names syncNames
+		// Repeatable read keeps the token, the tasks and the names to
+		// one point in time even while other requests commit.
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if since == 0 {
+				if cursor, err = events.Latest(tx, userID.String()); err != nil {
+					return err
+				}
+				if err := tx.Where("user_id = ?", userID).Order("created_at, id").Find(&tasks).Error; err != nil {
+					return err
+				}
+				names, err = loadSyncNames(tx, userID, nil)
+				return err
+			}
+
+			if changes, err = events.Since(tx, userID.String(), since, syncBatchSize); err != nil {
+				return err
+			}
+			cursor = since
+			touched := make(map[uuid.UUID]bool)
+			var ids []uuid.UUID
+			for _, event := range changes {
+				cursor = event.ID
+				if !touched[event.TaskID] {
+					touched[event.TaskID] = true
+					ids = append(ids, event.TaskID)
+				}
+			}
+			if len(ids) == 0 {
+				names, err = loadSyncNames(tx, userID, nil)
+				return err
+			}
+
+			if err := tx.Where("user_id = ? AND id IN ?", userID, ids).Find(&tasks).Error; err != nil {
+				return err
+			}
+			live := make(map[uuid.UUID]bool, len(tasks))
+			for _, task := range tasks {
+				live[task.ID] = true
+			}
+			for _, id := range ids {
+				if !live[id] {
+					deleted = append(deleted, id)
+				}
+			}
+
+			seen, err := tasksAsOf(tx, userID, ids, since)
+			if err != nil {
+				return err
+			}
+			names, err = loadSyncNames(tx, userID, seen)
+			return err
+		}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch changes"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"tasks":            tasks,
+			"deleted":          deleted,
+			"projects":         names.Projects,
+			"labels":           names.Labels,
+			"deleted_projects": names.DeletedProjects,
+			"deleted_labels":   names.DeletedLabels,
+			"sync_token":       formatSyncToken(cursor),
+			"has_more":         len(changes) == syncBatchSize,
+		})
+	}
+}
+
+// tasksAsOf returns the tasks with ids as the event log had them at the
+// event cursor since, leaving out those that did not exist then.
+func tasksAsOf(tx *gorm.DB, userID uuid.UUID, ids []uuid.UUID, since uint64) ([]models.Task, error) {
+	latest := tx.Model(&models.TaskEvent{}).
+		Select("MAX(id)").
+		Where("user_id = ? AND task_id IN ? AND id <= ?", userID, ids, since).
+		Group("task_id")
+	var seen []models.TaskEvent
+	if err := tx.Where("id IN (?)", latest).Find(&seen).Error; err != nil {
+		return nil, err
+	}
+
+	tasks := make([]models.Task, 0, len(seen))
+	for _, event := range seen {
+		if event.Type == models.EventTaskDeleted {
+			continue
+		}
+		var task models.Task
+		if err := json.Unmarshal([]byte(event.Payload), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// loadSyncNames lists the user's projects and labels, and those of the
+// tasks as the client saw them that no task has any more.
+func loadSyncNames(tx *gorm.DB, userID uuid.UUID, seen []models.Task) (syncNames, error) {
+	var tasks []models.Task
+	if err := tx.Select("project", "labels").Where("user_id = ?", userID).Find(&tasks).Error; err != nil {
+		return syncNames{}, err
+	}
+	projects, labels := taskNames(tasks)
+	oldProjects, oldLabels := taskNames(seen)
+
+	names := syncNames{Projects: projects, Labels: labels, DeletedProjects: []string{}, DeletedLabels: []string{}}
+	for _, project := range oldProjects {
+		if !slices.Contains(projects, project) {
+			names.DeletedProjects = append(names.DeletedProjects, project)
+		}
+	}
+	for _, label := range oldLabels {
+		if !slices.Contains(labels, label) {
+			names.DeletedLabels = append(names.DeletedLabels, label)
+		}
+	}
+	return names, nil
+}
+
+// taskNames returns the distinct projects and labels of tasks, sorted.
+func taskNames(tasks []models.Task) ([]string, []string) {
+	projects, labels := []string{}, []string{}
+	for _, task := range tasks {
+		if task.Project != "" {
+			projects = append(projects, task.Project)
+		}
+		labels = append(labels, task.Labels...)
+	}
+	slices.Sort(projects)
+	slices.Sort(labels)
+	return slices.Compact(projects), slices.Compact(labels)
+}
+
+func PushSync(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Mutations []syncMutation `json:"mutations" binding:"required,max=500,dive"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		results := make([]syncResult, 0, len(input.Mutations))
+		for _, mutation := range input.Mutations {
+			var result syncResult
+			var recorded []models.TaskEvent
+			switch mutation.Op {
+			case mutationUpsert, mutationDelete:
+				result, recorded = applyMutation(c.Request.Context(), tasks, userID, mutation)
+			default:
+				result, recorded = applyNameMutation(c.Request.Context(), tasks, userID, mutation)
+			}
+			publishTaskEvents(c, broker, recorded...)
+			results = append(results, result)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	}
+}
+
+// applyMutation applies one task mutation in its own transaction, so a
+// conflict on one item does not hold back the rest of the batch.
+func applyMutation(ctx context.Context, tasks *service.TaskService, userID uuid.UUID, mutation syncMutation) (syncResult, []models.TaskEvent) {
+	id := mutation.ID
+	result := syncResult{ID: &id}
+	if mutation.ID == uuid.Nil {
+		result.Status = mutationRejected
+		result.Reason = "id is required"
+		return result, nil
+	}
+	if mutation.Op == mutationUpsert && strings.TrimSpace(mutation.Title) == "" {
+		result.Status = mutationRejected
+		result.Reason = "title is required"
+		return result, nil
+	}
+
+	input := service.TaskInput{
+		Title:       mutation.Title,
+		Description: mutation.Description,
+		Status:      mutation.Status,
+		DueDate:     mutation.DueDate,
+		Priority:    mutation.Priority,
+		Project:     mutation.Project,
+		Labels:      mutation.Labels,
+		Recurrence:  mutation.Recurrence,
+		ParentID:    mutation.ParentID,
+	}
+	if mutation.Op == mutationUpsert {
+		if err := input.Normalize(); err != nil {
+			result.Status = mutationRejected
+			result.Reason = err.Error()
+			return result, nil
+		}
+	}
+
+	var recorded []models.TaskEvent
+	err := tasks.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := tasks.Get(ctx, userID, mutation.ID)
+		found := err == nil
+		switch {
+		case errors.Is(err, service.ErrForbidden):
+			result.Status = mutationRejected
+			result.Reason = "task belongs to another user"
+			return errSyncConflict
+		case err != nil && !errors.Is(err, service.ErrNotFound):
+			return err
+		}
+
+		switch {
+		case found && existing.Version != mutation.BaseVersion:
+			result.Status = mutationConflict
+			result.Reason = "task changed on the server since version " + strconv.Itoa(mutation.BaseVersion)
+			result.Task = &existing
+			return errSyncConflict
+		case !found && mutation.Op == mutationUpsert && mutation.BaseVersion > 0:
+			result.Status = mutationConflict
+			result.Reason = "task was deleted on the server"
+			return errSyncConflict
+		}
+
+		switch {
+		case mutation.Op == mutationDelete && !found:
+			// Already gone; deletes are idempotent.
+		case mutation.Op == mutationDelete:
+			taskEvents, err := tasks.Delete(ctx, userID, existing)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, taskEvents...)
+		case !found:
+			task := service.NewTask(userID, input)
+			task.ID = mutation.ID
+			task, taskEvents, err := tasks.Insert(ctx, task)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, taskEvents...)
+			result.Task = &task
+		default:
+			task, taskEvents, err := tasks.Update(ctx, userID, existing, input)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, taskEvents...)
+			result.Task = &task
+		}
+
+		result.Status = mutationApplied
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errSyncConflict):
+		return result, nil
+	case errors.Is(err, errVersionMismatch):
+		// Another request wrote the task between the check and the write.
+		result = syncResult{ID: &id, Status: mutationConflict, Reason: "task changed on the server"}
+		if task, err := tasks.Get(ctx, userID, mutation.ID); err == nil {
+			result.Task = &task
+		}
+		return result, nil
+	case errors.Is(err, errInvalidParent), errors.Is(err, errHasSubtasks):
+		return syncResult{ID: &id, Status: mutationRejected, Reason: err.Error()}, nil
+	case err != nil:
+		return syncResult{ID: &id, Status: mutationRejected, Reason: "failed to apply mutation"}, nil
+	}
+	return result, recorded
+}
+
+// applyNameMutation renames or deletes a project or label on every task of
+// the user that has it, in one transaction.
+func applyNameMutation(ctx context.Context, tasks *service.TaskService, userID uuid.UUID, mutation syncMutation) (syncResult, []models.TaskEvent) {
+	name := strings.TrimSpace(mutation.Name)
+	newName := strings.TrimSpace(mutation.NewName)
+	isLabel := mutation.Op == mutationRenameLabel || mutation.Op == mutationDeleteLabel
+	if isLabel {
+		name = strings.ToLower(strings.TrimPrefix(name, "#"))
+	}
+	result := syncResult{Name: name}
+	switch {
+	case name == "":
+		result.Status = mutationRejected
+		result.Reason = "name is required"
+		return result, nil
+	case (mutation.Op == mutationRenameProject || mutation.Op == mutationRenameLabel) && newName == "":
+		result.Status = mutationRejected
+		result.Reason = "new_name is required"
+		return result, nil
+	}
+
+	var recorded []models.TaskEvent
+	err := tasks.Transaction(ctx, func(ctx context.Context) error {
+		list, err := tasks.List(ctx, userID, repository.TaskFilter{})
+		if err != nil {
+			return err
+		}
+		for _, task := range list {
+			input := service.InputFromTask(task)
+			switch {
+			case !isLabel && task.Project != name, isLabel && !slices.Contains(task.Labels, name):
+				continue
+			case mutation.Op == mutationRenameProject:
+				input.Project = newName
+			case mutation.Op == mutationDeleteProject:
+				input.Project = ""
+			case mutation.Op == mutationRenameLabel:
+				input.Labels = append(withoutLabels(task.Labels, []string{name}), newName)
+			default:
+				input.Labels = withoutLabels(task.Labels, []string{name})
+			}
+			if err := input.Normalize(); err != nil {
+				return service.ValidationError{Err: err}
+			}
+			_, taskEvents, err := tasks.Update(ctx, userID, task, input)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, taskEvents...)
+		}
+		return nil
+	})
+
+	var invalid service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		result.Status = mutationRejected
+		result.Reason = err.Error()
+		return result, nil
+	case err != nil:
+		result.Status = mutationRejected
+		result.Reason = "failed to apply mutation"
+		return result, nil
+	}
+	result.Status = mutationApplied
+	return result, recorded
+}
+
+func formatSyncToken(cursor uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatUint(cursor, 10)))
+}
+
+func parseSyncToken(token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := strings.CutPrefix(string(raw), syncTokenPrefix)
+	if !ok {
+		return 0, errors.New("unknown sync token version")
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
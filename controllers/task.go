@@ -1,107 +1,190 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
+	"log"
 	"net/http"
+	"to_do_api/events"
 	"to_do_api/models"
+	"to_do_api/service"
 )
 
-func CreateTask(db *gorm.DB) gin.HandlerFunc {
+func CreateTask(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var task models.Task
-		if err := c.ShouldBindJSON(&task); err != nil {
+		var input service.TaskInput
+		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		userID, _ := uuid.Parse(c.GetString("user_id"))
-		task.UserID = userID
 
-		if err := db.Create(&task).Error; err != nil {
+		task, recorded, err := tasks.Create(c.Request.Context(), userID, input)
+		var invalid service.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, errInvalidParent) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 			return
 		}
+		publishTaskEvents(c, broker, recorded...)
 
+		c.Header("ETag", taskETag(task))
 		c.JSON(http.StatusCreated, task)
 	}
 }
 
-func ListTasks(db *gorm.DB) gin.HandlerFunc {
+// ListTasks is given a service that reads from a read replica when one is
+// configured, so a task written a moment ago may be missing until it has
+// replicated.
+func ListTasks(tasks *service.TaskService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var tasks []models.Task
 		userID, _ := uuid.Parse(c.GetString("user_id"))
 
-		if err := db.Where("user_id = ?", userID).Find(&tasks).Error; err != nil {
+		filter, err := parseTaskFilter(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		list, err := tasks.List(c.Request.Context(), userID, filter)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 			return
 		}
 
-		c.JSON(http.StatusOK, tasks)
+		etag := tasksETag(list)
+		if notModified(c, etag) {
+			return
+		}
+
+		c.Header("ETag", etag)
+		c.JSON(http.StatusOK, list)
 	}
 }
 
-func UpdateTask(db *gorm.DB) gin.HandlerFunc {
+func GetTask(tasks *service.TaskService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		taskID, err := uuid.Parse(c.Param("id"))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		task, ok := findOwnedTask(c, tasks, "Not authorized to view this task")
+		if !ok {
 			return
 		}
 
-		var task models.Task
-		if err := db.First(&task, taskID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		etag := taskETag(task)
+		if notModified(c, etag) {
 			return
 		}
 
-		userID, _ := uuid.Parse(c.GetString("user_id"))
-		if task.UserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this task"})
+		c.Header("ETag", etag)
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+// UpdateTask replaces every writable field of the task. Fields left out of
+// the body are reset, so PUT can mark a task incomplete or clear its
+// description.
+func UpdateTask(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := findOwnedTask(c, tasks, "Not authorized to update this task")
+		if !ok {
 			return
 		}
 
-		var updateData models.Task
-		if err := c.ShouldBindJSON(&updateData); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !checkIfMatch(c, taskETag(task)) {
 			return
 		}
 
-		if err := db.Model(&task).Updates(updateData).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		var input service.TaskInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := input.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, task)
+		saveTask(c, tasks, broker, task, input)
+	}
+}
+
+// saveTask writes input over task and responds with the result.
+func saveTask(c *gin.Context, tasks *service.TaskService, broker events.Broker, task models.Task, input service.TaskInput) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	task, recorded, err := tasks.Update(c.Request.Context(), userID, task, input)
+	if errors.Is(err, errVersionMismatch) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has been modified"})
+		return
+	}
+	if errors.Is(err, errInvalidParent) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	publishTaskEvents(c, broker, recorded...)
+
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusOK, task)
 }
 
-func DeleteTask(db *gorm.DB) gin.HandlerFunc {
+func DeleteTask(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		taskID, err := uuid.Parse(c.Param("id"))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		task, ok := findOwnedTask(c, tasks, "Not authorized to delete this task")
+		if !ok {
 			return
 		}
 
-		var task models.Task
-		if err := db.First(&task, taskID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		if !checkIfMatch(c, taskETag(task)) {
 			return
 		}
 
 		userID, _ := uuid.Parse(c.GetString("user_id"))
-		if task.UserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to delete this task"})
+
+		recorded, err := tasks.Delete(c.Request.Context(), userID, task)
+		if errors.Is(err, errVersionMismatch) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has been modified"})
 			return
 		}
-
-		if err := db.Delete(&task).Error; err != nil {
+		if errors.Is(err, errHasSubtasks) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Task has subtasks; delete them first"})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 			return
 		}
+		publishTaskEvents(c, broker, recorded...)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 	}
 }
+
+var (
+	errInvalidParent = service.ErrInvalidParent
+	errHasSubtasks   = service.ErrHasSubtasks
+)
+
+// publishTaskEvents pushes committed events to live streams. Failures are only
+// logged: the change itself succeeded and clients catch up on reconnect.
+func publishTaskEvents(c *gin.Context, broker events.Broker, taskEvents ...models.TaskEvent) {
+	if broker == nil {
+		return
+	}
+	for _, event := range taskEvents {
+		if err := broker.Publish(c.Request.Context(), event); err != nil {
+			log.Println("Failed to publish task event:", err)
+		}
+	}
+}
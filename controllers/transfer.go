@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/service"
+	"to_do_api/transfer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxImportBytes    = 10 << 20
+	maxImportRows     = 10000
+	importSampleCount = 5
+)
+
+type importReport struct {
+	Format    string              `json:"format"`
+	DryRun    bool                `json:"dry_run"`
+	Mapping   map[string]string   `json:"mapping,omitempty"`
+	App       string              `json:"app,omitempty"`
+	AppReport *transfer.AppReport `json:"app_report,omitempty"`
+	Rows      int                 `json:"rows"`
+	Valid     bool                `json:"valid"`
+	Problems  []transfer.Problem  `json:"problems"`
+	Created   int                 `json:"created"`
+	TaskIDs   []uuid.UUID         `json:"task_ids,omitempty"`
+}
+
+// ExportTasks streams the user's tasks, narrowed by the ListTasks filters, as
+// ?format=json (the default), csv, todotxt, markdown or ics. Tasks are read with a
+// cursor and written as they arrive, so large accounts are never held in
+// memory; only markdown, which nests subtasks, collects them first. todo.txt
+// due dates are days in ?timezone=, UTC by default.
+func ExportTasks(tasks *service.TaskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		location := time.UTC
+		if name := c.Query("timezone"); name != "" {
+			var err error
+			if location, err = time.LoadLocation(name); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone: " + name})
+				return
+			}
+		}
+
+		format, err := transfer.DetectFormat(c.DefaultQuery("format", transfer.FormatJSON), "", "",
+			transfer.FormatJSON, transfer.FormatCSV, transfer.FormatTodoTxt, transfer.FormatMarkdown, transfer.FormatICalendar)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		encoder, err := transfer.NewEncoder(format, c.Writer, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		filter, err := parseTaskFilter(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		disposition := `attachment; filename="` + transfer.Filename(format) + `"`
+		streamTasks(c, tasks, userID, filter, encoder, transfer.ContentType(format), disposition)
+	}
+}
+
+// streamTasks writes userID's tasks matching filter through encoder as they
+// are read.
+func streamTasks(c *gin.Context, tasks *service.TaskService, userID uuid.UUID, filter repository.TaskFilter, encoder transfer.Encoder, contentType, disposition string) {
+	started := false
+	start := func() {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", disposition)
+		c.Status(http.StatusOK)
+		started = true
+	}
+
+	err := tasks.Each(c.Request.Context(), userID, filter, func(task models.Task) error {
+		if !started {
+			start()
+		}
+		return encoder.Encode(task)
+	})
+	// Once the first byte is out the status can no longer change, so
+	// failures can only cut the export short.
+	if err != nil {
+		if !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export tasks"})
+			return
+		}
+		log.Println("Failed to export tasks:", err)
+		return
+	}
+	if !started {
+		start()
+	}
+	if err := encoder.Close(); err != nil {
+		log.Println("Failed to finish export:", err)
+	}
+}
+
+// PreviewImport is the column-mapping step: it reads an uploaded CSV or JSON
+// file and returns its columns, the suggested mapping onto task fields and a
+// few sample records, without importing anything.
+func PreviewImport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upload, ok := readImportUpload(c, transfer.FormatCSV, transfer.FormatJSON)
+		if !ok {
+			return
+		}
+
+		table, err := transfer.ReadTable(upload.format, upload.data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		sample := table.Records
+		if len(sample) > importSampleCount {
+			sample = sample[:importSampleCount]
+		}
+		samples := make([]map[string]interface{}, len(sample))
+		for i, record := range sample {
+			samples[i] = record.Values
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"format":            upload.format,
+			"columns":           table.Columns,
+			"fields":            transfer.Fields,
+			"suggested_mapping": transfer.SuggestMapping(table.Columns),
+			"rows":              len(table.Records),
+			"sample":            samples,
+		})
+	}
+}
+
+// ImportTasks imports a CSV, JSON, todo.txt, Markdown checklist or iCalendar
+// file. For CSV and JSON the mapping option is a JSON object from task field
+// to column name and defaults to the suggested mapping. iCalendar imports
+// read VTODOs, and VEVENTs too with the events option. Rows are validated
+// like any other task write; if any row fails nothing is imported and the
+// report lists every problem. With dry_run the report is returned without
+// importing. With the app option the file is another app's export instead;
+// see transfer.AppImporters.
+func ImportTasks(tasks *service.TaskService, broker events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if app := importOption(c, "app"); app != "" {
+			importAppExport(c, tasks, broker, app)
+			return
+		}
+
+		upload, ok := readImportUpload(c, transfer.FormatCSV, transfer.FormatJSON, transfer.FormatTodoTxt, transfer.FormatMarkdown, transfer.FormatICalendar)
+		if !ok {
+			return
+		}
+
+		switch upload.format {
+		case transfer.FormatICalendar:
+			withEvents := false
+			if value := importOption(c, "events"); value != "" {
+				var err error
+				if withEvents, err = strconv.ParseBool(value); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid events"})
+					return
+				}
+			}
+			rows, problems := transfer.ReadICalendar(upload.data, upload.location, withEvents)
+			report := importReport{Format: upload.format, DryRun: upload.dryRun, Rows: len(rows) + len(problems)}
+			commitImport(c, tasks, broker, report, rows, problems)
+			return
+		case transfer.FormatTodoTxt:
+			rows, problems := transfer.ReadTodoTxt(upload.data, upload.location)
+			report := importReport{Format: upload.format, DryRun: upload.dryRun, Rows: len(rows) + len(problems)}
+			commitImport(c, tasks, broker, report, rows, problems)
+			return
+		case transfer.FormatMarkdown:
+			rows, problems := transfer.ReadMarkdown(upload.data)
+			report := importReport{Format: upload.format, DryRun: upload.dryRun, Rows: len(rows) + len(problems)}
+			commitImport(c, tasks, broker, report, rows, problems)
+			return
+		}
+
+		table, err := transfer.ReadTable(upload.format, upload.data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		mapping := transfer.SuggestMapping(table.Columns)
+		if raw := importOption(c, "mapping"); raw != "" {
+			mapping = nil
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of field to column"})
+				return
+			}
+		}
+		if err := transfer.CheckMapping(mapping, table.Columns); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, problems := transfer.Convert(table, mapping, upload.location)
+		report := importReport{Format: upload.format, DryRun: upload.dryRun, Mapping: mapping, Rows: len(table.Records)}
+		commitImport(c, tasks, broker, report, rows, problems)
+	}
+}
+
+func importAppExport(c *gin.Context, tasks *service.TaskService, broker events.Broker, app string) {
+	importer, ok := transfer.AppImporters[app]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown app %q; apps are %s", app, strings.Join(transfer.AppNames(), ", "))})
+		return
+	}
+	upload, ok := readImportUpload(c, importer.Format())
+	if !ok {
+		return
+	}
+
+	rows, problems, appReport := importer.Read(transfer.AppSource{
+		Data:     upload.data,
+		Filename: upload.filename,
+		Location: upload.location,
+		Now:      time.Now(),
+	})
+	report := importReport{Format: upload.format, DryRun: upload.dryRun, App: app, AppReport: &appReport, Rows: len(rows) + len(problems)}
+	commitImport(c, tasks, broker, report, rows, problems)
+}
+
+// errImportCycle reports subtasks in an import that are their own ancestors.
+var errImportCycle = errors.New("subtasks in this import form a cycle")
+
+// commitImport validates rows and, unless this is a dry run or something is
+// invalid, creates them all in one transaction. Parents are created before
+// their subtasks.
+func commitImport(c *gin.Context, tasks *service.TaskService, broker events.Broker, report importReport, rows []transfer.Row, problems []transfer.Problem) {
+	if report.Rows > maxImportRows {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("at most %d tasks per import", maxImportRows)})
+		return
+	}
+
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	batch := make([]models.Task, 0, len(rows))
+	imported := make([]transfer.Row, 0, len(rows))
+	for _, row := range rows {
+		input := service.TaskInput{
+			Title:       row.Title,
+			Description: row.Description,
+			Status:      row.Status,
+			DueDate:     row.DueDate,
+			Priority:    row.Priority,
+			Project:     row.Project,
+			Labels:      row.Labels,
+			Recurrence:  row.Recurrence,
+		}
+		if strings.TrimSpace(input.Title) == "" {
+			problems = append(problems, transfer.Problem{Line: row.Line, Field: "title", Error: "title is required"})
+			continue
+		}
+		if err := input.Normalize(); err != nil {
+			problems = append(problems, transfer.Problem{Line: row.Line, Error: err.Error()})
+			continue
+		}
+		batch = append(batch, service.NewTask(userID, input))
+		imported = append(imported, row)
+	}
+
+	report.Problems = problems
+	if report.Problems == nil {
+		report.Problems = []transfer.Problem{}
+	}
+	report.Valid = len(problems) == 0
+
+	switch {
+	case report.DryRun:
+		c.JSON(http.StatusOK, report)
+		return
+	case !report.Valid:
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	var recorded []models.TaskEvent
+	err := tasks.Transaction(c.Request.Context(), func(ctx context.Context) error {
+		created := make(map[int]uuid.UUID, len(batch))
+		pending := make([]int, len(batch))
+		for i := range pending {
+			pending[i] = i
+		}
+
+		for len(pending) > 0 {
+			var waiting []int
+			for _, i := range pending {
+				task := batch[i]
+				if parentLine := imported[i].ParentLine; parentLine != 0 {
+					parentID, ok := created[parentLine]
+					if !ok {
+						waiting = append(waiting, i)
+						continue
+					}
+					task.ParentID = &parentID
+				}
+
+				task, taskEvents, err := tasks.Insert(ctx, task)
+				if err != nil {
+					return err
+				}
+				created[imported[i].Line] = task.ID
+				recorded = append(recorded, taskEvents...)
+				report.TaskIDs = append(report.TaskIDs, task.ID)
+			}
+			if len(waiting) == len(pending) {
+				return errImportCycle
+			}
+			pending = waiting
+		}
+		return nil
+	})
+	if errors.Is(err, errImportCycle) {
+		report.Valid, report.TaskIDs = false, nil
+		report.Problems = append(report.Problems, transfer.Problem{Error: err.Error()})
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import tasks"})
+		return
+	}
+	publishTaskEvents(c, broker, recorded...)
+
+	report.Created = len(report.TaskIDs)
+	c.JSON(http.StatusCreated, report)
+}
+
+type importUpload struct {
+	data     []byte
+	filename string
+	format   string
+	dryRun   bool
+	location *time.Location
+}
+
+// readImportUpload reads the file from a multipart "file" field or, for any
+// other content type, the raw body, along with the format, dry_run and
+// timezone options shared by every import. With a single format there is
+// nothing to detect, and that format is used unless another is asked for.
+func readImportUpload(c *gin.Context, formats ...string) (importUpload, bool) {
+	var upload importUpload
+	var filename, contentType string
+
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, header, formErr := c.Request.FormFile("file")
+		if formErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+			return upload, false
+		}
+		defer file.Close()
+		filename, contentType = header.Filename, header.Header.Get("Content-Type")
+		upload.data, err = readLimited(file)
+	} else {
+		contentType = c.ContentType()
+		upload.data, err = readLimited(c.Request.Body)
+	}
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return upload, false
+	}
+
+	upload.filename = filename
+	explicit := importOption(c, "format")
+	if explicit == "" && len(formats) == 1 {
+		explicit = formats[0]
+	}
+	if upload.format, err = transfer.DetectFormat(explicit, filename, contentType, formats...); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return upload, false
+	}
+
+	if value := importOption(c, "dry_run"); value != "" {
+		if upload.dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+			return upload, false
+		}
+	}
+
+	upload.location = time.UTC
+	if name := importOption(c, "timezone"); name != "" {
+		if upload.location, err = time.LoadLocation(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone: " + name})
+			return upload, false
+		}
+	}
+	return upload, true
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	if r == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxImportBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportBytes {
+		return nil, errors.New("import files are limited to 10 MB")
+	}
+	return data, nil
+}
+
+// importOption reads an option from the query string or a multipart field.
+func importOption(c *gin.Context, name string) string {
+	if value := c.Query(name); value != "" {
+		return value
+	}
+	return c.PostForm(name)
+}
@@ -1,17 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"to_do_api/auth"
-	"to_do_api/config"
 	"to_do_api/models"
+	"to_do_api/service"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-func Register(db *gorm.DB) gin.HandlerFunc {
+func Register(users *service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var user models.User
 		if err := c.ShouldBindJSON(&user); err != nil {
@@ -19,23 +18,12 @@ func Register(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var existingUser models.User
-		if err := db.Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
+		user, err := users.Register(c.Request.Context(), user.Email, user.Password)
+		if errors.Is(err, service.ErrEmailTaken) {
 			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
 			return
-		} else if err != gorm.ErrRecordNotFound {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-			return
 		}
-
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-			return
-		}
-
-		user.Password = string(hashedPassword)
-		if err := db.Create(&user).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 			return
 		}
@@ -47,7 +35,7 @@ func Register(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-func Login(db *gorm.DB, authService auth.AuthService) gin.HandlerFunc {
+func Login(users *service.UserService, authService auth.AuthService, jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var credentials struct {
 			Email    string `json:"email" binding:"required,email"`
@@ -59,18 +47,17 @@ func Login(db *gorm.DB, authService auth.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		var user models.User
-		if err := db.Where("email = ?", credentials.Email).First(&user).Error; err != nil {
+		user, err := users.Authenticate(c.Request.Context(), credentials.Email, credentials.Password)
+		if errors.Is(err, service.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
-
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credentials.Password)); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 			return
 		}
 
-		token, err := authService.GenerateToken(user.ID, config.LoadConfig().JWT_SECRET)
+		token, err := authService.GenerateToken(user.ID, jwtSecret)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication error"})
 			return
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"to_do_api/models"
+	"to_do_api/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		var input struct {
+			URL    string   `json:"url" binding:"required,url"`
+			Events []string `json:"events" binding:"required,min=1"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, event := range input.Events {
+			if !isWebhookEvent(event) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + event})
+				return
+			}
+		}
+
+		secret, err := generateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		webhook := models.Webhook{
+			UserID: userID,
+			URL:    input.URL,
+			Secret: secret,
+			Events: input.Events,
+			Active: true,
+		}
+		if err := db.Create(&webhook).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+			return
+		}
+
+		// The secret is only ever shown once, on creation.
+		c.JSON(http.StatusCreated, gin.H{
+			"webhook": webhook,
+			"secret":  secret,
+		})
+	}
+}
+
+func ListWebhooks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+
+		var webhooks []models.Webhook
+		if err := db.Where("user_id = ?", userID).Order("created_at").Find(&webhooks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func DeleteWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		webhook, ok := findOwnedWebhook(c, db)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&webhook).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+	}
+}
+
+func ListWebhookDeliveries(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		webhook, ok := findOwnedWebhook(c, db)
+		if !ok {
+			return
+		}
+
+		page, pageSize, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		var deliveries []models.WebhookDelivery
+		err := db.Where("webhook_id = ?", webhook.ID).
+			Order("created_at DESC").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&deliveries).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// RedeliverWebhook queues a fresh copy of an earlier delivery. The original
+// row is left untouched so the log keeps its history.
+func RedeliverWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := db.WithContext(c.Request.Context())
+		webhook, ok := findOwnedWebhook(c, db)
+		if !ok {
+			return
+		}
+
+		deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+			return
+		}
+
+		var original models.WebhookDelivery
+		if err := db.Where("id = ? AND webhook_id = ?", deliveryID, webhook.ID).First(&original).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+			return
+		}
+
+		redelivery, err := webhooks.Redeliver(original)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue redelivery"})
+			return
+		}
+		if err := db.Create(&redelivery).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue redelivery"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, redelivery)
+	}
+}
+
+func findOwnedWebhook(c *gin.Context, db *gorm.DB) (models.Webhook, bool) {
+	var webhook models.Webhook
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return webhook, false
+	}
+
+	if err := db.First(&webhook, webhookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return webhook, false
+	}
+
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	if webhook.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to access this webhook"})
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range models.WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
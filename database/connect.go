@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+	"to_do_api/config"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// readReplicas names the resolver ReadReplica routes to. Queries that do
+// not ask for it stay on the primary, so a client always reads its own
+// writes except where replication lag is acceptable.
+const readReplicas = "read_replicas"
+
+// Backoff spaces out connection attempts: the first retry waits Initial,
+// each later one twice as long up to Max, until Timeout has passed since the
+// first attempt.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Timeout time.Duration
+}
+
+// Retry calls attempt until it succeeds, ctx is done or the backoff's
+// timeout would pass before the next attempt, and returns the last error.
+func Retry(ctx context.Context, backoff Backoff, attempt func() error) error {
+	deadline := time.Now().Add(backoff.Timeout)
+	wait := backoff.Initial
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", n, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > backoff.Max {
+			wait = backoff.Max
+		}
+	}
+}
+
+// Connect opens the database, retrying for DB_CONNECT_TIMEOUT while it is
+// not accepting connections yet, and applies the pool limits, statement
+// timeout and read replicas from cfg.
+func Connect(cfg *config.Config) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := Retry(context.Background(), Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Timeout: cfg.DB_CONNECT_TIMEOUT}, func() error {
+		var err error
+		db, err = Open(cfg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.DB_MAX_OPEN_CONNS)
+	sqlDB.SetMaxIdleConns(cfg.DB_MAX_IDLE_CONNS)
+	sqlDB.SetConnMaxLifetime(cfg.DB_CONN_MAX_LIFETIME)
+	sqlDB.SetConnMaxIdleTime(cfg.DB_CONN_MAX_IDLE_TIME)
+
+	if cfg.DB_STATEMENT_TIMEOUT > 0 {
+		if err := db.Use(StatementTimeout(cfg.DB_STATEMENT_TIMEOUT)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.DB_READ_REPLICAS) > 0 {
+		var replicas []gorm.Dialector
+		for _, address := range cfg.DB_READ_REPLICAS {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				host, port = address, cfg.DB_PORT
+			}
+			replicas = append(replicas, postgres.Open(dsn(cfg, host, port)))
+		}
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}, readReplicas)
+		resolver.SetMaxOpenConns(cfg.DB_MAX_OPEN_CONNS).
+			SetMaxIdleConns(cfg.DB_MAX_IDLE_CONNS).
+			SetConnMaxLifetime(cfg.DB_CONN_MAX_LIFETIME).
+			SetConnMaxIdleTime(cfg.DB_CONN_MAX_IDLE_TIME)
+		if err := db.Use(resolver); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// ReadReplica returns a handle whose queries go to a read replica, if any
+// are configured, and may therefore miss the latest writes. Writes and
+// transactions through it still go to the primary.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(readReplicas))
+}
@@ -1,27 +1,60 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
 	"to_do_api/config"
-	"to_do_api/models"
 )
 
-func InitDB(cfg *config.Config) *gorm.DB {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DB_HOST, cfg.DB_PORT, cfg.DB_USER, cfg.DB_PASSWORD, cfg.DB_NAME)
+func DSN(cfg *config.Config) string {
+	return dsn(cfg, cfg.DB_HOST, cfg.DB_PORT)
+}
+
+// dsn addresses the server at host and port with the primary's credentials,
+// which read replicas share.
+func dsn(cfg *config.Config, host, port string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host, port, cfg.DB_USER, cfg.DB_PASSWORD, cfg.DB_NAME)
+}
+
+// Open connects to the database DB_DRIVER selects without touching its
+// schema.
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	switch cfg.DB_DRIVER {
+	case "postgres":
+		return gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{})
+	case "sqlite":
+		return OpenSQLite(cfg.DB_PATH)
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q: use postgres or sqlite", cfg.DB_DRIVER)
+	}
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// InitDB connects to the database, waiting for it to come up, and, unless
+// AUTO_MIGRATE is off, applies pending migrations.
+func InitDB(cfg *config.Config) *gorm.DB {
+	db, err := Connect(cfg)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Task{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	if cfg.AUTO_MIGRATE {
+		if err := Migrate(db); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
 
 	return db
 }
+
+// Ping checks that the primary database answers.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,392 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var embeddedMigrations embed.FS
+
+// Migrations holds the schema migrations shipped with the application, one
+// directory per dialect:
+//
+//	postgres/20261019120000_initial.up.sql
+//	postgres/20261019120000_initial.down.sql
+//
+// Statements in a file are separated by a semicolon at the end of a line.
+// Each migration runs in a transaction with its schema_migrations row.
+// SQLite lacks ALTER TABLE ... ADD COLUMN IF NOT EXISTS, so the migrator
+// checks for the column itself there.
+var Migrations, _ = fs.Sub(embeddedMigrations, "migrations")
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var addColumnIfNotExists = regexp.MustCompile(`(?is)^ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+) (.*)$`)
+
+// migrationLockKey names the Postgres advisory lock held while migrating, so
+// that replicas starting together apply each migration once.
+const migrationLockKey = 7_461_829_310
+
+// Migration is one versioned, reversible schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied. A version
+// recorded in the database without files in this build is Missing.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+// Migrate applies every pending migration.
+func Migrate(db *gorm.DB) error {
+	_, err := NewMigrator(db, Migrations).Up(0)
+	return err
+}
+
+// Migrator applies the migrations in a file system laid out like
+// Migrations to a database, recording them in schema_migrations.
+type Migrator struct {
+	db   *gorm.DB
+	fsys fs.FS
+}
+
+func NewMigrator(db *gorm.DB, fsys fs.FS) *Migrator {
+	return &Migrator{db: db, fsys: fsys}
+}
+
+// Migrations reads the migrations for the database's dialect, oldest first.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	return ReadMigrations(m.fsys, m.db.Dialector.Name())
+}
+
+// Up applies up to steps pending migrations, or all of them if steps is not
+// positive, and returns those it applied.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func(db *gorm.DB) error {
+		migrations, done, err := m.load(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+			if err := run(db, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back up to steps applied migrations, newest first, or all of
+// them if steps is not positive, and returns those it rolled back.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(func(db *gorm.DB) error {
+		migrations, done, err := m.load(db)
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]Migration, len(migrations))
+		for _, migration := range migrations {
+			byVersion[migration.Version] = migration
+		}
+
+		versions := make([]int64, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions {
+			if steps > 0 && len(reverted) == steps {
+				break
+			}
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but has no files to roll it back", version)
+			}
+			if err := run(db, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration, applied or not, by version.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := ensureMigrationTable(m.db); err != nil {
+		return nil, err
+	}
+	migrations, done, err := m.load(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range done {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func ensureMigrationTable(db *gorm.DB) error {
+	return db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamp NOT NULL)").Error
+}
+
+// load reads the migrations and the versions already applied.
+func (m *Migrator) load(db *gorm.DB) ([]Migration, map[int64]schemaMigration, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []schemaMigration
+	if err := db.Raw("SELECT version, name, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	done := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return migrations, done, nil
+}
+
+// locked runs fn while holding the migration lock. On Postgres that is a
+// session advisory lock, so fn gets a handle on the one connection holding
+// it. SQLite already allows one writer at a time; a second process that
+// raced to the same migration fails on its schema_migrations row and rolls
+// back.
+func (m *Migrator) locked(fn func(db *gorm.DB) error) error {
+	ctx := m.db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Migrations may rewrite large tables, so they outlast the statement
+	// timeout requests are held to.
+	ctx = WithoutStatementTimeout(ctx)
+	if err := ensureMigrationTable(m.db.WithContext(ctx)); err != nil {
+		return err
+	}
+	if m.db.Dialector.Name() != "postgres" {
+		return fn(m.db.WithContext(ctx))
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", int64(migrationLockKey)); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", int64(migrationLockKey))
+
+	session := m.db.Session(&gorm.Session{NewDB: true, Context: ctx})
+	session.Statement.ConnPool = conn
+	return fn(session)
+}
+
+// run applies or rolls back one migration together with its
+// schema_migrations row.
+func run(db *gorm.DB, migration Migration, up bool) error {
+	script, direction := migration.Up, "up"
+	if !up {
+		script, direction = migration.Down, "down"
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := execStatement(tx, statement); err != nil {
+				return fmt.Errorf("migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+			}
+		}
+		if up {
+			return tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC()).Error
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+	})
+}
+
+// execStatement runs one statement of a migration.
+func execStatement(tx *gorm.DB, statement string) error {
+	if tx.Dialector.Name() == "sqlite" {
+		if match := addColumnIfNotExists.FindStringSubmatch(statement); match != nil {
+			if tx.Migrator().HasColumn(match[1], match[2]) {
+				return nil
+			}
+			statement = "ALTER TABLE " + match[1] + " ADD COLUMN " + match[2] + " " + match[3]
+		}
+	}
+	return tx.Exec(statement).Error
+}
+
+// splitStatements splits a script at semicolons that end a line, dropping
+// comment lines.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// ReadMigrations reads the migrations for dialect from fsys, oldest first.
+// Every version needs both an up and a down file.
+func ReadMigrations(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	files := map[int64]int{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s/%s: want <version>_<name>.up.sql or .down.sql", dialect, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s/%s: %w", dialect, entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, dialect+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		files[version]++
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if files[migration.Version] != 2 {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// CreateMigration writes empty up and down files for a new migration into
+// every dialect directory under dir, versioned by the time now, and returns
+// their paths.
+func CreateMigration(dir, name string, now time.Time) ([]string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use letters, digits and _", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	version := now.UTC().Format("20060102150405")
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, entry.Name(), fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return paths, err
+			}
+			_, err = fmt.Fprintf(file, "-- %s %s (%s)\n", name, direction, entry.Name())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no dialect directories in %s", dir)
+	}
+	return paths, nil
+}
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS cal_dav_resources;
+DROP TABLE IF EXISTS app_passwords;
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS smart_list_shares;
+DROP TABLE IF EXISTS smart_lists;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS task_events;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS reminders;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS users;
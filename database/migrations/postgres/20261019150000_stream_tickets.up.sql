@@ -0,0 +1,7 @@
+CREATE TABLE stream_tickets (
+    ticket_hash text,
+    user_id uuid NOT NULL,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (ticket_hash)
+);
+CREATE INDEX idx_stream_tickets_expires_at ON stream_tickets (expires_at);
@@ -0,0 +1,194 @@
+-- The schema as AutoMigrate left it. Every statement is IF NOT EXISTS so
+-- that databases created before versioned migrations adopt this version
+-- without changes, whichever columns they had reached.
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid,
+    email text,
+    password text NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id uuid,
+    title text NOT NULL,
+    description text,
+    status numeric DEFAULT false,
+    due_date datetime,
+    priority integer NOT NULL DEFAULT 0,
+    project text,
+    labels text,
+    recurrence text,
+    parent_id uuid,
+    user_id uuid NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+-- The first schema had only id, title, description, status and user_id;
+-- later columns were added as tasks gained fields.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_date datetime;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority integer NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project text;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS labels text;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence text;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id uuid;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_at datetime;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS updated_at datetime;
+CREATE INDEX IF NOT EXISTS idx_tasks_project ON tasks (project);
+CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks (due_date);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks (parent_id);
+
+CREATE TABLE IF NOT EXISTS reminders (
+    id uuid,
+    task_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    remind_at datetime,
+    offset_minutes integer,
+    fire_at datetime NOT NULL,
+    channel text NOT NULL,
+    webhook_url text,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    locked_until datetime,
+    sent_at datetime,
+    last_error text,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_status ON reminders (status);
+CREATE INDEX IF NOT EXISTS idx_reminders_fire_at ON reminders (fire_at);
+CREATE INDEX IF NOT EXISTS idx_reminders_user_id ON reminders (user_id);
+CREATE INDEX IF NOT EXISTS idx_reminders_task_id ON reminders (task_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid,
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    title text NOT NULL,
+    body text,
+    task_id uuid,
+    read_at datetime,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications (created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id uuid,
+    user_id uuid NOT NULL,
+    event_type text NOT NULL,
+    enabled numeric NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preference ON notification_preferences (user_id, event_type);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid,
+    user_id uuid NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text NOT NULL,
+    active numeric NOT NULL,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id uuid,
+    webhook_id uuid NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime NOT NULL,
+    response_status integer,
+    last_error text,
+    delivered_at datetime,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+
+CREATE TABLE IF NOT EXISTS task_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id uuid NOT NULL,
+    task_id uuid NOT NULL,
+    type text NOT NULL,
+    payload text NOT NULL,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_task_events_user_id ON task_events (user_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id uuid,
+    idempotency_key text,
+    request_hash text NOT NULL,
+    status text NOT NULL,
+    response_status integer,
+    content_type text,
+    etag text,
+    response_body blob,
+    created_at datetime,
+    expires_at datetime NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS smart_lists (
+    id uuid,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    query text NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_smart_lists_user_id ON smart_lists (user_id);
+
+CREATE TABLE IF NOT EXISTS smart_list_shares (
+    smart_list_id uuid,
+    user_id uuid,
+    created_at datetime,
+    PRIMARY KEY (smart_list_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_smart_list_shares_user_id ON smart_list_shares (user_id);
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id uuid,
+    user_id uuid NOT NULL,
+    token_hash text NOT NULL,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token_hash ON calendar_feeds (token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user_id ON calendar_feeds (user_id);
+
+CREATE TABLE IF NOT EXISTS app_passwords (
+    id uuid,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    password_hash text NOT NULL,
+    last_used_at datetime,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_passwords_password_hash ON app_passwords (password_hash);
+CREATE INDEX IF NOT EXISTS idx_app_passwords_user_id ON app_passwords (user_id);
+
+CREATE TABLE IF NOT EXISTS cal_dav_resources (
+    task_id uuid,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    uid text NOT NULL,
+    PRIMARY KEY (task_id)
+);
+CREATE INDEX IF NOT EXISTS idx_caldav_resources_user_uid ON cal_dav_resources (user_id, uid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_caldav_resources_user_name ON cal_dav_resources (user_id, name);
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const statementTimeoutCancel = "database:statement_timeout_cancel"
+
+type noStatementTimeoutKey struct{}
+
+// WithoutStatementTimeout marks ctx so that statements run with it are not
+// limited by the statement timeout, for work such as migrations that is
+// expected to take long.
+func WithoutStatementTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStatementTimeoutKey{}, true)
+}
+
+// StatementTimeout is a GORM plugin that cancels each create, query, update,
+// delete and raw statement after the given duration. A statement whose
+// context has an earlier deadline, such as a request's, keeps it. Row, Rows
+// and Scan are not limited, as their results are read after they return.
+type StatementTimeout time.Duration
+
+func (t StatementTimeout) Name() string {
+	return "database:statement_timeout"
+}
+
+func (t StatementTimeout) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("database:start_timeout", t.start),
+		callbacks.Create().After("gorm:create").Register("database:stop_timeout", stopTimeout),
+		callbacks.Query().Before("gorm:query").Register("database:start_timeout", t.start),
+		callbacks.Query().After("gorm:query").Register("database:stop_timeout", stopTimeout),
+		callbacks.Update().Before("gorm:update").Register("database:start_timeout", t.start),
+		callbacks.Update().After("gorm:update").Register("database:stop_timeout", stopTimeout),
+		callbacks.Delete().Before("gorm:delete").Register("database:start_timeout", t.start),
+		callbacks.Delete().After("gorm:delete").Register("database:stop_timeout", stopTimeout),
+		callbacks.Raw().Before("gorm:raw").Register("database:start_timeout", t.start),
+		callbacks.Raw().After("gorm:raw").Register("database:stop_timeout", stopTimeout),
+	)
+}
+
+// timedStatement remembers what stopTimeout needs to undo.
+type timedStatement struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (t StatementTimeout) start(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Value(noStatementTimeoutKey{}) != nil {
+		return
+	}
+	deadline := time.Now().Add(time.Duration(t))
+	if current, ok := ctx.Deadline(); ok && current.Before(deadline) {
+		return
+	}
+
+	timed, cancel := context.WithDeadline(ctx, deadline)
+	db.InstanceSet(statementTimeoutCancel, timedStatement{ctx: db.Statement.Context, cancel: cancel})
+	db.Statement.Context = timed
+}
+
+func stopTimeout(db *gorm.DB) {
+	value, _ := db.InstanceGet(statementTimeoutCancel)
+	timed, ok := value.(timedStatement)
+	if !ok {
+		return
+	}
+	timed.cancel()
+	db.Statement.Context = timed.ctx
+	db.InstanceSet(statementTimeoutCancel, nil)
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Broker fans task events out to the streams open on this instance.
+// Implementations that span several instances must deliver an event
+// published on any of them to subscribers on all of them.
+type Broker interface {
+	Publish(ctx context.Context, event models.TaskEvent) error
+	// Subscribe returns a channel of events for one user. The channel is
+	// closed when ctx is done, or early if the subscriber falls too far
+	// behind; clients are expected to reconnect with their last event ID.
+	Subscribe(ctx context.Context, userID string) <-chan models.TaskEvent
+}
+
+// userLockClass namespaces the Postgres advisory locks taken by Record.
+const userLockClass = 1_952_805_748
+
+// Record appends a task change to the event log. Call it with the
+// transaction that makes the change, then Publish the result after commit.
+//
+// A user's events are recorded one transaction at a time, so their IDs are
+// in commit order: once an ID is visible, so are all of the user's lower
+// ones, and ID cursors never step over an event that commits late.
+func Record(tx *gorm.DB, eventType string, task models.Task) (models.TaskEvent, error) {
+	if err := lockUser(tx, task.UserID); err != nil {
+		return models.TaskEvent{}, err
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return models.TaskEvent{}, err
+	}
+
+	event := models.TaskEvent{
+		UserID:  task.UserID,
+		TaskID:  task.ID,
+		Type:    eventType,
+		Payload: string(payload),
+	}
+	err = tx.Create(&event).Error
+	return event, err
+}
+
+// lockUser holds a lock on the user's event log until tx ends. SQLite runs
+// one write transaction at a time, so only Postgres needs it.
+func lockUser(tx *gorm.DB, userID uuid.UUID) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	key := int32(binary.BigEndian.Uint32(userID[:4]))
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", userLockClass, key).Error
+}
+
+// Latest returns the ID of the user's last event, or 0 if there is none.
+// Because of the lock Record takes, no event of the user with a lower ID
+// can still commit, so it is a safe cursor to resume from.
+func Latest(db *gorm.DB, userID string) (uint64, error) {
+	var latest uint64
+	err := db.Model(&models.TaskEvent{}).Where("user_id = ?", userID).Select("COALESCE(MAX(id), 0)").Scan(&latest).Error
+	return latest, err
+}
+
+// Since returns the user's events after lastID in order, for replaying to a
+// client that reconnects with Last-Event-ID.
+func Since(db *gorm.DB, userID string, lastID uint64, limit int) ([]models.TaskEvent, error) {
+	var events []models.TaskEvent
+	err := db.Where("user_id = ? AND id > ?", userID, lastID).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
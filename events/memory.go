@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"to_do_api/models"
+)
+
+const subscriberBuffer = 64
+
+type subscriber struct {
+	mu     sync.Mutex
+	ch     chan models.TaskEvent
+	closed bool
+}
+
+// send delivers without blocking. A stalled client must not hold up
+// publishers, so if its buffer is full it is dropped instead; the client
+// reconnects and the replay from Last-Event-ID fills the gap.
+func (s *subscriber) send(event models.TaskEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// MemoryBroker delivers events to subscribers in the same process only.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*subscriber]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string]map[*subscriber]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event models.TaskEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers[event.UserID.String()] {
+		sub.send(event)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, userID string) <-chan models.TaskEvent {
+	sub := &subscriber{ch: make(chan models.TaskEvent, subscriberBuffer)}
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*subscriber]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers[userID], sub)
+		if len(b.subscribers[userID]) == 0 {
+			delete(b.subscribers, userID)
+		}
+		b.mu.Unlock()
+		sub.close()
+	}()
+
+	return sub.ch
+}
+
+// hasSubscribers reports whether anyone in this process follows userID's
+// events.
+func (b *MemoryBroker) hasSubscribers(userID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers[userID]) > 0
+}
+
+// CloseAll drops every subscriber, making clients reconnect and replay from
+// their last event ID.
+func (b *MemoryBroker) CloseAll() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+}
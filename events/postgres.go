@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"to_do_api/models"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	notifyChannel   = "task_events"
+	reconnectDelay  = time.Second
+	maxReconnectGap = 30 * time.Second
+)
+
+// PostgresBroker fans events out across API instances with LISTEN/NOTIFY.
+// Only the user and event IDs travel through NOTIFY, which keeps payloads
+// well under Postgres' 8000 byte limit; an instance with local subscribers
+// for the user loads the event from the log and hands it to them.
+type PostgresBroker struct {
+	db        *gorm.DB
+	dsn       string
+	local     *MemoryBroker
+	listening atomic.Bool
+}
+
+func NewPostgresBroker(db *gorm.DB, dsn string) *PostgresBroker {
+	return &PostgresBroker{db: db, dsn: dsn, local: NewMemoryBroker()}
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, event models.TaskEvent) error {
+	payload := event.UserID.String() + ":" + strconv.FormatUint(event.ID, 10)
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", notifyChannel, payload).Error
+}
+
+func (b *PostgresBroker) Subscribe(ctx context.Context, userID string) <-chan models.TaskEvent {
+	return b.local.Subscribe(ctx, userID)
+}
+
+// Ready reports whether the LISTEN connection is up. Until it is, events
+// published on other instances do not reach this one.
+func (b *PostgresBroker) Ready() error {
+	if !b.listening.Load() {
+		return errors.New("not listening for events")
+	}
+	return nil
+}
+
+// CloseAll drops every local subscriber, as MemoryBroker.CloseAll does.
+func (b *PostgresBroker) CloseAll() {
+	b.local.CloseAll()
+}
+
+// Run holds a dedicated LISTEN connection until ctx is cancelled,
+// reconnecting with backoff if it drops.
+func (b *PostgresBroker) Run(ctx context.Context) {
+	delay := reconnectDelay
+	for {
+		listened, err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("Event broker: listener stopped:", err)
+		// Back off only while reconnecting keeps failing.
+		if listened {
+			delay = reconnectDelay
+		}
+
+		// Notifications sent while disconnected are lost, so make every
+		// client resume from its Last-Event-ID.
+		b.local.CloseAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectGap {
+			delay = maxReconnectGap
+		}
+	}
+}
+
+// listen relays notifications until the connection fails, and reports
+// whether it got as far as listening.
+func (b *PostgresBroker) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return false, err
+	}
+	b.listening.Store(true)
+	defer b.listening.Store(false)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		// A payload without a user comes from an instance running an older
+		// version, and is loaded regardless.
+		userID, eventID, ok := strings.Cut(notification.Payload, ":")
+		if !ok {
+			eventID = notification.Payload
+		} else if !b.local.hasSubscribers(userID) {
+			continue
+		}
+		id, err := strconv.ParseUint(eventID, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var event models.TaskEvent
+		if err := b.db.WithContext(ctx).First(&event, id).Error; err != nil {
+			log.Println("Event broker: failed to load event:", err)
+			continue
+		}
+		b.local.Publish(ctx, event)
+	}
+}
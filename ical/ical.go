@@ -0,0 +1,268 @@
+// Package ical reads and writes the parts of iCalendar (RFC 5545) that tasks
+// need: components and properties, TEXT escaping, DATE and DATE-TIME values
+// and the time zones they refer to.
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the longest content line, in octets, before folding.
+const maxLineLength = 75
+
+// Property is one content line. Parameter names are upper case; quoted
+// parameter values are unquoted. Value is raw, still escaped if it is TEXT.
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block such as VCALENDAR or VTODO. Line is the
+// line its BEGIN is on.
+type Component struct {
+	Name       string
+	Line       int
+	Properties []Property
+	Components []*Component
+}
+
+// Get returns the first property called name.
+func (c *Component) Get(name string) (Property, bool) {
+	for _, property := range c.Properties {
+		if property.Name == name {
+			return property, true
+		}
+	}
+	return Property{}, false
+}
+
+// All returns every property called name.
+func (c *Component) All(name string) []Property {
+	var properties []Property
+	for _, property := range c.Properties {
+		if property.Name == name {
+			properties = append(properties, property)
+		}
+	}
+	return properties
+}
+
+// Decode reads the top-level components of an iCalendar stream, usually a
+// single VCALENDAR. Lines may end in CRLF or LF.
+func Decode(r io.Reader) ([]*Component, error) {
+	var top []*Component
+	var stack []*Component
+
+	lines := newLineReader(r)
+	for {
+		line, text, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		property, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch property.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(property.Value), Line: line}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			} else {
+				top = append(top, component)
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(property.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", line, property.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: %s outside a component", line, property.Name)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, property)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("line %d: %s is never closed", stack[len(stack)-1].Line, stack[len(stack)-1].Name)
+	}
+	return top, nil
+}
+
+// lineReader unfolds content lines, remembering where each one started.
+type lineReader struct {
+	scanner *bufio.Scanner
+	line    int
+	pending string
+	start   int
+	primed  bool
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &lineReader{scanner: scanner}
+}
+
+func (l *lineReader) next() (int, string, error) {
+	for l.scanner.Scan() {
+		l.line++
+		text := strings.TrimSuffix(l.scanner.Text(), "\r")
+		if l.line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if l.primed && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			l.pending += text[1:]
+			continue
+		}
+		line, folded, primed := l.start, l.pending, l.primed
+		l.start, l.pending, l.primed = l.line, text, true
+		if primed {
+			return line, folded, nil
+		}
+	}
+	if err := l.scanner.Err(); err != nil {
+		return 0, "", err
+	}
+	if l.primed {
+		l.primed = false
+		return l.start, l.pending, nil
+	}
+	return 0, "", io.EOF
+}
+
+// parseLine splits name *(";" param) ":" value, honouring quoted parameter
+// values, which may contain ':' and ';'.
+func parseLine(text string) (Property, error) {
+	property := Property{Params: map[string]string{}}
+
+	end := strings.IndexAny(text, ";:")
+	if end <= 0 {
+		return Property{}, fmt.Errorf("invalid content line %q", truncate(text))
+	}
+	property.Name = strings.ToUpper(text[:end])
+	rest := text[end:]
+
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return Property{}, fmt.Errorf("invalid parameter in %s", property.Name)
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value strings.Builder
+		for len(rest) > 0 && rest[0] != ';' && rest[0] != ':' {
+			if rest[0] == '"' {
+				closing := strings.IndexByte(rest[1:], '"')
+				if closing < 0 {
+					return Property{}, fmt.Errorf("unterminated quote in %s", property.Name)
+				}
+				value.WriteString(rest[1 : closing+1])
+				rest = rest[closing+2:]
+				continue
+			}
+			next := strings.IndexAny(rest, `";:`)
+			if next < 0 {
+				next = len(rest)
+			}
+			value.WriteString(rest[:next])
+			rest = rest[next:]
+		}
+		property.Params[name] = value.String()
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return Property{}, fmt.Errorf("%s has no value", property.Name)
+	}
+	property.Value = rest[1:]
+	return property, nil
+}
+
+func truncate(text string) string {
+	if len(text) > 40 {
+		return text[:40] + "..."
+	}
+	return text
+}
+
+// Writer writes content lines, folding them at 75 octets. The first error
+// sticks and is returned by Flush.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) Begin(name string) {
+	w.Property("BEGIN", name)
+}
+
+func (w *Writer) End(name string) {
+	w.Property("END", name)
+}
+
+// Property writes a raw value. Each param is a whole "NAME=value" pair.
+func (w *Writer) Property(name, value string, params ...string) {
+	var line bytes.Buffer
+	line.WriteString(name)
+	for _, param := range params {
+		line.WriteByte(';')
+		line.WriteString(param)
+	}
+	line.WriteByte(':')
+	line.WriteString(value)
+	w.writeFolded(line.String())
+}
+
+// Text writes a TEXT value, escaping it.
+func (w *Writer) Text(name, text string, params ...string) {
+	w.Property(name, EscapeText(text), params...)
+}
+
+func (w *Writer) writeFolded(line string) {
+	if w.err != nil {
+		return
+	}
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		_, w.err = w.w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines spend one octet on the leading space.
+		limit = maxLineLength - 1
+	}
+	if w.err == nil {
+		_, w.err = w.w.WriteString(line + "\r\n")
+	}
+}
+
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
@@ -0,0 +1,277 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zones resolves the TZIDs of one calendar. IANA names such as Europe/Berlin
+// come from the system time zone database. Other names, like Outlook's
+// "W. Europe Standard Time", are looked up in the calendar's VTIMEZONE
+// definitions: first through the IANA name many exporters put in
+// X-LIC-LOCATION or at the end of the TZID, then by evaluating the
+// definition's STANDARD and DAYLIGHT observances.
+type Zones struct {
+	definitions map[string]*Component
+	resolvers   map[string]func(wall time.Time) time.Time
+}
+
+// NewZones collects the VTIMEZONE definitions in calendar, which may be nil.
+func NewZones(calendar *Component) *Zones {
+	zones := &Zones{
+		definitions: map[string]*Component{},
+		resolvers:   map[string]func(time.Time) time.Time{},
+	}
+	if calendar != nil {
+		for _, component := range calendar.Components {
+			if component.Name != "VTIMEZONE" {
+				continue
+			}
+			if tzid, ok := component.Get("TZID"); ok {
+				zones.definitions[tzid.Value] = component
+			}
+		}
+	}
+	return zones
+}
+
+// Resolve returns the instant a wall clock time in tzid refers to. The wall
+// time's own location is ignored.
+func (z *Zones) Resolve(tzid string, wall time.Time) (time.Time, error) {
+	resolve, ok := z.resolvers[tzid]
+	if !ok {
+		var err error
+		if resolve, err = z.resolver(tzid); err != nil {
+			return time.Time{}, err
+		}
+		z.resolvers[tzid] = resolve
+	}
+	return resolve(wall), nil
+}
+
+func (z *Zones) resolver(tzid string) (func(time.Time) time.Time, error) {
+	candidates := []string{tzid}
+	definition := z.definitions[tzid]
+	if definition != nil {
+		if location, ok := definition.Get("X-LIC-LOCATION"); ok {
+			candidates = append(candidates, location.Value)
+		}
+	}
+	// e.g. /mozilla.org/20050126_1/America/Argentina/Buenos_Aires
+	if parts := strings.Split(strings.Trim(tzid, "/"), "/"); len(parts) > 2 {
+		candidates = append(candidates, strings.Join(parts[len(parts)-3:], "/"), strings.Join(parts[len(parts)-2:], "/"))
+	}
+
+	for _, name := range candidates {
+		if name == "" || strings.EqualFold(name, "Local") {
+			continue
+		}
+		if location, err := time.LoadLocation(name); err == nil {
+			return func(wall time.Time) time.Time { return inLocation(wall, location) }, nil
+		}
+	}
+
+	if definition == nil {
+		return nil, fmt.Errorf("unknown time zone %q", tzid)
+	}
+	var observances []observance
+	for _, component := range definition.Components {
+		if component.Name != "STANDARD" && component.Name != "DAYLIGHT" {
+			continue
+		}
+		o, err := parseObservance(component)
+		if err != nil {
+			return nil, fmt.Errorf("time zone %q: %w", tzid, err)
+		}
+		observances = append(observances, o)
+	}
+	if len(observances) == 0 {
+		return nil, fmt.Errorf("time zone %q has no observances", tzid)
+	}
+	return func(wall time.Time) time.Time {
+		offset := offsetAt(observances, wall)
+		return inLocation(wall, time.FixedZone(tzid, offset))
+	}, nil
+}
+
+func inLocation(wall time.Time, location *time.Location) time.Time {
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, location)
+}
+
+// observance is one STANDARD or DAYLIGHT block. Only the yearly rules time
+// zones use are understood: BYMONTH with BYDAY such as -1SU (the last
+// Sunday) or 2SU, or BYMONTHDAY with a plain BYDAY.
+type observance struct {
+	start      time.Time // wall clock, in UTC
+	offsetFrom int
+	offsetTo   int
+	yearly     bool
+	month      time.Month
+	week       int
+	weekday    *time.Weekday
+	monthDays  []int
+	until      time.Time
+}
+
+func parseObservance(component *Component) (observance, error) {
+	var o observance
+	dtstart, ok := component.Get("DTSTART")
+	if !ok {
+		return o, fmt.Errorf("%s has no DTSTART", component.Name)
+	}
+	start, err := time.Parse(dateTimeLayout, strings.TrimSuffix(dtstart.Value, "Z"))
+	if err != nil {
+		return o, fmt.Errorf("invalid %s DTSTART %q", component.Name, dtstart.Value)
+	}
+	o.start = start
+
+	for _, name := range []string{"TZOFFSETFROM", "TZOFFSETTO"} {
+		property, ok := component.Get(name)
+		if !ok {
+			return o, fmt.Errorf("%s has no %s", component.Name, name)
+		}
+		offset, err := parseOffset(property.Value)
+		if err != nil {
+			return o, err
+		}
+		if name == "TZOFFSETFROM" {
+			o.offsetFrom = offset
+		} else {
+			o.offsetTo = offset
+		}
+	}
+
+	rrule, ok := component.Get("RRULE")
+	if !ok {
+		return o, nil
+	}
+	o.month = start.Month()
+	for _, part := range strings.Split(strings.ToUpper(rrule.Value), ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			o.yearly = value == "YEARLY"
+		case "BYMONTH":
+			month, err := strconv.Atoi(value)
+			if err != nil || month < 1 || month > 12 {
+				return o, fmt.Errorf("invalid BYMONTH %q", value)
+			}
+			o.month = time.Month(month)
+		case "BYDAY":
+			code := value[max(len(value)-2, 0):]
+			day, ok := weekdayCodes[code]
+			if !ok {
+				return o, fmt.Errorf("invalid BYDAY %q", value)
+			}
+			o.weekday = &day
+			if n := strings.TrimSuffix(value, code); n != "" {
+				if o.week, err = strconv.Atoi(n); err != nil || o.week == 0 || o.week < -5 || o.week > 5 {
+					return o, fmt.Errorf("invalid BYDAY %q", value)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, field := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(field)
+				if err != nil || day < 1 || day > 31 {
+					return o, fmt.Errorf("invalid BYMONTHDAY %q", value)
+				}
+				o.monthDays = append(o.monthDays, day)
+			}
+		case "UNTIL":
+			if o.until, err = time.Parse(dateTimeLayout, strings.TrimSuffix(value, "Z")); err != nil {
+				return o, fmt.Errorf("invalid UNTIL %q", value)
+			}
+		}
+	}
+	return o, nil
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseOffset reads a UTC offset such as +0100 or -053000 as seconds.
+func parseOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// onset returns when o takes effect in year, as a wall clock time.
+func (o observance) onset(year int) (time.Time, bool) {
+	if !o.yearly {
+		return o.start, true
+	}
+
+	day := o.start.Day()
+	switch {
+	case o.weekday != nil && o.week > 0:
+		first := time.Date(year, o.month, 1, 0, 0, 0, 0, time.UTC)
+		day = 1 + (int(*o.weekday)-int(first.Weekday())+7)%7 + (o.week-1)*7
+	case o.weekday != nil && o.week < 0:
+		last := time.Date(year, o.month+1, 0, 0, 0, 0, 0, time.UTC)
+		day = last.Day() - (int(last.Weekday())-int(*o.weekday)+7)%7 + (o.week+1)*7
+	case o.weekday != nil && len(o.monthDays) > 0:
+		day = 0
+		for _, monthDay := range o.monthDays {
+			if time.Date(year, o.month, monthDay, 0, 0, 0, 0, time.UTC).Weekday() == *o.weekday {
+				day = monthDay
+				break
+			}
+		}
+	case len(o.monthDays) > 0:
+		day = o.monthDays[0]
+	}
+
+	t := time.Date(year, o.month, day, o.start.Hour(), o.start.Minute(), o.start.Second(), 0, time.UTC)
+	if day < 1 || t.Month() != o.month || t.Before(o.start) || (!o.until.IsZero() && t.After(o.until)) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// offsetAt returns the UTC offset of the observance most recently in effect
+// at wall, or, before any of them, the offset the earliest one replaced.
+func offsetAt(observances []observance, wall time.Time) int {
+	wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.UTC)
+
+	var latest time.Time
+	offset, found := 0, false
+	for _, o := range observances {
+		for _, year := range []int{wall.Year() - 1, wall.Year()} {
+			onset, ok := o.onset(year)
+			if ok && !onset.After(wall) && (!found || onset.After(latest)) {
+				latest, offset, found = onset, o.offsetTo, true
+			}
+		}
+	}
+	if found {
+		return offset
+	}
+
+	earliest := observances[0]
+	for _, o := range observances[1:] {
+		if o.start.Before(earliest.start) {
+			earliest = o
+		}
+	}
+	return earliest.offsetFrom
+}
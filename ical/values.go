@@ -0,0 +1,105 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+// EscapeText escapes a TEXT value.
+func EscapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// UnescapeText reverses EscapeText. Unknown escapes keep their character.
+func UnescapeText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// SplitText splits a multi-valued TEXT property such as CATEGORIES on
+// unescaped commas and unescapes each value.
+func SplitText(value string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, UnescapeText(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, UnescapeText(value[start:]))
+}
+
+// JoinText escapes values and joins them for a multi-valued TEXT property.
+func JoinText(values []string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = EscapeText(value)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// FormatDateTime writes t as a UTC DATE-TIME.
+func FormatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout) + "Z"
+}
+
+// ParseTime reads a DATE or DATE-TIME property. UTC times end in Z; times
+// with a TZID are resolved through zones; floating times, which have
+// neither, are taken to be in loc. A DATE is midnight in loc, and dateOnly
+// reports it so the caller can pick a time of day.
+func ParseTime(property Property, zones *Zones, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	value := strings.TrimSpace(property.Value)
+	if strings.EqualFold(property.Params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		day, err := time.ParseInLocation(dateLayout, value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s date %q", property.Name, value)
+		}
+		return day, true, nil
+	}
+
+	if utc, ok := strings.CutSuffix(value, "Z"); ok {
+		t, err := time.Parse(dateTimeLayout, utc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s time %q", property.Name, value)
+		}
+		return t, false, nil
+	}
+
+	wall, err := time.Parse(dateTimeLayout, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s time %q", property.Name, value)
+	}
+	if tzid := property.Params["TZID"]; tzid != "" {
+		t, err := zones.Resolve(tzid, wall)
+		return t, false, err
+	}
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc), false, nil
+}
@@ -1,33 +1,240 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 	"to_do_api/auth"
 	"to_do_api/config"
 	"to_do_api/controllers"
 	"to_do_api/database"
+	"to_do_api/events"
+	"to_do_api/metrics"
 	"to_do_api/middleware"
+	"to_do_api/models"
+	"to_do_api/notify"
+	"to_do_api/repository"
+	"to_do_api/scheduler"
+	"to_do_api/service"
+	"to_do_api/tracing"
+	"to_do_api/webhooks"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	cfg := config.LoadConfig()
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("Unknown command %q", args[0])
+		}
+		migrateMain(cfg, args[1:])
+		return
+	}
+
+	// serve calls stop once the first signal arrives, so a second one stops
+	// the process without waiting for the drain.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		log.Fatal("Failed to set up tracing: ", err)
+	}
+
 	db := database.InitDB(cfg)
+	if err := metrics.InstrumentDB(db, "primary"); err != nil {
+		log.Fatal("Failed to instrument database: ", err)
+	}
+	if err := tracing.InstrumentDB(db); err != nil {
+		log.Fatal("Failed to instrument database: ", err)
+	}
+	background := newWorkers()
+
+	var draining atomic.Bool
+	readiness := map[string]controllers.HealthCheck{
+		"database": func(ctx context.Context) error { return database.Ping(ctx, db) },
+		"server": func(context.Context) error {
+			if draining.Load() {
+				return errors.New("shutting down")
+			}
+			return nil
+		},
+	}
+
+	reminders := scheduler.NewReminderScheduler(db, map[string]notify.Channel{
+		models.ReminderChannelEmail:   notify.NewEmailChannel(cfg),
+		models.ReminderChannelWebhook: notify.NewWebhookChannel(),
+		models.ReminderChannelInApp:   notify.NewInAppChannel(db),
+	})
+	background.Go(reminders.Run)
+	background.Go(webhooks.NewDispatcher(db).Run)
+
+	var broker events.Broker
+	switch cfg.EVENT_BROKER {
+	case "postgres":
+		pgBroker := events.NewPostgresBroker(db, database.DSN(cfg))
+		background.Go(pgBroker.Run)
+		readiness["event_broker"] = func(context.Context) error { return pgBroker.Ready() }
+		broker = pgBroker
+	case "memory":
+		broker = events.NewMemoryBroker()
+	default:
+		log.Fatal("Unknown EVENT_BROKER: ", cfg.EVENT_BROKER)
+	}
+
+	background.Go(func(ctx context.Context) {
+		middleware.RunIdempotencyCleanup(ctx, db, time.Hour)
+	})
+
+	tasks := service.NewTaskService(repository.NewGormTaskRepository(db))
+	replicaTasks := service.NewTaskService(repository.NewGormTaskRepository(database.ReadReplica(db)))
+	users := service.NewUserService(repository.NewGormUserRepository(db))
 
 	r := gin.Default()
+	r.Use(otelgin.Middleware(cfg.TRACING_SERVICE_NAME), middleware.Metrics())
 
-	r.POST("/register", controllers.Register(db))
-	r.POST("/login", controllers.Login(db, &auth.DefaultAuthService{}))
+	// Probes for orchestrators: liveness, and readiness to take traffic.
+	r.GET("/healthz", controllers.Healthz())
+	r.GET("/readyz", controllers.Readyz(readiness))
+
+	r.POST("/register", controllers.Register(users))
+	r.POST("/login", controllers.Login(users, &auth.DefaultAuthService{}, cfg.JWT_SECRET))
+
+	// Calendar apps authenticate with the secret token in the feed URL.
+	r.GET("/calendar/feed/:token", controllers.ServeCalendarFeed(db, tasks))
+
+	// CalDAV apps cannot log in for a JWT, so they use app passwords.
+	r.GET("/.well-known/caldav", controllers.CalDAVWellKnown())
+	r.Handle("PROPFIND", "/.well-known/caldav", controllers.CalDAVWellKnown())
+	dav := r.Group("/caldav")
+	dav.Use(middleware.AppPasswordAuth(db, "CalDAV"))
+	{
+		handler := controllers.CalDAV(db, tasks, broker)
+		for _, method := range controllers.CalDAVMethods {
+			dav.Handle(method, "/*path", handler)
+		}
+	}
 
 	authorized := r.Group("/")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AuthMiddleware(cfg.JWT_SECRET), middleware.Idempotency(db, cfg.IDEMPOTENCY_TTL))
 	{
-		authorized.POST("/tasks", controllers.CreateTask(db))
-		authorized.GET("/tasks", controllers.ListTasks(db))
-		authorized.PUT("/tasks/:id", controllers.UpdateTask(db))
-		authorized.DELETE("/tasks/:id", controllers.DeleteTask(db))
+		authorized.POST("/tasks", controllers.CreateTask(tasks, broker))
+		authorized.GET("/tasks", controllers.ListTasks(replicaTasks))
+		authorized.POST("/tasks/bulk", controllers.BulkTasks(tasks, broker))
+		authorized.POST("/tasks/quick", controllers.QuickAddTask(tasks, broker))
+		authorized.GET("/tasks/export", controllers.ExportTasks(tasks))
+		authorized.POST("/tasks/import/preview", controllers.PreviewImport())
+		authorized.POST("/tasks/import", controllers.ImportTasks(tasks, broker))
+		authorized.GET("/tasks/:id", controllers.GetTask(tasks))
+		authorized.PUT("/tasks/:id", controllers.UpdateTask(tasks, broker))
+		authorized.PATCH("/tasks/:id", controllers.PatchTask(tasks, broker))
+		authorized.DELETE("/tasks/:id", controllers.DeleteTask(tasks, broker))
+
+		authorized.POST("/tasks/stream/tickets", controllers.CreateStreamTicket(db))
+
+		authorized.GET("/search", controllers.SearchTasks(db))
+
+		authorized.POST("/smart-lists", controllers.CreateSmartList(db))
+		authorized.GET("/smart-lists", controllers.ListSmartLists(db))
+		authorized.GET("/smart-lists/:id", controllers.GetSmartList(db))
+		authorized.PUT("/smart-lists/:id", controllers.UpdateSmartList(db))
+		authorized.DELETE("/smart-lists/:id", controllers.DeleteSmartList(db))
+		authorized.GET("/smart-lists/:id/tasks", controllers.EvaluateSmartList(db, tasks))
+		authorized.POST("/smart-lists/:id/shares", controllers.ShareSmartList(db))
+		authorized.GET("/smart-lists/:id/shares", controllers.ListSmartListShares(db))
+		authorized.DELETE("/smart-lists/:id/shares/:user_id", controllers.UnshareSmartList(db))
+
+		authorized.POST("/calendar/feed", controllers.CreateCalendarFeed(db))
+		authorized.GET("/calendar/feed", controllers.GetCalendarFeed(db))
+		authorized.DELETE("/calendar/feed", controllers.DeleteCalendarFeed(db))
+
+		authorized.POST("/app-passwords", controllers.CreateAppPassword(db))
+		authorized.GET("/app-passwords", controllers.ListAppPasswords(db))
+		authorized.DELETE("/app-passwords/:id", controllers.DeleteAppPassword(db))
+
+		authorized.GET("/sync", controllers.PullSync(db))
+		authorized.POST("/sync", controllers.PushSync(tasks, broker))
+
+		authorized.POST("/tasks/:id/reminders", controllers.CreateReminder(db, tasks))
+		authorized.GET("/tasks/:id/reminders", controllers.ListReminders(db, tasks))
+		authorized.DELETE("/tasks/:id/reminders/:reminder_id", controllers.DeleteReminder(db, tasks))
+
+		authorized.GET("/notifications", controllers.ListNotifications(db))
+		authorized.POST("/notifications/read-all", controllers.MarkAllNotificationsRead(db))
+		authorized.POST("/notifications/:id/read", controllers.MarkNotificationRead(db))
+		authorized.GET("/notifications/preferences", controllers.GetNotificationPreferences(db))
+		authorized.PUT("/notifications/preferences", controllers.UpdateNotificationPreferences(db))
+
+		authorized.POST("/webhooks", controllers.CreateWebhook(db))
+		authorized.GET("/webhooks", controllers.ListWebhooks(db))
+		authorized.DELETE("/webhooks/:id", controllers.DeleteWebhook(db))
+		authorized.GET("/webhooks/:id/deliveries", controllers.ListWebhookDeliveries(db))
+		authorized.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", controllers.RedeliverWebhook(db))
+	}
+
+	streaming := r.Group("/")
+	streaming.Use(middleware.StreamAuthMiddleware(db, cfg.JWT_SECRET))
+	{
+		streaming.GET("/tasks/stream", controllers.StreamTasks(db, broker))
+		streaming.GET("/tasks/ws", controllers.StreamTasksWebSocket(db, broker, cfg.STREAM_ALLOWED_ORIGINS))
+	}
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.PORT,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+		ReadTimeout:       cfg.HTTP_READ_TIMEOUT,
+		WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+		IdleTimeout:       cfg.HTTP_IDLE_TIMEOUT,
+	}
+	// Event streams stay open until the client leaves. Ending them lets the
+	// drain finish; clients reconnect, to another instance, and replay.
+	if closer, ok := broker.(interface{ CloseAll() }); ok {
+		srv.RegisterOnShutdown(closer.CloseAll)
 	}
 
-	log.Fatal(r.Run(":" + cfg.PORT))
+	servers := []*http.Server{srv}
+	// Metrics are served on their own listener, which can be kept off the
+	// public network, or else only to scrapers presenting METRICS_TOKEN.
+	switch {
+	case cfg.METRICS_ADDR != "":
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(cfg.METRICS_TOKEN))
+		servers = append(servers, &http.Server{
+			Addr:              cfg.METRICS_ADDR,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+			WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+		})
+	case cfg.METRICS_TOKEN != "":
+		r.GET("/metrics", gin.WrapH(metrics.Handler(cfg.METRICS_TOKEN)))
+	}
+
+	if err := serve(ctx, stop, servers, cfg.SHUTDOWN_TIMEOUT, &draining, background); err != nil {
+		log.Fatal(err)
+	}
+	// Send the spans still buffered, including those of the drain.
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.SHUTDOWN_TIMEOUT)
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Println("Failed to flush traces:", err)
+	}
+	cancel()
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
 }
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const statementStart = "metrics:statement_start"
+
+// InstrumentDB times every statement run through db and exports the
+// statistics of its connection pool, labelled with name.
+func InstrumentDB(db *gorm.DB, name string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := Registry.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		return err
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:start", startStatement),
+		callbacks.Create().After("gorm:create").Register("metrics:observe", observeStatement("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:start", startStatement),
+		callbacks.Query().After("gorm:query").Register("metrics:observe", observeStatement("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:start", startStatement),
+		callbacks.Update().After("gorm:update").Register("metrics:observe", observeStatement("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:start", startStatement),
+		callbacks.Delete().After("gorm:delete").Register("metrics:observe", observeStatement("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:start", startStatement),
+		callbacks.Row().After("gorm:row").Register("metrics:observe", observeStatement("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:start", startStatement),
+		callbacks.Raw().After("gorm:raw").Register("metrics:observe", observeStatement("raw")),
+	)
+}
+
+func startStatement(db *gorm.DB) {
+	db.InstanceSet(statementStart, time.Now())
+}
+
+func observeStatement(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, _ := db.InstanceGet(statementStart)
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		dbDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
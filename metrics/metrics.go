@@ -0,0 +1,105 @@
+// Package metrics collects the Prometheus metrics served on /metrics: HTTP
+// requests by route, database statements and connection pools, and business
+// events such as tasks created and logins.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric the application exports, along with the Go
+// runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to handle an HTTP request, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time to run a database statement, by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	tasksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "todo_tasks_created_total",
+		Help: "Tasks created, through any API.",
+	})
+	tasksCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "todo_tasks_completed_total",
+		Help: "Tasks marked done.",
+	})
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "todo_logins_total",
+		Help: "Password logins, by result: succeeded or failed.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, dbDuration,
+		tasksCreated, tasksCompleted, logins,
+	)
+	// Report the series before the first event, so rates start at zero.
+	logins.WithLabelValues("succeeded")
+	logins.WithLabelValues("failed")
+}
+
+// Handler serves the metrics. If token is set, scrapers must send it as a
+// bearer token.
+func Handler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return metrics
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+// ObserveRequest records a handled HTTP request. route is the pattern it
+// matched, such as /tasks/:id, so that IDs do not each get a series.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	httpRequests.With(labels).Inc()
+	httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+func TaskCreated() {
+	tasksCreated.Inc()
+}
+
+func TaskCompleted() {
+	tasksCompleted.Inc()
+}
+
+// Login records a password login attempt.
+func Login(succeeded bool) {
+	result := "failed"
+	if succeeded {
+		result = "succeeded"
+	}
+	logins.WithLabelValues(result).Inc()
+}
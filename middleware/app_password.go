@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"to_do_api/auth"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// appPasswordTouchInterval limits how often last_used_at is written, since
+// sync clients make many requests in a row.
+const appPasswordTouchInterval = time.Minute
+
+// AppPasswordAuth authenticates with an app password instead of a JWT. It is
+// sent either as HTTP Basic credentials, the account's email and the app
+// password, or as a bearer token, which suits scripts using it as an API
+// key. Failures are challenged for Basic credentials, which is what CalDAV
+// apps expect.
+func AppPasswordAuth(db *gorm.DB, realm string) gin.HandlerFunc {
+	challenge := `Basic realm="` + realm + `", charset="UTF-8"`
+	return func(c *gin.Context) {
+		email, password, basic := c.Request.BasicAuth()
+		if !basic {
+			scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") {
+				c.Header("WWW-Authenticate", challenge)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+				return
+			}
+			password = strings.TrimSpace(token)
+		}
+
+		var appPassword models.AppPassword
+		err := db.Where("password_hash = ?", auth.HashSecret(password)).First(&appPassword).Error
+		if err == nil && basic {
+			var user models.User
+			err = db.Select("email").Where("id = ?", appPassword.UserID).First(&user).Error
+			if err == nil && !strings.EqualFold(user.Email, email) {
+				err = gorm.ErrRecordNotFound
+			}
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		now := time.Now()
+		if appPassword.LastUsedAt == nil || now.Sub(*appPassword.LastUsedAt) > appPasswordTouchInterval {
+			db.Model(&appPassword).Update("last_used_at", now)
+		}
+
+		c.Set("user_id", appPassword.UserID.String())
+		c.Next()
+	}
+}
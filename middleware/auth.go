@@ -3,13 +3,16 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"net/http"
 	"strings"
+	"time"
 	"to_do_api/auth"
-	"to_do_api/config"
+	"to_do_api/models"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -22,14 +25,61 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
 			return
 		}
-		token, err := auth.ValidateToken(bearerToken[1], config.LoadConfig().JWT_SECRET)
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+
+		authenticate(c, bearerToken[1], jwtSecret)
+	}
+}
+
+// StreamAuthMiddleware is AuthMiddleware for streaming endpoints. EventSource
+// and browser WebSockets cannot set an Authorization header, so they may
+// pass ?ticket= instead, with a single-use ticket from CreateStreamTicket. A
+// JWT is not accepted in the URL, where access logs and traces record it.
+func StreamAuthMiddleware(db *gorm.DB, jwtSecret string) gin.HandlerFunc {
+	header := AuthMiddleware(jwtSecret)
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" || c.GetHeader("Authorization") != "" {
+			header(c)
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", claims["user_id"])
+		userID, err := redeemStreamTicket(db.WithContext(c.Request.Context()), ticket)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
+			return
+		}
+		c.Set("user_id", userID.String())
 		c.Next()
 	}
 }
+
+// redeemStreamTicket deletes the ticket as it is used, so that of several
+// requests presenting it only one gets through.
+func redeemStreamTicket(db *gorm.DB, ticket string) (uuid.UUID, error) {
+	hash := auth.HashSecret(ticket)
+	var stored models.StreamTicket
+	if err := db.Where("ticket_hash = ? AND expires_at > ?", hash, time.Now().UTC()).First(&stored).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	result := db.Where("ticket_hash = ?", hash).Delete(&models.StreamTicket{})
+	if result.Error != nil {
+		return uuid.Nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return stored.UserID, nil
+}
+
+func authenticate(c *gin.Context, tokenString, jwtSecret string) {
+	token, err := auth.ValidateToken(tokenString, jwtSecret)
+	if err != nil || !token.Valid {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	c.Set("user_id", claims["user_id"])
+	c.Next()
+}
@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodyBytes bounds the body read for hashing. It leaves room
+	// for the largest import plus its multipart framing.
+	maxIdempotentBodyBytes = 16 << 20
+
+	// idempotencyLease is how long a key stays claimed by a request that has
+	// not finished. It is renewed while the handler runs, so a key left in
+	// progress by a crashed process can be claimed again once it runs out,
+	// rather than only after the full ttl, but a slow request keeps its key.
+	idempotencyLease = 2 * time.Minute
+)
+
+// responseRecorder tees the response body so it can be stored for replay.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST, PATCH and DELETE requests carrying an
+// Idempotency-Key header safe to retry. The first response for a user and
+// key is stored for ttl and replayed for identical retries; reusing the key
+// with a different request is rejected with 422, and a retry that arrives
+// while the original is still running gets 409. Server errors and panics are
+// not stored, so a request that failed with 5xx can be retried for real.
+//
+// It must run after AuthMiddleware, since keys are scoped per user.
+func Idempotency(db *gorm.DB, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || !isKeyedMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes)); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := uuid.Parse(c.GetString("user_id"))
+		hash := requestHash(c.Request, body)
+
+		claim, record, claimed, err := claimIdempotencyKey(db, userID, key, hash, ttl)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		}
+
+		if !claimed {
+			switch {
+			case record.RequestHash != hash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case record.Status == models.IdempotencyInProgress:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				replay(c, record)
+			}
+			return
+		}
+
+		stopRenewing := claim.renew()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				stopRenewing()
+				claim.release()
+				panic(recovered)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		stopRenewing()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			claim.release()
+			return
+		}
+
+		result := claim.held().Updates(map[string]interface{}{
+			"status":          models.IdempotencyCompleted,
+			"response_status": status,
+			"content_type":    recorder.Header().Get("Content-Type"),
+			"etag":            recorder.Header().Get("ETag"),
+			"response_body":   recorder.body.Bytes(),
+			"expires_at":      time.Now().UTC().Add(ttl),
+		})
+		switch {
+		case result.Error != nil:
+			log.Println("Failed to store idempotent response:", result.Error)
+		case result.RowsAffected == 0:
+			log.Println("Idempotency key was claimed by another request before the response was stored")
+		}
+	}
+}
+
+// idempotencyClaim is the in-progress record a request made for its key.
+// Writes on its behalf apply only while the record is still that claim, so
+// a request that lost its lease cannot overwrite or drop the claim of the
+// request that took the key over.
+type idempotencyClaim struct {
+	db        *gorm.DB
+	userID    uuid.UUID
+	key       string
+	hash      string
+	lease     time.Duration
+	expiresAt time.Time
+}
+
+// held scopes a query to the claim's record, if it is still the claim.
+func (c *idempotencyClaim) held() *gorm.DB {
+	return c.db.Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND idempotency_key = ? AND request_hash = ?", c.userID, c.key, c.hash).
+		Where("status = ? AND expires_at = ?", models.IdempotencyInProgress, c.expiresAt)
+}
+
+// renew extends the lease every half lease until the returned function is
+// called, which waits for a renewal under way to finish.
+func (c *idempotencyClaim) renew() func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(c.lease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			expiresAt := leaseEnd(time.Now().UTC(), c.lease)
+			result := c.held().Update("expires_at", expiresAt)
+			if result.Error != nil {
+				log.Println("Failed to renew idempotency key:", result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				return
+			}
+			c.expiresAt = expiresAt
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// release deletes the claim so the request can be retried for real.
+func (c *idempotencyClaim) release() {
+	if err := c.held().Delete(&models.IdempotencyKey{}).Error; err != nil {
+		log.Println("Failed to release idempotency key:", err)
+	}
+}
+
+// leaseEnd is kept to whole microseconds, which every database stores
+// exactly, so the claim can be matched by it.
+func leaseEnd(now time.Time, lease time.Duration) time.Time {
+	return now.Add(lease).Truncate(time.Microsecond)
+}
+
+// claimIdempotencyKey inserts an in-progress record for the key, leased for
+// idempotencyLease or ttl if that is shorter. If the key is already taken it
+// returns the existing record and claimed=false. The insert is the
+// synchronisation point: of two concurrent requests with the same key only
+// one can create the row.
+func claimIdempotencyKey(db *gorm.DB, userID uuid.UUID, key, hash string, ttl time.Duration) (*idempotencyClaim, models.IdempotencyKey, bool, error) {
+	now := time.Now().UTC()
+	lease := min(ttl, idempotencyLease)
+	record := models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: hash,
+		Status:      models.IdempotencyInProgress,
+		ExpiresAt:   leaseEnd(now, lease),
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return nil, record, false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claim := &idempotencyClaim{db: db, userID: userID, key: key, hash: hash, lease: lease, expiresAt: record.ExpiresAt}
+			return claim, record, true, nil
+		}
+
+		var existing models.IdempotencyKey
+		if err := db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return nil, existing, false, err
+		}
+		if existing.ExpiresAt.After(now) {
+			return nil, existing, false, nil
+		}
+
+		// Expired, or a lease its request never completed: drop it and try to claim the key afresh.
+		if err := db.Delete(&models.IdempotencyKey{}, "user_id = ? AND idempotency_key = ? AND expires_at <= ?", userID, key, now).Error; err != nil {
+			return nil, existing, false, err
+		}
+	}
+	return nil, record, false, gorm.ErrDuplicatedKey
+}
+
+func replay(c *gin.Context, record models.IdempotencyKey) {
+	c.Header("Idempotent-Replayed", "true")
+	if record.ETag != "" {
+		c.Header("ETag", record.ETag)
+	}
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(record.ResponseStatus, contentType, record.ResponseBody)
+	c.Abort()
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	// Encode sorts by key, so reordered parameters are the same request.
+	hash.Write([]byte(r.URL.Query().Encode()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isKeyedMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// RunIdempotencyCleanup deletes expired idempotency keys every interval until
+// ctx is cancelled.
+func RunIdempotencyCleanup(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := db.WithContext(ctx).Where("expires_at <= ?", time.Now().UTC()).Delete(&models.IdempotencyKey{}).Error; err != nil {
+				log.Println("Failed to purge idempotency keys:", err)
+			}
+		}
+	}
+}
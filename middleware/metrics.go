@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"time"
+	"to_do_api/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records every request by the route it matched. Requests that
+// matched none are grouped under "unmatched", so probing random paths
+// cannot create unbounded series.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"to_do_api/config"
+	"to_do_api/database"
+)
+
+const migrateUsage = `usage: to_do_api [flags] migrate <command>
+
+  up [N]          apply all pending migrations, or the next N
+  down [N|all]    roll back the last migration, the last N, or all
+  status          list migrations and when they were applied
+  create <name>   add empty up and down files to database/migrations
+`
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+
+	if args[0] == "create" {
+		if len(args) < 2 {
+			return fmt.Errorf("create needs a name\n%s", migrateUsage)
+		}
+		paths, err := database.CreateMigration("database/migrations", strings.Join(args[1:], " "), time.Now())
+		for _, path := range paths {
+			fmt.Fprintln(out, "created", path)
+		}
+		return err
+	}
+
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	migrator := database.NewMigrator(db, database.Migrations)
+
+	switch args[0] {
+	case "up":
+		steps, err := migrateSteps(args[1:], 0)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(steps)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		steps, err := migrateSteps(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if status.Missing {
+				applied += " (no files)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+// migrateSteps reads the optional step count of up and down. "all" is 0,
+// which the migrator takes as no limit.
+func migrateSteps(args []string, defaultSteps int) (int, error) {
+	if len(args) == 0 {
+		return defaultSteps, nil
+	}
+	if args[0] == "all" {
+		return 0, nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("invalid step count %q", args[0])
+	}
+	return steps, nil
+}
+
+func migrateMain(cfg *config.Config, args []string) {
+	if err := runMigrate(cfg, args, os.Stdout); err != nil {
+		log.Fatal("migrate: ", err)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppPassword signs in clients that cannot use the JWT login, such as CalDAV
+// apps. Only a hash of the password is stored.
+type AppPassword struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name         string     `gorm:"not null" json:"name"`
+	PasswordHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (password *AppPassword) BeforeCreate(tx *gorm.DB) error {
+	if password.ID == uuid.Nil {
+		password.ID = uuid.New()
+	}
+	return nil
+}
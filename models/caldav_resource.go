@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// CalDAVResource records the resource name and UID a CalDAV client gave a
+// task it created, when they are not the defaults of <task id>.ics and the
+// task ID.
+type CalDAVResource struct {
+	TaskID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_caldav_resources_user_name;index:idx_caldav_resources_user_uid"`
+	Name   string    `gorm:"not null;uniqueIndex:idx_caldav_resources_user_name"`
+	UID    string    `gorm:"not null;index:idx_caldav_resources_user_uid"`
+}
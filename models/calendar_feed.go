@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CalendarFeed is a user's secret iCalendar feed. The token is the only
+// credential calendar apps send, so only its SHA-256 hash is stored.
+type CalendarFeed struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	TokenHash string    `gorm:"not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (feed *CalendarFeed) BeforeCreate(tx *gorm.DB) error {
+	if feed.ID == uuid.Nil {
+		feed.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyKey remembers the response to a mutating request so a retry
+// with the same Idempotency-Key header is answered without running it again.
+type IdempotencyKey struct {
+	UserID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Key            string    `gorm:"column:idempotency_key;primaryKey;size:255"`
+	RequestHash    string    `gorm:"not null"`
+	Status         string    `gorm:"not null"`
+	ResponseStatus int
+	ContentType    string
+	ETag           string `gorm:"column:etag"`
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time `gorm:"not null;index"`
+}
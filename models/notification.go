@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationTypeReminder   = "reminder"
+	NotificationTypeAssignment = "assignment"
+	NotificationTypeMention    = "mention"
+	NotificationTypeShare      = "share"
+)
+
+// NotificationTypes lists the event types a user can set preferences for.
+var NotificationTypes = []string{
+	NotificationTypeReminder,
+	NotificationTypeAssignment,
+	NotificationTypeMention,
+	NotificationTypeShare,
+}
+
+type Notification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `json:"body"`
+	TaskID    *uuid.UUID `gorm:"type:uuid" json:"task_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (notification *Notification) BeforeCreate(tx *gorm.DB) error {
+	if notification.ID == uuid.Nil {
+		notification.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationPreference turns one event type on or off for a user. Types
+// without a row are enabled.
+type NotificationPreference struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_notification_preference" json:"-"`
+	EventType string    `gorm:"not null;uniqueIndex:idx_notification_preference" json:"event_type"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+}
+
+func (preference *NotificationPreference) BeforeCreate(tx *gorm.DB) error {
+	if preference.ID == uuid.Nil {
+		preference.ID = uuid.New()
+	}
+	return nil
+}
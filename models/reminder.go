@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ReminderChannelEmail   = "email"
+	ReminderChannelWebhook = "webhook"
+	ReminderChannelInApp   = "in_app"
+
+	ReminderStatusPending = "pending"
+	ReminderStatusSent    = "sent"
+	ReminderStatusFailed  = "failed"
+)
+
+// Reminder fires once at FireAt. It is either pinned to an absolute RemindAt
+// or defined as OffsetMinutes before the task's due date, in which case
+// FireAt is recomputed whenever the due date changes.
+type Reminder struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TaskID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"task_id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RemindAt      *time.Time `json:"remind_at"`
+	OffsetMinutes *int       `json:"offset_minutes"`
+	FireAt        time.Time  `gorm:"not null;index" json:"fire_at"`
+	Channel       string     `gorm:"not null" json:"channel"`
+	WebhookURL    string     `json:"webhook_url,omitempty"`
+	Status        string     `gorm:"not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LockedUntil   *time.Time `json:"-"`
+	SentAt        *time.Time `json:"sent_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (reminder *Reminder) BeforeCreate(tx *gorm.DB) error {
+	if reminder.ID == uuid.Nil {
+		reminder.ID = uuid.New()
+	}
+	return nil
+}
+
+// FireTime is when the reminder fires for task: RemindAt if pinned, otherwise
+// OffsetMinutes before the task's due date.
+func (reminder Reminder) FireTime(task Task) time.Time {
+	if reminder.RemindAt != nil {
+		return *reminder.RemindAt
+	}
+	return task.DueDate.UTC().Add(-time.Duration(*reminder.OffsetMinutes) * time.Minute)
+}
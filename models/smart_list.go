@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SmartList is a saved taskquery filter. Evaluating it always selects the
+// caller's own tasks, so sharing a smart list shares the filter, not the
+// owner's tasks.
+type SmartList struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Query     string    `gorm:"not null" json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (list *SmartList) BeforeCreate(tx *gorm.DB) error {
+	if list.ID == uuid.Nil {
+		list.ID = uuid.New()
+	}
+	return nil
+}
+
+// SmartListShare gives another user access to a smart list.
+type SmartListShare struct {
+	SmartListID uuid.UUID `gorm:"type:uuid;primaryKey" json:"smart_list_id"`
+	UserID      uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamTicket lets a browser open an event stream without putting its JWT
+// in the URL, where access logs and traces would record it. Tickets are
+// short-lived and single-use, and only a hash is stored.
+type StreamTicket struct {
+	TicketHash string    `gorm:"primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+}
@@ -1,16 +1,42 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	PriorityNone = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+// PriorityNames maps the priority names clients may use to their values.
+var PriorityNames = map[string]int{
+	"none":   PriorityNone,
+	"low":    PriorityLow,
+	"medium": PriorityMedium,
+	"high":   PriorityHigh,
+}
+
 type Task struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Title       string    `gorm:"not null" json:"title"`
-	Description string    `json:"description"`
-	Status      bool      `gorm:"default:false" json:"status"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Description string     `json:"description"`
+	Status      bool       `gorm:"default:false" json:"status"`
+	DueDate     *time.Time `gorm:"index" json:"due_date"`
+	Priority    int        `gorm:"not null;default:0" json:"priority"`
+	Project     string     `gorm:"index" json:"project"`
+	Labels      []string   `gorm:"serializer:json" json:"labels"`
+	Recurrence  string     `json:"recurrence"`
+	ParentID    *uuid.UUID `gorm:"type:uuid;index" json:"parent_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	Version     int        `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 func (task *Task) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskEvent is an append-only log of task changes. Its auto-incrementing ID
+// orders events and is what streaming clients send back as Last-Event-ID.
+type TaskEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	TaskID    uuid.UUID `gorm:"type:uuid;not null" json:"task_id"`
+	Type      string    `gorm:"not null" json:"type"`
+	Payload   string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
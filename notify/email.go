@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+	"to_do_api/config"
+)
+
+// smtpTimeout bounds a delivery whose context has no earlier deadline.
+const smtpTimeout = 30 * time.Second
+
+type EmailChannel struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewEmailChannel(cfg *config.Config) *EmailChannel {
+	return &EmailChannel{
+		Host:     cfg.SMTP_HOST,
+		Port:     cfg.SMTP_PORT,
+		Username: cfg.SMTP_USER,
+		Password: cfg.SMTP_PASSWORD,
+		From:     cfg.SMTP_FROM,
+	}
+}
+
+func (e *EmailChannel) Send(ctx context.Context, msg Message) error {
+	if e.Host == "" {
+		return fmt.Errorf("email channel is not configured")
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + headerValue(e.From),
+		"To: " + headerValue(msg.User.Email),
+		"Subject: " + mime.QEncoding.Encode("UTF-8", headerValue(msg.Subject)),
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	return e.send(ctx, auth, msg.User.Email, []byte(body))
+}
+
+// send does what smtp.SendMail does, but gives up when ctx is done or its
+// deadline passes, so a stalled server cannot hold a reminder past its lease.
+func (e *EmailChannel) send(ctx context.Context, auth smtp.Auth, to string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(e.Host, e.Port))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server does not support authentication")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// headerValue keeps a value, such as a subject made from a task title, on
+// its header line, so it cannot add headers or start the body.
+func headerValue(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
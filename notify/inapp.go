@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"context"
+	"to_do_api/models"
+
+	"gorm.io/gorm"
+)
+
+// InAppChannel stores the message in the user's notification inbox.
+type InAppChannel struct {
+	DB *gorm.DB
+}
+
+func NewInAppChannel(db *gorm.DB) *InAppChannel {
+	return &InAppChannel{DB: db}
+}
+
+func (i *InAppChannel) Send(ctx context.Context, msg Message) error {
+	taskID := msg.Task.ID
+	return Publish(i.DB.WithContext(ctx), models.Notification{
+		UserID: msg.User.ID,
+		Type:   models.NotificationTypeReminder,
+		Title:  msg.Subject,
+		Body:   msg.Body,
+		TaskID: &taskID,
+	})
+}
@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+
+	"to_do_api/models"
+)
+
+// Message is what a channel delivers for a single reminder.
+type Message struct {
+	User     models.User
+	Task     models.Task
+	Reminder models.Reminder
+	Subject  string
+	Body     string
+}
+
+// Channel delivers a message over one transport. Implementations must be
+// safe for concurrent use.
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Publish adds a notification to the user's inbox unless they have turned
+// that event type off. Handlers should go through Publish rather than
+// creating models.Notification rows directly. Passing a transaction makes
+// the notification part of it.
+func Publish(db *gorm.DB, notification models.Notification) error {
+	enabled, err := Enabled(db, notification.UserID, notification.Type)
+	if err != nil || !enabled {
+		return err
+	}
+	return db.Create(&notification).Error
+}
+
+// Enabled reports whether the user wants notifications of the given type.
+func Enabled(db *gorm.DB, userID uuid.UUID, eventType string) (bool, error) {
+	var preferences []models.NotificationPreference
+	if err := db.Where("user_id = ? AND event_type = ?", userID, eventType).Limit(1).Find(&preferences).Error; err != nil {
+		return false, err
+	}
+	if len(preferences) == 0 {
+		return true, nil
+	}
+	return preferences[0].Enabled, nil
+}
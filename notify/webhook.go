@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"to_do_api/outbound"
+)
+
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+// NewWebhookChannel posts to reminders' webhook URLs, which users choose, so
+// it only reaches public addresses.
+func NewWebhookChannel() *WebhookChannel {
+	return &WebhookChannel{Client: outbound.NewClient(10 * time.Second)}
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	if msg.Reminder.WebhookURL == "" {
+		return fmt.Errorf("reminder has no webhook url")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":       "reminder.fired",
+		"reminder_id": msg.Reminder.ID,
+		"task":        msg.Task,
+		"subject":     msg.Subject,
+		"body":        msg.Body,
+		"fired_at":    time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.Reminder.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package outbound sends HTTP requests to URLs that users supply, such as
+// webhook endpoints, without letting them reach the server's own network.
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for requests to loopback, private,
+// link-local and other non-public addresses.
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// sharedAddressSpace is carrier-grade NAT, which netip does not count as
+// private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewClient returns a client that refuses to connect to non-public
+// addresses and does not follow redirects, which could otherwise lead it
+// to one. The address is checked after DNS resolution, when dialling, so a
+// public name that resolves to an internal address is refused too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: it would be dialled instead of the target.
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func checkAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %w", address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%s: %w", ip, ErrForbiddenAddress)
+	}
+	return nil
+}
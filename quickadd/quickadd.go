@@ -0,0 +1,635 @@
+// Package quickadd turns a single line such as
+//
+//	Call mom tomorrow 5pm #family !high every sunday
+//
+// into task fields. It understands:
+//
+//	labels       #family or @errands
+//	project      +home
+//	priority     !low, !medium, !high, or !, !!, !!!
+//	dates        today, tonight, tomorrow, monday, next friday, next week,
+//	             next month, in 3 days, oct 21, 21st october 2027, 2026-10-21
+//	times        5pm, 5:30 pm, 17:00, noon, midnight, in 2 hours
+//	recurrence   daily, weekly, every day, every other week, every 2 months,
+//	             every weekday, every mon and thu
+//
+// "on", "by" and "due" before a date and "at" before a time are absorbed.
+// Only the first date, time, recurrence and project are used; later ones
+// stay in the title, as does anything in double quotes. A weekday name means
+// the next such day, today included. Abbreviated weekdays (mon, wed, sat)
+// are only dates after on, by, due, next or every, since on their own they
+// are usually ordinary words ("Fix the sat nav"). A date without a time is
+// due at the end of that day. With both a date and a recurrence, the task is
+// due on the date and repeats from there; with only a recurrence, it is due
+// at the first occurrence.
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+	"to_do_api/recurrence"
+)
+
+const (
+	KindDate       = "date"
+	KindTime       = "time"
+	KindRecurrence = "recurrence"
+	KindLabel      = "label"
+	KindProject    = "project"
+	KindPriority   = "priority"
+)
+
+// Span is a part of the input that was understood as something other than
+// the title. Start and End are byte offsets, for highlighting as the user
+// types.
+type Span struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type Result struct {
+	Title      string     `json:"title"`
+	DueDate    *time.Time `json:"due_date"`
+	HasTime    bool       `json:"has_time"`
+	Priority   int        `json:"priority"`
+	Project    string     `json:"project"`
+	Labels     []string   `json:"labels"`
+	Recurrence string     `json:"recurrence"`
+	Spans      []Span     `json:"spans"`
+}
+
+type word struct {
+	text  string
+	lower string
+	start int
+	end   int
+}
+
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+type clock struct {
+	hour   int
+	minute int
+}
+
+var endOfDay = clock{23, 59}
+
+type parser struct {
+	input string
+	words []word
+	now   time.Time
+
+	date  *civilDate
+	clock *clock
+	rule  *recurrence.Rule
+
+	result Result
+	title  []string
+}
+
+// Parse interprets text relative to now. now's location is taken to be the
+// user's time zone; the returned due date is in UTC.
+func Parse(text string, now time.Time) Result {
+	p := &parser{input: text, words: split(text), now: now}
+	p.result.Labels = []string{}
+	p.result.Spans = []Span{}
+
+	matchers := []func(int) (string, int){
+		p.matchLabel,
+		p.matchProject,
+		p.matchPriority,
+		p.matchRecurrence,
+		p.matchDate,
+		p.matchTime,
+	}
+
+	for i := 0; i < len(p.words); {
+		if n := p.matchQuoted(i); n > 0 {
+			i += n
+			continue
+		}
+
+		matched := false
+		for _, match := range matchers {
+			if kind, n := match(i); n > 0 {
+				first, last := p.words[i], p.words[i+n-1]
+				p.result.Spans = append(p.result.Spans, Span{
+					Kind:  kind,
+					Text:  text[first.start:last.end],
+					Start: first.start,
+					End:   last.end,
+				})
+				i += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			p.title = append(p.title, p.words[i].text)
+			i++
+		}
+	}
+
+	p.result.Title = strings.Join(p.title, " ")
+	p.resolveDueDate()
+	return p.result
+}
+
+func split(text string) []word {
+	var words []word
+	start := -1
+	for i, r := range text + " " {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if start >= 0 {
+				w := text[start:i]
+				words = append(words, word{
+					text:  w,
+					lower: strings.TrimRight(strings.ToLower(w), ",.;"),
+					start: start,
+					end:   i,
+				})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return words
+}
+
+func (p *parser) word(i int) string {
+	if i < len(p.words) {
+		return p.words[i].lower
+	}
+	return ""
+}
+
+// matchQuoted keeps a "quoted run" in the title verbatim, without quotes.
+func (p *parser) matchQuoted(i int) int {
+	if !strings.HasPrefix(p.words[i].text, `"`) {
+		return 0
+	}
+	for j := i; j < len(p.words); j++ {
+		text := p.words[j].text
+		if (j > i || len(text) > 1) && strings.HasSuffix(text, `"`) {
+			quoted := p.input[p.words[i].start+1 : p.words[j].end-1]
+			p.title = append(p.title, quoted)
+			return j - i + 1
+		}
+	}
+	return 0
+}
+
+var labelPattern = regexp.MustCompile(`^[#@]([\p{L}\p{N}_-]{1,64})$`)
+
+func (p *parser) matchLabel(i int) (string, int) {
+	match := labelPattern.FindStringSubmatch(p.word(i))
+	if match == nil {
+		return "", 0
+	}
+	for _, label := range p.result.Labels {
+		if label == match[1] {
+			return KindLabel, 1
+		}
+	}
+	p.result.Labels = append(p.result.Labels, match[1])
+	return KindLabel, 1
+}
+
+func (p *parser) matchProject(i int) (string, int) {
+	text := strings.TrimRight(p.words[i].text, ",.;")
+	if p.result.Project != "" || len(text) < 2 || text[0] != '+' {
+		return "", 0
+	}
+	p.result.Project = text[1:]
+	return KindProject, 1
+}
+
+var priorityWords = map[string]int{
+	"!":       models.PriorityLow,
+	"!!":      models.PriorityMedium,
+	"!!!":     models.PriorityHigh,
+	"!low":    models.PriorityLow,
+	"!medium": models.PriorityMedium,
+	"!med":    models.PriorityMedium,
+	"!high":   models.PriorityHigh,
+}
+
+func (p *parser) matchPriority(i int) (string, int) {
+	priority, ok := priorityWords[p.word(i)]
+	if !ok || p.result.Priority != models.PriorityNone {
+		return "", 0
+	}
+	p.result.Priority = priority
+	return KindPriority, 1
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// weekdayAbbreviations are only weekdays where a date or recurrence is
+// expected; see weekday.
+var weekdayAbbreviations = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var frequencies = map[string]string{
+	"day": recurrence.Daily, "days": recurrence.Daily,
+	"week": recurrence.Weekly, "weeks": recurrence.Weekly,
+	"month": recurrence.Monthly, "months": recurrence.Monthly,
+	"year": recurrence.Yearly, "years": recurrence.Yearly,
+}
+
+var adverbs = map[string]recurrence.Rule{
+	"daily":    {Freq: recurrence.Daily, Interval: 1},
+	"weekly":   {Freq: recurrence.Weekly, Interval: 1},
+	"biweekly": {Freq: recurrence.Weekly, Interval: 2},
+	"monthly":  {Freq: recurrence.Monthly, Interval: 1},
+	"yearly":   {Freq: recurrence.Yearly, Interval: 1},
+	"annually": {Freq: recurrence.Yearly, Interval: 1},
+}
+
+func (p *parser) matchRecurrence(i int) (string, int) {
+	if p.rule != nil {
+		return "", 0
+	}
+
+	if rule, ok := adverbs[p.word(i)]; ok {
+		p.rule = &rule
+		return KindRecurrence, 1
+	}
+	if w := p.word(i); w != "every" && w != "each" {
+		return "", 0
+	}
+
+	rule := recurrence.Rule{Interval: 1}
+	n := 1
+	switch w := p.word(i + 1); {
+	case w == "weekday" || w == "weekdays":
+		rule.Freq = recurrence.Weekly
+		rule.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		n++
+	case w == "weekend" || w == "weekends":
+		rule.Freq = recurrence.Weekly
+		rule.ByDay = []time.Weekday{time.Saturday, time.Sunday}
+		n++
+	case weekdayOK(w):
+		rule.Freq = recurrence.Weekly
+		for {
+			if day, ok := weekday(p.word(i+n), true); ok {
+				rule.ByDay = append(rule.ByDay, day)
+				n++
+			} else if weekdayOK(p.word(i+n+1)) && p.word(i+n) == "and" {
+				n++
+			} else {
+				break
+			}
+		}
+	default:
+		if w == "other" {
+			rule.Interval = 2
+			n++
+		} else if interval, err := strconv.Atoi(w); err == nil && interval > 0 && interval < 1000 {
+			rule.Interval = interval
+			n++
+		}
+		freq, ok := frequencies[p.word(i+n)]
+		if !ok {
+			return "", 0
+		}
+		rule.Freq = freq
+		n++
+	}
+
+	// Round-trip through Parse to get the canonical form.
+	parsed, err := recurrence.Parse(rule.String())
+	if err != nil {
+		return "", 0
+	}
+	p.rule = &parsed
+	return KindRecurrence, n
+}
+
+// weekday reads a weekday name, and also an abbreviation if abbreviated is
+// set because a word such as "on" or "every" introduced it.
+func weekday(w string, abbreviated bool) (time.Weekday, bool) {
+	if day, ok := weekdays[w]; ok {
+		return day, true
+	}
+	if abbreviated {
+		day, ok := weekdayAbbreviations[w]
+		return day, ok
+	}
+	return 0, false
+}
+
+// weekdayOK reports whether w is a weekday where one is expected.
+func weekdayOK(w string) bool {
+	_, ok := weekday(w, true)
+	return ok
+}
+
+func (p *parser) matchDate(i int) (string, int) {
+	if p.date != nil {
+		return "", 0
+	}
+	offset := 0
+	switch p.word(i) {
+	case "on", "by", "due":
+		offset = 1
+	}
+
+	n := p.parseDate(i+offset, offset > 0)
+	if n == 0 {
+		return "", 0
+	}
+	return KindDate, n + offset
+}
+
+// parseDate reads a date phrase at i and sets p.date, and for "tonight" and
+// "in N hours" also p.clock. introduced is set when on, by or due came before
+// the phrase.
+func (p *parser) parseDate(i int, introduced bool) int {
+	today := civil(p.now)
+	set := func(d civilDate, n int) int {
+		p.date = &d
+		return n
+	}
+
+	w := p.word(i)
+	switch w {
+	case "today":
+		return set(today, 1)
+	case "tonight":
+		if p.clock == nil {
+			p.clock = &clock{20, 0}
+		}
+		return set(today, 1)
+	case "tomorrow", "tmr", "tmrw":
+		return set(today.add(0, 0, 1), 1)
+	case "next":
+		switch next := p.word(i + 1); {
+		case next == "week":
+			return set(nextWeekday(today.add(0, 0, 1), time.Monday), 2)
+		case next == "month":
+			return set(civilDate{today.year, today.month + 1, 1}.normalize(), 2)
+		case next == "year":
+			return set(civilDate{today.year + 1, time.January, 1}, 2)
+		case weekdayOK(next):
+			day, _ := weekday(next, true)
+			return set(nextWeekday(today.add(0, 0, 1), day), 2)
+		}
+		return 0
+	case "in":
+		return p.parseIn(i)
+	}
+
+	if day, ok := weekday(w, introduced); ok {
+		return set(nextWeekday(today, day), 1)
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", w, p.now.Location()); err == nil {
+		return set(civil(t), 1)
+	}
+
+	// "oct 21", "october 21st 2027", "21 oct", "21st of october"
+	if month, ok := months[w]; ok {
+		if day, ok := parseDay(p.word(i + 1)); ok {
+			d, n := p.withYear(civilDate{today.year, month, day}, i+2)
+			return set(d, n+2)
+		}
+		return 0
+	}
+	if day, ok := parseDay(w); ok {
+		n := 1
+		if p.word(i+n) == "of" {
+			n++
+		}
+		if month, ok := months[p.word(i+n)]; ok {
+			d, extra := p.withYear(civilDate{today.year, month, day}, i+n+1)
+			return set(d, n+1+extra)
+		}
+	}
+	return 0
+}
+
+// withYear reads an optional year at i. Without one, a date already past
+// this year means next year.
+func (p *parser) withYear(d civilDate, i int) (civilDate, int) {
+	if year, err := strconv.Atoi(p.word(i)); err == nil && year >= 1970 && year < 3000 {
+		d.year = year
+		return d.normalize(), 1
+	}
+	if d.before(civil(p.now)) {
+		d.year++
+	}
+	return d.normalize(), 0
+}
+
+func (p *parser) parseIn(i int) int {
+	amount := p.word(i + 1)
+	count, err := strconv.Atoi(amount)
+	if amount == "a" || amount == "an" {
+		count, err = 1, nil
+	}
+	if err != nil || count < 1 || count > 1000 {
+		return 0
+	}
+
+	today := civil(p.now)
+	switch p.word(i + 2) {
+	case "minute", "minutes", "min", "mins", "hour", "hours", "hr", "hrs":
+		if p.clock != nil {
+			return 0
+		}
+		unit := time.Minute
+		if strings.HasPrefix(p.word(i+2), "h") {
+			unit = time.Hour
+		}
+		at := p.now.Add(time.Duration(count) * unit)
+		d := civil(at)
+		p.date = &d
+		p.clock = &clock{at.Hour(), at.Minute()}
+	case "day", "days":
+		d := today.add(0, 0, count)
+		p.date = &d
+	case "week", "weeks":
+		d := today.add(0, 0, 7*count)
+		p.date = &d
+	case "month", "months":
+		d := today.add(0, count, 0)
+		p.date = &d
+	default:
+		return 0
+	}
+	return 3
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a|p)?$`)
+
+func (p *parser) matchTime(i int) (string, int) {
+	if p.clock != nil {
+		return "", 0
+	}
+	offset := 0
+	if w := p.word(i); w == "at" || w == "@" {
+		offset = 1
+	}
+
+	c, n := parseClock(p.word(i+offset), p.word(i+offset+1))
+	if n == 0 {
+		return "", 0
+	}
+	p.clock = &c
+	return KindTime, n + offset
+}
+
+// parseClock reads a time from w, or from w and a following "am"/"pm".
+func parseClock(w, next string) (clock, int) {
+	switch w {
+	case "noon":
+		return clock{12, 0}, 1
+	case "midnight":
+		return clock{0, 0}, 1
+	}
+
+	match := clockPattern.FindStringSubmatch(w)
+	if match == nil {
+		return clock{}, 0
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	n := 1
+	meridiem := match[3]
+	if meridiem == "" && (next == "am" || next == "pm") {
+		meridiem = next
+		n = 2
+	}
+
+	switch {
+	case minute > 59:
+		return clock{}, 0
+	case meridiem != "":
+		if hour < 1 || hour > 12 {
+			return clock{}, 0
+		}
+		hour %= 12
+		if strings.HasPrefix(meridiem, "p") {
+			hour += 12
+		}
+	case match[2] == "" || hour > 23:
+		// A bare number is not a time.
+		return clock{}, 0
+	}
+	return clock{hour, minute}, n
+}
+
+// parseDay reads a day of the month: 21, 21st, 2nd, 3rd, 4th.
+func parseDay(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+	day, err := strconv.Atoi(w)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+func (p *parser) resolveDueDate() {
+	if p.rule != nil {
+		p.result.Recurrence = p.rule.String()
+	}
+	p.result.HasTime = p.clock != nil
+	if p.date == nil && p.clock == nil && p.rule == nil {
+		return
+	}
+
+	at := endOfDay
+	if p.clock != nil {
+		at = *p.clock
+	}
+
+	var due time.Time
+	if p.date != nil {
+		due = p.date.at(at, p.now.Location())
+	} else {
+		due = civil(p.now).at(at, p.now.Location())
+		if p.rule != nil {
+			due = p.rule.First(due)
+			if due.Before(p.now) {
+				due = p.rule.Next(due, due)
+			}
+		} else if due.Before(p.now) {
+			due = due.AddDate(0, 0, 1)
+		}
+	}
+
+	due = due.UTC()
+	p.result.DueDate = &due
+}
+
+func civil(t time.Time) civilDate {
+	year, month, day := t.Date()
+	return civilDate{year, month, day}
+}
+
+func (d civilDate) at(c clock, loc *time.Location) time.Time {
+	return time.Date(d.year, d.month, d.day, c.hour, c.minute, 0, 0, loc)
+}
+
+func (d civilDate) add(years, months, days int) civilDate {
+	return civil(d.at(clock{12, 0}, time.UTC).AddDate(years, months, days))
+}
+
+func (d civilDate) normalize() civilDate {
+	return d.add(0, 0, 0)
+}
+
+func (d civilDate) before(other civilDate) bool {
+	return d.at(clock{}, time.UTC).Before(other.at(clock{}, time.UTC))
+}
+
+// nextWeekday returns the first day on or after from that falls on day.
+func nextWeekday(from civilDate, day time.Weekday) civilDate {
+	weekday := from.at(clock{12, 0}, time.UTC).Weekday()
+	return from.add(0, 0, (int(day)-int(weekday)+7)%7)
+}
@@ -0,0 +1,210 @@
+// Package recurrence handles the subset of RFC 5545 RRULEs tasks use:
+// FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL and, for weekly rules,
+// BYDAY. Weeks start on Monday.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+var dayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+}
+
+// Parse reads an RRULE value such as FREQ=WEEKLY;BYDAY=SU. A leading
+// "RRULE:" is accepted.
+func Parse(value string) (Rule, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	rule := Rule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid recurrence part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch val {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = val
+			default:
+				return Rule{}, fmt.Errorf("unsupported recurrence frequency %q", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 || interval > 999 {
+				return Rule{}, fmt.Errorf("invalid recurrence interval %q", val)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekday(code)
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid recurrence day %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported recurrence part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("recurrence requires FREQ")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	rule.ByDay = normalizeDays(rule.ByDay)
+	return rule, nil
+}
+
+// String returns the canonical RRULE value, without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = dayCodes[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// First returns the first occurrence on or after t, keeping t's time of day.
+func (r Rule) First(t time.Time) time.Time {
+	if len(r.ByDay) == 0 || r.onDay(t.Weekday()) {
+		return t
+	}
+	return r.Next(t, t)
+}
+
+// Next returns the first occurrence after t of the series that starts at
+// start, keeping start's time of day. Each occurrence is counted from start
+// rather than from the one before it, so months and years that lack start's
+// day of the month use their last day instead, and later ones return to it:
+// monthly from January 31 gives February 28, then March 31.
+func (r Rule) Next(start, t time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	if t.Before(start) {
+		return r.First(start)
+	}
+
+	switch r.Freq {
+	case Daily:
+		return nthAfter(t, func(n int) time.Time { return start.AddDate(0, 0, n) }, daysBetween(start, t), interval)
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return nthAfter(t, func(n int) time.Time { return start.AddDate(0, 0, 7*n) }, daysBetween(start, t)/7, interval)
+		}
+		for i := 0; i <= 7*interval; i++ {
+			next := start.AddDate(0, 0, daysBetween(start, t)+i)
+			weeks := daysBetween(weekStart(start), weekStart(next)) / 7
+			if next.After(t) && r.onDay(next.Weekday()) && weeks%interval == 0 {
+				return next
+			}
+		}
+	case Monthly:
+		return nthAfter(t, func(n int) time.Time { return addMonths(start, n) }, monthsBetween(start, t), interval)
+	case Yearly:
+		return nthAfter(t, func(n int) time.Time { return addMonths(start, n) }, monthsBetween(start, t), 12*interval)
+	}
+	return t
+}
+
+// nthAfter returns the first of occurrence(0), occurrence(step),
+// occurrence(2*step)... that falls after t, starting the search from the
+// multiple of step at or below near.
+func nthAfter(t time.Time, occurrence func(n int) time.Time, near, step int) time.Time {
+	for n := near / step * step; ; n += step {
+		if next := occurrence(n); next.After(t) {
+			return next
+		}
+	}
+}
+
+// daysBetween counts the calendar days from a to b, whatever the clocks
+// and daylight saving changes in between.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()-a.Month())
+}
+
+func (r Rule) onDay(day time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func weekday(code string) (time.Weekday, bool) {
+	for i, c := range dayCodes {
+		if c == code {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// normalizeDays sorts days Monday first and removes duplicates.
+func normalizeDays(days []time.Weekday) []time.Weekday {
+	if len(days) == 0 {
+		return nil
+	}
+	order := func(day time.Weekday) int { return (int(day) + 6) % 7 }
+	sort.Slice(days, func(i, j int) bool { return order(days[i]) < order(days[j]) })
+
+	unique := days[:1]
+	for _, day := range days[1:] {
+		if day != unique[len(unique)-1] {
+			unique = append(unique, day)
+		}
+	}
+	return unique
+}
+
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	year, month, day := t.AddDate(0, 0, -offset).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"to_do_api/database"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/taskquery"
+	"to_do_api/webhooks"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GormTaskRepository keeps tasks in the application database. Each write
+// commits together with everything that depends on it: the event log, the
+// webhook outbox, reminder times and, on delete, reminders and CalDAV
+// names. Given a transaction, or a context carrying one from
+// database.WithTx, its writes become part of it.
+type GormTaskRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTaskRepository(db *gorm.DB) *GormTaskRepository {
+	return &GormTaskRepository{db: db}
+}
+
+// Transaction runs fn in a database transaction carried by the context it
+// is given, committing if fn returns nil.
+func (r *GormTaskRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return fn(database.WithTx(ctx, tx))
+	})
+}
+
+func (r *GormTaskRepository) Create(ctx context.Context, task models.Task) (models.Task, []models.TaskEvent, error) {
+	var event models.TaskEvent
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		task.Version = 1
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		var err error
+		event, err = recordTaskChange(tx, models.EventTaskCreated, task)
+		return err
+	})
+	if err != nil {
+		return task, nil, err
+	}
+	return task, []models.TaskEvent{event}, nil
+}
+
+func (r *GormTaskRepository) Get(ctx context.Context, id uuid.UUID) (models.Task, error) {
+	var task models.Task
+	err := database.Conn(ctx, r.db).First(&task, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return task, ErrNotFound
+	}
+	return task, err
+}
+
+func (r *GormTaskRepository) List(ctx context.Context, userID uuid.UUID, filter TaskFilter) ([]models.Task, error) {
+	var tasks []models.Task
+	query := ApplyTaskFilter(database.Conn(ctx, r.db).Where("user_id = ?", userID), filter)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	err := query.Order("created_at, id").Find(&tasks).Error
+	return tasks, err
+}
+
+// Each reads the tasks with a cursor.
+func (r *GormTaskRepository) Each(ctx context.Context, userID uuid.UUID, filter TaskFilter, fn func(models.Task) error) error {
+	db := database.Conn(ctx, r.db)
+	query := ApplyTaskFilter(db.Model(&models.Task{}).Where("user_id = ?", userID), filter)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	rows, err := query.Order("created_at, id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.Task
+		if err := db.ScanRows(rows, &task); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ApplyTaskFilter narrows a query on tasks by filter, for callers that
+// build further on the query, such as exports and bulk updates.
+func ApplyTaskFilter(query *gorm.DB, filter TaskFilter) *gorm.DB {
+	if filter.IDs != nil {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.DueBefore != nil {
+		query = query.Where("due_date < ?", filter.DueBefore.UTC())
+	}
+	if filter.DueAfter != nil {
+		query = query.Where("due_date > ?", filter.DueAfter.UTC())
+	}
+	if filter.HasDueDate != nil {
+		if *filter.HasDueDate {
+			query = query.Where("due_date IS NOT NULL")
+		} else {
+			query = query.Where("due_date IS NULL")
+		}
+	}
+	if filter.Query != nil {
+		query = taskquery.Apply(query, filter.Query, filter.now())
+	}
+	return query
+}
+
+func (r *GormTaskRepository) Update(ctx context.Context, current, next models.Task) (models.Task, []models.TaskEvent, error) {
+	var recorded []models.TaskEvent
+	task := current
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&task).
+			Select("title", "description", "status", "due_date", "priority", "project", "labels", "recurrence", "parent_id", "version").
+			Where("version = ?", current.Version).
+			Updates(models.Task{
+				Title:       next.Title,
+				Description: next.Description,
+				Status:      next.Status,
+				DueDate:     next.DueDate,
+				Priority:    next.Priority,
+				Project:     next.Project,
+				Labels:      next.Labels,
+				Recurrence:  next.Recurrence,
+				ParentID:    next.ParentID,
+				Version:     current.Version + 1,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		if !sameTime(current.DueDate, task.DueDate) {
+			if err := rescheduleReminders(tx, task); err != nil {
+				return err
+			}
+		}
+
+		eventTypes := []string{models.EventTaskUpdated}
+		if !current.Status && task.Status {
+			eventTypes = append(eventTypes, models.EventTaskCompleted)
+		}
+		for _, eventType := range eventTypes {
+			event, err := recordTaskChange(tx, eventType, task)
+			if err != nil {
+				return err
+			}
+			recorded = append(recorded, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return current, nil, err
+	}
+	return task, recorded, nil
+}
+
+func (r *GormTaskRepository) Delete(ctx context.Context, task models.Task) ([]models.TaskEvent, error) {
+	var event models.TaskEvent
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var subtasks int64
+		if err := tx.Model(&models.Task{}).Where("parent_id = ?", task.ID).Count(&subtasks).Error; err != nil {
+			return err
+		}
+		if subtasks > 0 {
+			return ErrHasSubtasks
+		}
+
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.Reminder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.CalDAVResource{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("version = ?", task.Version).Delete(&task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		var err error
+		event, err = recordTaskChange(tx, models.EventTaskDeleted, task)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []models.TaskEvent{event}, nil
+}
+
+// recordTaskChange writes everything that has to commit together with a task
+// change: the webhook outbox rows and the event log entry.
+func recordTaskChange(tx *gorm.DB, eventType string, task models.Task) (models.TaskEvent, error) {
+	if err := webhooks.Enqueue(tx, task.UserID, eventType, task); err != nil {
+		return models.TaskEvent{}, err
+	}
+	return events.Record(tx, eventType, task)
+}
+
+// rescheduleReminders moves pending offset reminders after the task's due
+// date changed. Reminders whose due date was removed are left where they were.
+func rescheduleReminders(tx *gorm.DB, task models.Task) error {
+	if task.DueDate == nil {
+		return nil
+	}
+
+	var reminders []models.Reminder
+	if err := tx.Where("task_id = ? AND status = ? AND offset_minutes IS NOT NULL", task.ID, models.ReminderStatusPending).Find(&reminders).Error; err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		if err := tx.Model(&models.Reminder{}).Where("id = ?", reminder.ID).Update("fire_at", reminder.FireTime(task)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
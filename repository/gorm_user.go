@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GormUserRepository keeps users in the application database.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("email = ?", user.Email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+		return tx.Create(&user).Error
+	})
+	return user, err
+}
+
+func (r *GormUserRepository) Get(ctx context.Context, id uuid.UUID) (models.User, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *GormUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.first(ctx, "email = ?", email)
+}
+
+func (r *GormUserRepository) first(ctx context.Context, condition string, value interface{}) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where(condition, value).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrNotFound
+	}
+	return user, err
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"to_do_api/models"
+	"to_do_api/taskquery"
+
+	"github.com/google/uuid"
+)
+
+var errDuplicateTask = errors.New("task already exists")
+
+// MemoryTaskRepository keeps tasks and their event log in process. It has no
+// reminders, webhooks or CalDAV names to keep in step, so it suits tests and
+// tools that only deal in tasks. A transaction holds off other writes until
+// it ends, but reads outside it see its writes as they are made.
+type MemoryTaskRepository struct {
+	// txMu is held by the running transaction, or by a single write made
+	// outside one.
+	txMu        sync.Mutex
+	mu          sync.Mutex
+	tasks       map[uuid.UUID]models.Task
+	events      []models.TaskEvent
+	lastEventID uint64
+}
+
+func NewMemoryTaskRepository() *MemoryTaskRepository {
+	return &MemoryTaskRepository{tasks: make(map[uuid.UUID]models.Task)}
+}
+
+type memoryTxKey struct{}
+
+// Transaction runs fn and, if it fails, puts the tasks and event log back
+// as they were before it.
+func (r *MemoryTaskRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Value(memoryTxKey{}) != r {
+		r.txMu.Lock()
+		defer r.txMu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, r)
+	}
+
+	r.mu.Lock()
+	tasks := make(map[uuid.UUID]models.Task, len(r.tasks))
+	for id, task := range r.tasks {
+		tasks[id] = task
+	}
+	events, lastEventID := len(r.events), r.lastEventID
+	r.mu.Unlock()
+
+	err := fn(ctx)
+	if err != nil {
+		r.mu.Lock()
+		r.tasks, r.events, r.lastEventID = tasks, r.events[:events], lastEventID
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// write waits for any transaction other than the one ctx is part of and
+// returns the function that lets the next one start.
+func (r *MemoryTaskRepository) write(ctx context.Context) func() {
+	if ctx.Value(memoryTxKey{}) == r {
+		return func() {}
+	}
+	r.txMu.Lock()
+	return r.txMu.Unlock
+}
+
+func (r *MemoryTaskRepository) Create(ctx context.Context, task models.Task) (models.Task, []models.TaskEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return task, nil, err
+	}
+	defer r.write(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if task.ID == uuid.Nil {
+		task.ID = uuid.New()
+	}
+	if _, ok := r.tasks[task.ID]; ok {
+		return task, nil, errDuplicateTask
+	}
+	task.Version = 1
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if task.UpdatedAt.IsZero() {
+		task.UpdatedAt = task.CreatedAt
+	}
+	task = copyTask(task)
+	r.tasks[task.ID] = task
+
+	event, err := r.record(models.EventTaskCreated, task)
+	if err != nil {
+		delete(r.tasks, task.ID)
+		return task, nil, err
+	}
+	return copyTask(task), []models.TaskEvent{event}, nil
+}
+
+func (r *MemoryTaskRepository) Get(ctx context.Context, id uuid.UUID) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return models.Task{}, ErrNotFound
+	}
+	return copyTask(task), nil
+}
+
+func (r *MemoryTaskRepository) List(ctx context.Context, userID uuid.UUID, filter TaskFilter) ([]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := filter.now()
+	tasks := []models.Task{}
+	for _, task := range r.tasks {
+		if task.UserID == userID && matchesFilter(task, filter, now) {
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID.String() < tasks[j].ID.String()
+	})
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+func (r *MemoryTaskRepository) Each(ctx context.Context, userID uuid.UUID, filter TaskFilter, fn func(models.Task) error) error {
+	tasks, err := r.List(ctx, userID, filter)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchesFilter(task models.Task, filter TaskFilter, now time.Time) bool {
+	if filter.IDs != nil && !slices.Contains(filter.IDs, task.ID) {
+		return false
+	}
+	if filter.Status != nil && task.Status != *filter.Status {
+		return false
+	}
+	if filter.DueBefore != nil && (task.DueDate == nil || !task.DueDate.Before(*filter.DueBefore)) {
+		return false
+	}
+	if filter.DueAfter != nil && (task.DueDate == nil || !task.DueDate.After(*filter.DueAfter)) {
+		return false
+	}
+	if filter.HasDueDate != nil && (task.DueDate != nil) != *filter.HasDueDate {
+		return false
+	}
+	return filter.Query == nil || taskquery.Match(filter.Query, task, now)
+}
+
+func (r *MemoryTaskRepository) Update(ctx context.Context, current, next models.Task) (models.Task, []models.TaskEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return current, nil, err
+	}
+	defer r.write(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tasks[current.ID]
+	if !ok || stored.Version != current.Version {
+		return current, nil, ErrVersionConflict
+	}
+
+	task := stored
+	task.Title = next.Title
+	task.Description = next.Description
+	task.Status = next.Status
+	task.DueDate = next.DueDate
+	task.Priority = next.Priority
+	task.Project = next.Project
+	task.Labels = next.Labels
+	task.Recurrence = next.Recurrence
+	task.ParentID = next.ParentID
+	task.Version++
+	task.UpdatedAt = time.Now()
+	task = copyTask(task)
+
+	eventTypes := []string{models.EventTaskUpdated}
+	if !stored.Status && task.Status {
+		eventTypes = append(eventTypes, models.EventTaskCompleted)
+	}
+	var recorded []models.TaskEvent
+	for _, eventType := range eventTypes {
+		event, err := r.record(eventType, task)
+		if err != nil {
+			r.events = r.events[:len(r.events)-len(recorded)]
+			return current, nil, err
+		}
+		recorded = append(recorded, event)
+	}
+	r.tasks[task.ID] = task
+	return copyTask(task), recorded, nil
+}
+
+func (r *MemoryTaskRepository) Delete(ctx context.Context, task models.Task) ([]models.TaskEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer r.write(ctx)()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tasks[task.ID]
+	if !ok || stored.Version != task.Version {
+		return nil, ErrVersionConflict
+	}
+
+	for _, candidate := range r.tasks {
+		if candidate.ParentID != nil && *candidate.ParentID == task.ID {
+			return nil, ErrHasSubtasks
+		}
+	}
+
+	event, err := r.record(models.EventTaskDeleted, stored)
+	if err != nil {
+		return nil, err
+	}
+	delete(r.tasks, task.ID)
+	return []models.TaskEvent{event}, nil
+}
+
+// Events returns the recorded events in order.
+func (r *MemoryTaskRepository) Events() []models.TaskEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.TaskEvent(nil), r.events...)
+}
+
+func (r *MemoryTaskRepository) record(eventType string, task models.Task) (models.TaskEvent, error) {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return models.TaskEvent{}, err
+	}
+	r.lastEventID++
+	event := models.TaskEvent{
+		ID:        r.lastEventID,
+		UserID:    task.UserID,
+		TaskID:    task.ID,
+		Type:      eventType,
+		Payload:   string(payload),
+		CreatedAt: time.Now(),
+	}
+	r.events = append(r.events, event)
+	return event, nil
+}
+
+// copyTask detaches the slice and pointers of task from the caller's, so
+// that neither side can change the other's copy.
+func copyTask(task models.Task) models.Task {
+	if task.Labels != nil {
+		task.Labels = append([]string{}, task.Labels...)
+	}
+	if task.DueDate != nil {
+		dueDate := *task.DueDate
+		task.DueDate = &dueDate
+	}
+	if task.ParentID != nil {
+		parentID := *task.ParentID
+		task.ParentID = &parentID
+	}
+	return task
+}
+
+// MemoryUserRepository keeps user accounts in process.
+type MemoryUserRepository struct {
+	mu      sync.Mutex
+	users   map[uuid.UUID]models.User
+	byEmail map[string]uuid.UUID
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:   make(map[uuid.UUID]models.User),
+		byEmail: make(map[string]uuid.UUID),
+	}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return user, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byEmail[user.Email]; ok {
+		return user, ErrEmailTaken
+	}
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	r.users[user.ID] = user
+	r.byEmail[user.Email] = user.ID
+	return user, nil
+}
+
+func (r *MemoryUserRepository) Get(ctx context.Context, id uuid.UUID) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.byEmail[email]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return r.users[id], nil
+}
@@ -0,0 +1,87 @@
+// Package repository stores tasks and users behind interfaces, so that the
+// rules in package service do not depend on how they are kept. The Gorm
+// repositories use the application database, PostgreSQL or SQLite; the
+// Memory ones keep everything in process, for tests and tools.
+//
+// Every implementation must pass the contract suite in repositorytest.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"to_do_api/models"
+	"to_do_api/taskquery"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrVersionConflict reports a write based on a version of a task that
+	// is no longer the stored one.
+	ErrVersionConflict = errors.New("task version changed")
+	// ErrHasSubtasks reports a delete of a task that still has subtasks.
+	ErrHasSubtasks = errors.New("task has subtasks")
+	ErrEmailTaken  = errors.New("email already registered")
+)
+
+// TaskFilter narrows a task listing. Unset fields do not filter.
+type TaskFilter struct {
+	// IDs, if not nil, keeps only the tasks with these IDs.
+	IDs        []uuid.UUID
+	Status     *bool
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	HasDueDate *bool
+	// Query is a parsed taskquery expression; its relative dates are
+	// resolved against Now, or the current time if Now is zero.
+	Query taskquery.Node
+	Now   time.Time
+	// Limit, if positive, caps how many tasks a listing returns.
+	Limit int
+}
+
+func (f TaskFilter) now() time.Time {
+	if f.Now.IsZero() {
+		return time.Now()
+	}
+	return f.Now
+}
+
+// TaskRepository stores tasks along with the log of their changes. Every
+// write returns the events it recorded, in order, for the caller to
+// publish once the write is committed.
+type TaskRepository interface {
+	// Create stores a new task at version 1. Its ID and timestamps are
+	// set unless the caller set them.
+	Create(ctx context.Context, task models.Task) (models.Task, []models.TaskEvent, error)
+	// Get returns the task with id, of any user, or ErrNotFound.
+	Get(ctx context.Context, id uuid.UUID) (models.Task, error)
+	// List returns a user's tasks matching filter, oldest first.
+	List(ctx context.Context, userID uuid.UUID, filter TaskFilter) ([]models.Task, error)
+	// Each calls fn with the tasks List would return, one at a time, so a
+	// caller streaming them need not hold them all. It stops at the first
+	// error fn returns and returns it.
+	Each(ctx context.Context, userID uuid.UUID, filter TaskFilter, fn func(models.Task) error) error
+	// Update writes next's client-writable fields over current, which must
+	// still be the stored version, and bumps the version. Completing a task
+	// records a completed event after the updated one.
+	Update(ctx context.Context, current, next models.Task) (models.Task, []models.TaskEvent, error)
+	// Delete removes task, which must still be the stored version and have
+	// no subtasks.
+	Delete(ctx context.Context, task models.Task) ([]models.TaskEvent, error)
+	// Transaction runs fn and commits the writes made with the context it
+	// passes fn together, or none of them if fn returns an error. Calls
+	// nest, a failed inner call undoing only its own writes.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// UserRepository stores user accounts. Emails are unique and compared
+// exactly.
+type UserRepository interface {
+	// Create stores a new user, or fails with ErrEmailTaken.
+	Create(ctx context.Context, user models.User) (models.User, error)
+	Get(ctx context.Context, id uuid.UUID) (models.User, error)
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+}
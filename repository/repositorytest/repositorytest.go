@@ -0,0 +1,369 @@
+// Package repositorytest is the contract every repository implementation
+// must pass. An implementation's tests call TestTaskRepository and
+// TestUserRepository with a constructor for an empty store.
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/taskquery"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaskRepository runs the task contract against stores from newRepo,
+// which must return an empty store on every call.
+func TestTaskRepository(t *testing.T, newRepo func(t *testing.T) repository.TaskRepository) {
+	t.Run("CreateGet", func(t *testing.T) { testCreateGet(t, newRepo(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newRepo(t)) })
+	t.Run("Each", func(t *testing.T) { testEach(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("UpdateStale", func(t *testing.T) { testUpdateStale(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("DeleteStale", func(t *testing.T) { testDeleteStale(t, newRepo(t)) })
+	t.Run("Transaction", func(t *testing.T) { testTransaction(t, newRepo(t)) })
+}
+
+// TestUserRepository runs the user contract against stores from newRepo,
+// which must return an empty store on every call.
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T) repository.UserRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	user, err := repo.Create(ctx, models.User{Email: "ada@example.com", Password: "hash"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, user.ID)
+
+	found, err := repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user, found)
+
+	found, err = repo.GetByEmail(ctx, "ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user, found)
+
+	_, err = repo.Create(ctx, models.User{Email: "ada@example.com", Password: "other"})
+	assert.ErrorIs(t, err, repository.ErrEmailTaken)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByEmail(ctx, "grace@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func create(t *testing.T, repo repository.TaskRepository, task models.Task) models.Task {
+	t.Helper()
+	task, _, err := repo.Create(context.Background(), task)
+	require.NoError(t, err)
+	return task
+}
+
+func testCreateGet(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	due := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+
+	task, recorded, err := repo.Create(ctx, models.Task{
+		Title:    "Write report",
+		DueDate:  &due,
+		Priority: models.PriorityHigh,
+		Labels:   []string{"work"},
+		UserID:   userID,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, task.ID)
+	assert.Equal(t, 1, task.Version)
+	assert.False(t, task.CreatedAt.IsZero())
+
+	require.Len(t, recorded, 1)
+	assert.Equal(t, models.EventTaskCreated, recorded[0].Type)
+	assert.Equal(t, task.ID, recorded[0].TaskID)
+	assert.Equal(t, userID, recorded[0].UserID)
+	var payload models.Task
+	require.NoError(t, json.Unmarshal([]byte(recorded[0].Payload), &payload))
+	assert.Equal(t, "Write report", payload.Title)
+
+	found, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Write report", found.Title)
+	assert.Equal(t, []string{"work"}, found.Labels)
+	assert.Equal(t, models.PriorityHigh, found.Priority)
+	require.NotNil(t, found.DueDate)
+	assert.True(t, due.Equal(*found.DueDate))
+
+	id := uuid.New()
+	task = create(t, repo, models.Task{ID: id, Title: "Keeps its ID", UserID: userID})
+	assert.Equal(t, id, task.ID)
+
+	_, err = repo.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testList(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	soon := start.Add(48 * time.Hour)
+	later := start.Add(30 * 24 * time.Hour)
+
+	report := create(t, repo, models.Task{Title: "Write report", DueDate: &soon, Priority: models.PriorityHigh, UserID: userID, CreatedAt: start})
+	milk := create(t, repo, models.Task{Title: "Buy milk", Status: true, UserID: userID, CreatedAt: start.Add(time.Minute)})
+	taxes := create(t, repo, models.Task{Title: "File taxes", DueDate: &later, Project: "Home", UserID: userID, CreatedAt: start.Add(2 * time.Minute)})
+	create(t, repo, models.Task{Title: "Someone else's", UserID: uuid.New(), CreatedAt: start})
+
+	titles := func(filter repository.TaskFilter) []string {
+		t.Helper()
+		tasks, err := repo.List(ctx, userID, filter)
+		require.NoError(t, err)
+		titles := []string{}
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+	yes, no := true, false
+	cutoff := start.Add(7 * 24 * time.Hour)
+
+	assert.Equal(t, []string{report.Title, milk.Title, taxes.Title}, titles(repository.TaskFilter{}))
+	assert.Equal(t, []string{milk.Title}, titles(repository.TaskFilter{Status: &yes}))
+	assert.Equal(t, []string{report.Title}, titles(repository.TaskFilter{DueBefore: &cutoff}))
+	assert.Equal(t, []string{taxes.Title}, titles(repository.TaskFilter{DueAfter: &cutoff}))
+	assert.Equal(t, []string{report.Title, taxes.Title}, titles(repository.TaskFilter{HasDueDate: &yes}))
+	assert.Equal(t, []string{milk.Title}, titles(repository.TaskFilter{HasDueDate: &no}))
+
+	assert.Equal(t, []string{report.Title, taxes.Title}, titles(repository.TaskFilter{IDs: []uuid.UUID{taxes.ID, report.ID, uuid.New()}}))
+	assert.Empty(t, titles(repository.TaskFilter{IDs: []uuid.UUID{}}))
+	assert.Equal(t, []string{report.Title, milk.Title}, titles(repository.TaskFilter{Limit: 2}))
+
+	query, err := taskquery.Parse("priority:high OR project:home")
+	require.NoError(t, err)
+	assert.Equal(t, []string{report.Title, taxes.Title}, titles(repository.TaskFilter{Query: query}))
+
+	query, err = taskquery.Parse("due:<7d AND NOT status:done")
+	require.NoError(t, err)
+	assert.Equal(t, []string{report.Title}, titles(repository.TaskFilter{Query: query, Now: start}))
+
+	tasks, err := repo.List(ctx, uuid.New(), repository.TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func testEach(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	report := create(t, repo, models.Task{Title: "Write report", UserID: userID, CreatedAt: start})
+	milk := create(t, repo, models.Task{Title: "Buy milk", Status: true, UserID: userID, CreatedAt: start.Add(time.Minute)})
+	taxes := create(t, repo, models.Task{Title: "File taxes", UserID: userID, CreatedAt: start.Add(2 * time.Minute)})
+	create(t, repo, models.Task{Title: "Someone else's", UserID: uuid.New(), CreatedAt: start})
+
+	var titles []string
+	err := repo.Each(ctx, userID, repository.TaskFilter{}, func(task models.Task) error {
+		titles = append(titles, task.Title)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{report.Title, milk.Title, taxes.Title}, titles)
+
+	no := false
+	titles = nil
+	err = repo.Each(ctx, userID, repository.TaskFilter{Status: &no}, func(task models.Task) error {
+		titles = append(titles, task.Title)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{report.Title, taxes.Title}, titles)
+
+	failed := errors.New("failed")
+	calls := 0
+	err = repo.Each(ctx, userID, repository.TaskFilter{}, func(models.Task) error {
+		calls++
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, 1, calls)
+}
+
+func testUpdate(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	due := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+	parent := create(t, repo, models.Task{Title: "Launch", UserID: userID})
+	task := create(t, repo, models.Task{Title: "Draft", Description: "first pass", UserID: userID})
+
+	next := task
+	next.Title = "Draft post"
+	next.Description = ""
+	next.DueDate = &due
+	next.Labels = []string{"blog"}
+	next.ParentID = &parent.ID
+	updated, recorded, err := repo.Update(ctx, task, next)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+	assert.Equal(t, "Draft post", updated.Title)
+	assert.Empty(t, updated.Description)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, models.EventTaskUpdated, recorded[0].Type)
+
+	found, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, found.Version)
+	assert.Equal(t, "Draft post", found.Title)
+	assert.Empty(t, found.Description)
+	assert.Equal(t, []string{"blog"}, found.Labels)
+	require.NotNil(t, found.ParentID)
+	assert.Equal(t, parent.ID, *found.ParentID)
+	require.NotNil(t, found.DueDate)
+	assert.True(t, due.Equal(*found.DueDate))
+
+	// Completing a task records a completed event after the update.
+	next = found
+	next.Status = true
+	updated, recorded, err = repo.Update(ctx, found, next)
+	require.NoError(t, err)
+	assert.True(t, updated.Status)
+	require.Len(t, recorded, 2)
+	assert.Equal(t, models.EventTaskUpdated, recorded[0].Type)
+	assert.Equal(t, models.EventTaskCompleted, recorded[1].Type)
+	assert.Less(t, recorded[0].ID, recorded[1].ID)
+
+	// Server-owned fields are not taken from next.
+	next = updated
+	next.UserID = uuid.New()
+	next.Version = 42
+	updated, _, err = repo.Update(ctx, updated, next)
+	require.NoError(t, err)
+	assert.Equal(t, userID, updated.UserID)
+	assert.Equal(t, 4, updated.Version)
+}
+
+func testUpdateStale(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	task := create(t, repo, models.Task{Title: "Draft", UserID: uuid.New()})
+
+	next := task
+	next.Title = "First"
+	_, _, err := repo.Update(ctx, task, next)
+	require.NoError(t, err)
+
+	next.Title = "Second"
+	_, recorded, err := repo.Update(ctx, task, next)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.Empty(t, recorded)
+
+	found, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", found.Title)
+	assert.Equal(t, 2, found.Version)
+
+	_, _, err = repo.Update(ctx, models.Task{ID: uuid.New(), Version: 1}, next)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+}
+
+func testDelete(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	root := create(t, repo, models.Task{Title: "Launch", UserID: userID})
+	child := create(t, repo, models.Task{Title: "Draft", ParentID: &root.ID, UserID: userID})
+	grandchild := create(t, repo, models.Task{Title: "Outline", ParentID: &child.ID, UserID: userID})
+	other := create(t, repo, models.Task{Title: "Unrelated", UserID: userID})
+
+	// A task with subtasks stays until they are gone.
+	for _, task := range []models.Task{root, child} {
+		_, err := repo.Delete(ctx, task)
+		assert.ErrorIs(t, err, repository.ErrHasSubtasks)
+	}
+
+	for _, task := range []models.Task{grandchild, child, root} {
+		recorded, err := repo.Delete(ctx, task)
+		require.NoError(t, err)
+		require.Len(t, recorded, 1)
+		assert.Equal(t, models.EventTaskDeleted, recorded[0].Type)
+		assert.Equal(t, task.ID, recorded[0].TaskID)
+	}
+
+	for _, id := range []uuid.UUID{root.ID, child.ID, grandchild.ID} {
+		_, err := repo.Get(ctx, id)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	}
+	_, err := repo.Get(ctx, other.ID)
+	assert.NoError(t, err)
+}
+
+func testDeleteStale(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	task := create(t, repo, models.Task{Title: "Draft", UserID: uuid.New()})
+
+	next := task
+	next.Title = "Changed"
+	_, _, err := repo.Update(ctx, task, next)
+	require.NoError(t, err)
+
+	recorded, err := repo.Delete(ctx, task)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.Empty(t, recorded)
+
+	_, err = repo.Get(ctx, task.ID)
+	assert.NoError(t, err)
+}
+
+func testTransaction(t *testing.T, repo repository.TaskRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	kept := create(t, repo, models.Task{Title: "Kept", UserID: userID})
+	failed := errors.New("failed")
+
+	titles := func() []string {
+		t.Helper()
+		tasks, err := repo.List(ctx, userID, repository.TaskFilter{})
+		require.NoError(t, err)
+		titles := []string{}
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	// A failed transaction leaves nothing behind, including writes it
+	// could already read back.
+	err := repo.Transaction(ctx, func(ctx context.Context) error {
+		task, _, err := repo.Create(ctx, models.Task{Title: "Rolled back", UserID: userID})
+		require.NoError(t, err)
+		_, err = repo.Get(ctx, task.ID)
+		require.NoError(t, err)
+
+		next := kept
+		next.Title = "Renamed"
+		_, _, err = repo.Update(ctx, kept, next)
+		require.NoError(t, err)
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []string{"Kept"}, titles())
+	found, err := repo.Get(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Version)
+
+	// A failed inner transaction undoes only its own writes.
+	err = repo.Transaction(ctx, func(ctx context.Context) error {
+		_, _, err := repo.Create(ctx, models.Task{Title: "Committed", UserID: userID, CreatedAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		err = repo.Transaction(ctx, func(ctx context.Context) error {
+			_, err := repo.Delete(ctx, kept)
+			require.NoError(t, err)
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kept", "Committed"}, titles())
+}
@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"to_do_api/database"
+	"to_do_api/models"
+	"to_do_api/notify"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultInterval = 15 * time.Second
+	defaultLease    = 2 * time.Minute
+	maxAttempts     = 5
+	batchSize       = 100
+)
+
+// ReminderScheduler polls the reminders table and fires due reminders.
+//
+// State lives entirely in the database, so reminders survive restarts. A
+// reminder is claimed with a conditional UPDATE that only succeeds for one
+// worker, and the claim is a lease: if the process dies mid-delivery the
+// reminder becomes claimable again once LockedUntil has passed.
+type ReminderScheduler struct {
+	DB       *gorm.DB
+	Channels map[string]notify.Channel
+	Interval time.Duration
+	Lease    time.Duration
+	Now      func() time.Time
+}
+
+func NewReminderScheduler(db *gorm.DB, channels map[string]notify.Channel) *ReminderScheduler {
+	return &ReminderScheduler{
+		DB:       db,
+		Channels: channels,
+		Interval: defaultInterval,
+		Lease:    defaultLease,
+		Now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run fires due reminders every Interval until ctx is cancelled.
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			log.Println("Reminder scheduler:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce fires every reminder that is currently due and returns how many
+// were delivered.
+func (s *ReminderScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.Now()
+
+	var due []models.Reminder
+	err := s.DB.WithContext(ctx).
+		Where("status = ? AND fire_at <= ?", models.ReminderStatusPending, now).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("fire_at").
+		Limit(batchSize).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		claimed, err := s.claim(ctx, reminder)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.deliver(ctx, reminder); err != nil {
+			s.fail(ctx, reminder, err)
+			continue
+		}
+		s.markSent(ctx, reminder)
+		sent++
+	}
+	return sent, nil
+}
+
+// claim takes the lease from the time of the claim, not of the batch, so
+// reminders late in a batch get a full lease too.
+func (s *ReminderScheduler) claim(ctx context.Context, reminder models.Reminder) (bool, error) {
+	now := s.Now()
+	lockedUntil := now.Add(s.Lease)
+	result := s.DB.WithContext(ctx).Model(&models.Reminder{}).
+		Where("id = ? AND status = ?", reminder.ID, models.ReminderStatusPending).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Updates(map[string]interface{}{
+			"locked_until": lockedUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// deliver fires reminder, giving up after a third of the lease, so that it
+// is sent and recorded before another worker may claim it again.
+func (s *ReminderScheduler) deliver(ctx context.Context, reminder models.Reminder) error {
+	ctx, cancel := context.WithTimeout(ctx, s.Lease/3)
+	defer cancel()
+	return s.fire(ctx, reminder)
+}
+
+func (s *ReminderScheduler) fire(ctx context.Context, reminder models.Reminder) error {
+	channel, ok := s.Channels[reminder.Channel]
+	if !ok {
+		return fmt.Errorf("unknown channel %q", reminder.Channel)
+	}
+
+	var task models.Task
+	if err := s.DB.WithContext(ctx).First(&task, "id = ?", reminder.TaskID).Error; err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := s.DB.WithContext(ctx).First(&user, "id = ?", reminder.UserID).Error; err != nil {
+		return err
+	}
+
+	msg := notify.Message{
+		User:     user,
+		Task:     task,
+		Reminder: reminder,
+		Subject:  "Reminder: " + task.Title,
+		Body:     reminderBody(task),
+	}
+	return channel.Send(ctx, msg)
+}
+
+// markSent records a delivered reminder. Once sent it must not be failed
+// and retried, so recording is retried while the claim's lease keeps other
+// workers away, and the first attempt is made even during shutdown.
+func (s *ReminderScheduler) markSent(ctx context.Context, reminder models.Reminder) {
+	updates := map[string]interface{}{
+		"status":       models.ReminderStatusSent,
+		"sent_at":      s.Now(),
+		"locked_until": nil,
+		"last_error":   "",
+	}
+	backoff := database.Backoff{Initial: time.Second, Max: 10 * time.Second, Timeout: s.Lease / 2}
+	err := database.Retry(ctx, backoff, func() error {
+		return s.DB.WithContext(context.WithoutCancel(ctx)).Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updates).Error
+	})
+	if err != nil {
+		log.Println("Reminder scheduler: failed to record delivery:", err)
+	}
+}
+
+// fail keeps the reminder locked for another lease so the retry is spaced
+// out, or marks it failed once it has used up its attempts.
+func (s *ReminderScheduler) fail(ctx context.Context, reminder models.Reminder, cause error) {
+	updates := map[string]interface{}{
+		"locked_until": s.Now().Add(s.Lease),
+		"last_error":   cause.Error(),
+	}
+	if reminder.Attempts+1 >= maxAttempts {
+		updates["status"] = models.ReminderStatusFailed
+	}
+
+	if err := s.DB.WithContext(ctx).Model(&models.Reminder{}).Where("id = ?", reminder.ID).Updates(updates).Error; err != nil {
+		log.Println("Reminder scheduler: failed to record error:", err)
+	}
+}
+
+func reminderBody(task models.Task) string {
+	if task.DueDate == nil {
+		return task.Title
+	}
+	return fmt.Sprintf("%s is due %s", task.Title, task.DueDate.UTC().Format(time.RFC1123))
+}
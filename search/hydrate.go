@@ -0,0 +1,37 @@
+package search
+
+import (
+	"to_do_api/models"
+
+	"gorm.io/gorm"
+)
+
+// hydrate loads the tasks for ranked rows, keeping the rank order.
+func hydrate(query *gorm.DB, n int, row func(int) (string, float64, string)) ([]Result, error) {
+	if n == 0 {
+		return []Result{}, nil
+	}
+
+	ids := make([]string, n)
+	for i := 0; i < n; i++ {
+		ids[i], _, _ = row(i)
+	}
+
+	var tasks []models.Task
+	if err := query.Session(&gorm.Session{NewDB: true}).Where("id IN ?", ids).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID.String()] = task
+	}
+
+	results := make([]Result, 0, n)
+	for i := 0; i < n; i++ {
+		id, rank, snippet := row(i)
+		if task, ok := byID[id]; ok {
+			results = append(results, Result{Task: task, Rank: rank, Snippet: snippet})
+		}
+	}
+	return results, nil
+}
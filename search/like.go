@@ -0,0 +1,166 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"to_do_api/models"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const snippetRadius = 40
+
+// Like is a portable fallback built on LIKE, used where Postgres full-text
+// search is unavailable (such as the sqlite databases in tests). Ranking
+// counts term occurrences, weighting the title above the description.
+type Like struct{}
+
+func (Like) Search(query *gorm.DB, text string, limit, offset int) ([]Result, error) {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	query = query.Session(&gorm.Session{})
+	for _, term := range terms {
+		// Terms are letters and digits only, so they hold no LIKE wildcards.
+		pattern := "%" + term + "%"
+		query = query.Where("(LOWER(tasks.title) LIKE ? OR LOWER(tasks.description) LIKE ?)", pattern, pattern)
+	}
+
+	var tasks []models.Task
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(tasks))
+	for i, task := range tasks {
+		results[i] = Result{Task: task, Rank: likeRank(task, terms), Snippet: likeSnippet(task, terms)}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Task.ID.String() < results[j].Task.ID.String()
+	})
+
+	if offset >= len(results) {
+		return []Result{}, nil
+	}
+	end := offset + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	return results[offset:end], nil
+}
+
+func likeRank(task models.Task, terms []string) float64 {
+	title := strings.ToLower(task.Title)
+	description := strings.ToLower(task.Description)
+
+	var rank float64
+	for _, term := range terms {
+		rank += 2 * float64(strings.Count(title, term))
+		rank += float64(strings.Count(description, term))
+	}
+	return rank
+}
+
+// likeSnippet cuts a window around the first match and highlights every term
+// inside it. Matching is case-insensitive rune by rune on the original text,
+// since lower-casing can change the length of the text and so the offsets.
+func likeSnippet(task models.Task, terms []string) string {
+	text := task.Title
+	if task.Description != "" {
+		text += " — " + task.Description
+	}
+	text = strings.NewReplacer(matchStart, "", matchStop, "").Replace(text)
+
+	type span struct{ start, end int }
+	var matches []span
+	for i := 0; i < len(text); {
+		longest := 0
+		for _, term := range terms {
+			if n := matchFold(text[i:], term); n > longest {
+				longest = n
+			}
+		}
+		if longest > 0 {
+			matches = append(matches, span{i, i + longest})
+			i += longest
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+
+	first := 0
+	if len(matches) > 0 {
+		first = matches[0].start
+	}
+	start, end := first-snippetRadius, first+snippetRadius*2
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	// Avoid cutting through a multi-byte character.
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	at := start
+	for _, match := range matches {
+		if match.start < start || match.end > end {
+			continue
+		}
+		b.WriteString(text[at:match.start] + matchStart + text[match.start:match.end] + matchStop)
+		at = match.end
+	}
+	b.WriteString(text[at:end])
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return markup(b.String())
+}
+
+// matchFold reports how many bytes at the start of text match the
+// lower-cased term case-insensitively, or 0 if they do not.
+func matchFold(text, term string) int {
+	n := 0
+	for _, want := range term {
+		if n >= len(text) {
+			return 0
+		}
+		got, size := utf8.DecodeRuneInString(text[n:])
+		if !foldsTo(got, want) {
+			return 0
+		}
+		n += size
+	}
+	return n
+}
+
+// foldsTo reports whether r is want under Unicode case folding, so the
+// Kelvin sign matches "k".
+func foldsTo(r, want rune) bool {
+	if r == want || unicode.ToLower(r) == want {
+		return true
+	}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f == want {
+			return true
+		}
+	}
+	return false
+}
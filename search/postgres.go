@@ -0,0 +1,55 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// document must match the expression of the GIN index idx_tasks_search in
+// the database migrations, or Postgres will not use the index.
+const document = "to_tsvector('simple', coalesce(tasks.title, '') || ' ' || coalesce(tasks.description, ''))"
+
+// Postgres searches with tsvector/tsquery, ranks with ts_rank and builds
+// snippets with ts_headline.
+type Postgres struct{}
+
+func (Postgres) Search(query *gorm.DB, text string, limit, offset int) ([]Result, error) {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+
+	var rows []struct {
+		ID      string
+		Rank    float64
+		Snippet string
+	}
+	// ts_headline marks matches with the sentinels, after any the text
+	// itself contains have been removed.
+	err := query.Session(&gorm.Session{}).
+		Table("tasks").
+		Select(
+			"tasks.id, ts_rank("+document+", to_tsquery('simple', ?)) AS rank, "+
+				"ts_headline('simple', translate(coalesce(tasks.title, '') || ' — ' || coalesce(tasks.description, ''), ?, ''), to_tsquery('simple', ?), ?) AS snippet",
+			tsquery, matchStart+matchStop, tsquery, "StartSel="+matchStart+", StopSel="+matchStop+", MaxWords=30, MinWords=10",
+		).
+		Where(document+" @@ to_tsquery('simple', ?)", tsquery).
+		Order("rank DESC, tasks.id").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return hydrate(query, len(rows), func(i int) (string, float64, string) {
+		return rows[i].ID, rows[i].Rank, markup(rows[i].Snippet)
+	})
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"to_do_api/models"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// Snippets are cut with these private-use characters around matches and
+// turned into HTML afterwards by markup, so task text can never introduce
+// markup of its own.
+const (
+	matchStart = "\uE000"
+	matchStop  = "\uE001"
+)
+
+// Result is one matching task. Snippet is an HTML-escaped excerpt of the
+// matched text with matches wrapped in HighlightStart/HighlightStop.
+type Result struct {
+	Task    models.Task `json:"task"`
+	Rank    float64     `json:"rank"`
+	Snippet string      `json:"snippet"`
+}
+
+// Searcher runs a text search over an already scoped and filtered task
+// query. Every term must match, and each term also matches as a prefix, so
+// "meet" finds "meeting".
+type Searcher interface {
+	Search(query *gorm.DB, text string, limit, offset int) ([]Result, error)
+}
+
+// For picks the best implementation for the database behind db.
+func For(db *gorm.DB) Searcher {
+	if db.Dialector.Name() == "postgres" {
+		return Postgres{}
+	}
+	return Like{}
+}
+
+// Terms splits free text into lower-cased words, dropping punctuation so
+// user input cannot inject query syntax.
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// markup HTML-escapes a snippet and replaces its match sentinels with
+// HighlightStart/HighlightStop. Sentinels are only honoured in matching pairs,
+// so the result is well-formed whatever the text held.
+func markup(snippet string) string {
+	var b strings.Builder
+	open := false
+	for len(snippet) > 0 {
+		i := strings.IndexAny(snippet, matchStart+matchStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(snippet))
+			break
+		}
+		b.WriteString(html.EscapeString(snippet[:i]))
+		switch sentinel := snippet[i : i+len(matchStart)]; {
+		case sentinel == matchStart && !open:
+			b.WriteString(HighlightStart)
+			open = true
+		case sentinel == matchStop && open:
+			b.WriteString(HighlightStop)
+			open = false
+		}
+		snippet = snippet[i+len(matchStart):]
+	}
+	if open {
+		b.WriteString(HighlightStop)
+	}
+	return b.String()
+}
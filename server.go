@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// workers runs background loops, such as the reminder scheduler, until
+// they are stopped together.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+func (w *workers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels the workers and waits until they return or ctx is done.
+func (w *workers) Stop(ctx context.Context) error {
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve runs the servers until ctx is done, then shuts down within
+// timeout: draining is set so readiness probes fail, the servers stop
+// accepting connections and wait for the requests in flight, and then the
+// background workers are stopped, since those requests may still have
+// needed them. stop is called as the drain starts; for a ctx from
+// signal.NotifyContext that restores the default handling, so a second
+// signal kills the process instead of waiting.
+func serve(ctx context.Context, stop context.CancelFunc, servers []*http.Server, timeout time.Duration, draining *atomic.Bool, background *workers) error {
+	failed := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-failed:
+		background.Stop(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Println("Shutting down")
+	draining.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Requests still running at shutdown on %s: %v", srv.Addr, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := background.Stop(shutdownCtx); err != nil {
+		log.Println("Background workers still running at shutdown:", err)
+	}
+	return nil
+}
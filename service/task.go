@@ -0,0 +1,187 @@
+// Package service holds the rules for changing tasks and accounts: who may
+// touch a task, what a valid task is, how subtasks nest and how passwords
+// are kept. It reaches storage only through package repository, so the
+// same rules apply whichever implementation is behind it.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"to_do_api/metrics"
+	"to_do_api/models"
+	"to_do_api/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = repository.ErrNotFound
+	// ErrForbidden reports a task that belongs to another user.
+	ErrForbidden       = errors.New("task belongs to another user")
+	ErrInvalidParent   = errors.New("invalid parent task")
+	ErrVersionConflict = repository.ErrVersionConflict
+	ErrHasSubtasks     = repository.ErrHasSubtasks
+)
+
+// ValidationError reports input that breaks a rule of what a task may be.
+type ValidationError struct {
+	Err error
+}
+
+func (e ValidationError) Error() string { return e.Err.Error() }
+
+func (e ValidationError) Unwrap() error { return e.Err }
+
+// maxTaskDepth bounds how deeply subtasks nest.
+const maxTaskDepth = 32
+
+type TaskService struct {
+	repo repository.TaskRepository
+}
+
+func NewTaskService(repo repository.TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+// Create validates input and stores it as a new task of userID.
+func (s *TaskService) Create(ctx context.Context, userID uuid.UUID, input TaskInput) (models.Task, []models.TaskEvent, error) {
+	if err := input.Normalize(); err != nil {
+		return models.Task{}, nil, ValidationError{err}
+	}
+	return s.Insert(ctx, NewTask(userID, input))
+}
+
+// Insert stores an already validated task, such as one built by an import,
+// after checking its parent.
+func (s *TaskService) Insert(ctx context.Context, task models.Task) (models.Task, []models.TaskEvent, error) {
+	if err := s.checkParent(ctx, task.UserID, task.ID, task.ParentID); err != nil {
+		return task, nil, err
+	}
+	created, recorded, err := s.repo.Create(ctx, task)
+	if err == nil {
+		countMetric(ctx, metrics.TaskCreated)
+	}
+	return created, recorded, err
+}
+
+// Get returns the task with id if it belongs to userID, ErrForbidden if it
+// belongs to someone else and ErrNotFound if there is none.
+func (s *TaskService) Get(ctx context.Context, userID, id uuid.UUID) (models.Task, error) {
+	task, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return task, err
+	}
+	if task.UserID != userID {
+		return models.Task{}, ErrForbidden
+	}
+	return task, nil
+}
+
+func (s *TaskService) List(ctx context.Context, userID uuid.UUID, filter repository.TaskFilter) ([]models.Task, error) {
+	return s.repo.List(ctx, userID, filter)
+}
+
+// Each calls fn with each of userID's tasks matching filter, oldest first,
+// without holding them all in memory.
+func (s *TaskService) Each(ctx context.Context, userID uuid.UUID, filter repository.TaskFilter, fn func(models.Task) error) error {
+	return s.repo.Each(ctx, userID, filter, fn)
+}
+
+// Update replaces every writable field of task with input. task must be the
+// version the change is based on; a newer stored version fails with
+// ErrVersionConflict rather than being overwritten.
+func (s *TaskService) Update(ctx context.Context, userID uuid.UUID, task models.Task, input TaskInput) (models.Task, []models.TaskEvent, error) {
+	if task.UserID != userID {
+		return task, nil, ErrForbidden
+	}
+	if err := input.Normalize(); err != nil {
+		return task, nil, ValidationError{err}
+	}
+	if !sameID(task.ParentID, input.ParentID) {
+		if err := s.checkParent(ctx, task.UserID, task.ID, input.ParentID); err != nil {
+			return task, nil, err
+		}
+	}
+	updated, recorded, err := s.repo.Update(ctx, task, NewTask(task.UserID, input))
+	if err == nil && !task.Status && updated.Status {
+		countMetric(ctx, metrics.TaskCompleted)
+	}
+	return updated, recorded, err
+}
+
+// Delete removes task on the same terms as Update. A task with subtasks
+// cannot be deleted until they are.
+func (s *TaskService) Delete(ctx context.Context, userID uuid.UUID, task models.Task) ([]models.TaskEvent, error) {
+	if task.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return s.repo.Delete(ctx, task)
+}
+
+// Transaction runs fn so that the changes made with the context it is given
+// commit together, or not at all if fn returns an error. The metrics for
+// those changes are counted once they have committed.
+func (s *TaskService) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	outer, nested := ctx.Value(pendingMetricsKey{}).(*pendingMetrics)
+	pending := &pendingMetrics{}
+	err := s.repo.Transaction(context.WithValue(ctx, pendingMetricsKey{}, pending), fn)
+	if err != nil {
+		return err
+	}
+	// A nested transaction commits only with the one around it.
+	if nested {
+		*outer = append(*outer, *pending...)
+		return nil
+	}
+	for _, count := range *pending {
+		count()
+	}
+	return nil
+}
+
+type pendingMetricsKey struct{}
+
+// pendingMetrics holds the metric increments of a transaction that has not
+// committed yet.
+type pendingMetrics []func()
+
+// countMetric increments a metric now, or, inside a Transaction, once it
+// commits.
+func countMetric(ctx context.Context, count func()) {
+	if pending, ok := ctx.Value(pendingMetricsKey{}).(*pendingMetrics); ok {
+		*pending = append(*pending, count)
+		return
+	}
+	count()
+}
+
+// checkParent verifies that parentID, if set, is another task of the same
+// user and that making it taskID's parent would not create a cycle.
+func (s *TaskService) checkParent(ctx context.Context, userID, taskID uuid.UUID, parentID *uuid.UUID) error {
+	for depth, id := 0, parentID; id != nil; depth++ {
+		if *id == taskID {
+			return fmt.Errorf("%w: a task cannot be nested under itself or its subtasks", ErrInvalidParent)
+		}
+		if depth == maxTaskDepth {
+			return fmt.Errorf("%w: subtasks nest at most %d deep", ErrInvalidParent, maxTaskDepth)
+		}
+
+		parent, err := s.repo.Get(ctx, *id)
+		if errors.Is(err, repository.ErrNotFound) || err == nil && parent.UserID != userID {
+			return fmt.Errorf("%w: parent task %s not found", ErrInvalidParent, *id)
+		}
+		if err != nil {
+			return err
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"to_do_api/models"
+	"to_do_api/recurrence"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxLabels        = 50
+	maxProjectLength = 100
+)
+
+var labelPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,64}$`)
+
+// TaskInput is the client-writable part of a task. Everything else on
+// models.Task is owned by the server.
+type TaskInput struct {
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Status      bool       `json:"status"`
+	DueDate     *time.Time `json:"due_date"`
+	Priority    int        `json:"priority"`
+	Project     string     `json:"project"`
+	Labels      []string   `json:"labels"`
+	// Recurrence is an RFC 5545 RRULE such as FREQ=WEEKLY;BYDAY=SU.
+	Recurrence string `json:"recurrence"`
+	// ParentID makes the task a subtask of another of the user's tasks.
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+func InputFromTask(task models.Task) TaskInput {
+	return TaskInput{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		DueDate:     task.DueDate,
+		Priority:    task.Priority,
+		Project:     task.Project,
+		Labels:      task.Labels,
+		Recurrence:  task.Recurrence,
+		ParentID:    task.ParentID,
+	}
+}
+
+// Normalize validates what binding tags cannot express and puts the input in
+// canonical form: labels lower-cased without a leading # and deduplicated,
+// the recurrence rule rewritten canonically. Every path that writes a task
+// goes through it.
+func (input *TaskInput) Normalize() error {
+	if strings.TrimSpace(input.Title) == "" {
+		return errors.New("title is required")
+	}
+	if input.Priority < models.PriorityNone || input.Priority > models.PriorityHigh {
+		return fmt.Errorf("priority must be between %d and %d", models.PriorityNone, models.PriorityHigh)
+	}
+
+	input.Project = strings.TrimSpace(input.Project)
+	if len(input.Project) > maxProjectLength {
+		return fmt.Errorf("project must be at most %d bytes", maxProjectLength)
+	}
+
+	if len(input.Labels) > maxLabels {
+		return fmt.Errorf("at most %d labels per task", maxLabels)
+	}
+	labels := make([]string, 0, len(input.Labels))
+	seen := make(map[string]bool, len(input.Labels))
+	for _, label := range input.Labels {
+		label = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(label), "#"))
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("invalid label %q: use letters, digits, _ and -", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	input.Labels = labels
+
+	if input.Recurrence = strings.TrimSpace(input.Recurrence); input.Recurrence != "" {
+		rule, err := recurrence.Parse(input.Recurrence)
+		if err != nil {
+			return errors.New("invalid recurrence: " + err.Error())
+		}
+		input.Recurrence = rule.String()
+	}
+	return nil
+}
+
+// NewTask builds a user's new task from input.
+func NewTask(userID uuid.UUID, input TaskInput) models.Task {
+	return models.Task{
+		Title:       input.Title,
+		Description: input.Description,
+		Status:      input.Status,
+		DueDate:     input.DueDate,
+		Priority:    input.Priority,
+		Project:     input.Project,
+		Labels:      input.Labels,
+		Recurrence:  input.Recurrence,
+		ParentID:    input.ParentID,
+		UserID:      userID,
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"to_do_api/metrics"
+	"to_do_api/models"
+	"to_do_api/repository"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tracer times bcrypt, which is deliberately slow, in Register and
+// Authenticate.
+var tracer = otel.Tracer("to_do_api/service")
+
+var (
+	ErrEmailTaken = repository.ErrEmailTaken
+	// ErrInvalidCredentials does not say whether the email or the password
+	// was wrong.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type UserService struct {
+	repo repository.UserRepository
+}
+
+func NewUserService(repo repository.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// Register creates an account with a bcrypt hash of password.
+func (s *UserService) Register(ctx context.Context, email, password string) (models.User, error) {
+	if _, err := s.repo.GetByEmail(ctx, email); err == nil {
+		return models.User{}, ErrEmailTaken
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, err
+	}
+
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	span.End()
+	if err != nil {
+		return models.User{}, err
+	}
+	return s.repo.Create(ctx, models.User{Email: email, Password: string(hashedPassword)})
+}
+
+// Authenticate returns the account with email if password is its password.
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (models.User, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		metrics.Login(false)
+		return models.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	span.End()
+	if err != nil {
+		metrics.Login(false)
+		return models.User{}, ErrInvalidCredentials
+	}
+	metrics.Login(true)
+	return user, nil
+}
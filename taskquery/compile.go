@@ -0,0 +1,124 @@
+package taskquery
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Apply narrows a task query to the tasks matching node. Relative dates are
+// resolved against now, with calendar days in UTC.
+func Apply(query *gorm.DB, node Node, now time.Time) *gorm.DB {
+	sql, args := Compile(node, now)
+	return query.Where(sql, args...)
+}
+
+// Compile turns node into a parenthesized SQL condition with ? placeholders.
+func Compile(node Node, now time.Time) (string, []interface{}) {
+	c := &compiler{now: now.UTC()}
+	return "(" + node.compile(c) + ")", c.args
+}
+
+type compiler struct {
+	now  time.Time
+	args []interface{}
+}
+
+func (c *compiler) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	return "?"
+}
+
+func (n and) compile(c *compiler) string {
+	return "(" + n.left.compile(c) + " AND " + n.right.compile(c) + ")"
+}
+
+func (n or) compile(c *compiler) string {
+	return "(" + n.left.compile(c) + " OR " + n.right.compile(c) + ")"
+}
+
+func (n not) compile(c *compiler) string {
+	return "NOT " + n.operand.compile(c)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func contains(c *compiler, column, value string) string {
+	return "LOWER(COALESCE(" + column + ", '')) LIKE " + c.arg("%"+likeEscaper.Replace(value)+"%") + ` ESCAPE '\'`
+}
+
+func (n term) compile(c *compiler) string {
+	switch {
+	case n.field == "":
+		value := n.value.(string)
+		return "(" + contains(c, "title", value) + " OR " + contains(c, "description", value) + ")"
+	case n.field == "status":
+		return "status = " + c.arg(n.value)
+	case n.field == "priority":
+		return "priority " + n.op + " " + c.arg(n.value)
+	case n.field == "project":
+		if n.value == "none" {
+			return "COALESCE(project, '') = ''"
+		}
+		return "LOWER(COALESCE(project, '')) = " + c.arg(n.value)
+	case n.field == "label":
+		if n.value == "none" {
+			return "COALESCE(labels, '[]') IN ('[]', 'null')"
+		}
+		// Labels are stored as a JSON array of lower-case strings.
+		return "COALESCE(labels, '') LIKE " + c.arg(`%"`+likeEscaper.Replace(n.value.(string))+`"%`) + ` ESCAPE '\'`
+	case textColumns[n.field] != "":
+		return contains(c, textColumns[n.field], n.value.(string))
+	}
+	return compileDate(c, dateColumns[n.field], n.op, n.value.(dateValue))
+}
+
+// compileDate guards every comparison with IS NOT NULL so that NOT around a
+// date term also matches tasks without that date.
+func compileDate(c *compiler, column, op string, value dateValue) string {
+	switch value.kind {
+	case dateNone:
+		return column + " IS NULL"
+	case dateAny:
+		return column + " IS NOT NULL"
+	case dateOverdue:
+		return "(" + column + " IS NOT NULL AND " + column + " < " + c.arg(c.now) + " AND status = " + c.arg(false) + ")"
+	case dateRelativeDay, dateDay:
+		start := value.time
+		if value.kind == dateRelativeDay {
+			today := time.Date(c.now.Year(), c.now.Month(), c.now.Day(), 0, 0, 0, 0, time.UTC)
+			start = today.AddDate(0, 0, value.days)
+		}
+		return compileRange(c, column, op, start, start.AddDate(0, 0, 1))
+	case dateOffset:
+		at := c.now.Add(value.offset)
+		if op == "=" {
+			if value.offset < 0 {
+				return compileRange(c, column, op, at, c.now)
+			}
+			return compileRange(c, column, op, c.now, at)
+		}
+		return "(" + column + " IS NOT NULL AND " + column + " " + op + " " + c.arg(at) + ")"
+	default:
+		return "(" + column + " IS NOT NULL AND " + column + " " + op + " " + c.arg(value.time) + ")"
+	}
+}
+
+// compileRange compares column with the half-open interval [start, end).
+func compileRange(c *compiler, column, op string, start, end time.Time) string {
+	var condition string
+	switch op {
+	case "<":
+		condition = column + " < " + c.arg(start)
+	case "<=":
+		condition = column + " < " + c.arg(end)
+	case ">":
+		condition = column + " >= " + c.arg(end)
+	case ">=":
+		condition = column + " >= " + c.arg(start)
+	default:
+		condition = column + " >= " + c.arg(start) + " AND " + column + " < " + c.arg(end)
+	}
+	return "(" + column + " IS NOT NULL AND " + condition + ")"
+}
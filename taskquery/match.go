@@ -0,0 +1,154 @@
+package taskquery
+
+import (
+	"strings"
+	"time"
+	"to_do_api/models"
+)
+
+// Match reports whether task matches node, with the same meaning as the SQL
+// Compile produces, for stores that filter tasks in memory.
+func Match(node Node, task models.Task, now time.Time) bool {
+	return node.match(&matcher{now: now.UTC()}, task)
+}
+
+type matcher struct {
+	now time.Time
+}
+
+func (n and) match(m *matcher, task models.Task) bool {
+	return n.left.match(m, task) && n.right.match(m, task)
+}
+
+func (n or) match(m *matcher, task models.Task) bool {
+	return n.left.match(m, task) || n.right.match(m, task)
+}
+
+func (n not) match(m *matcher, task models.Task) bool {
+	return !n.operand.match(m, task)
+}
+
+func (n term) match(m *matcher, task models.Task) bool {
+	switch {
+	case n.field == "":
+		value := n.value.(string)
+		return containsFold(task.Title, value) || containsFold(task.Description, value)
+	case n.field == "status":
+		return task.Status == n.value.(bool)
+	case n.field == "priority":
+		return compareInts(task.Priority, n.op, n.value.(int))
+	case n.field == "project":
+		if n.value == "none" {
+			return task.Project == ""
+		}
+		return strings.ToLower(task.Project) == n.value
+	case n.field == "label":
+		if n.value == "none" {
+			return len(task.Labels) == 0
+		}
+		for _, label := range task.Labels {
+			if label == n.value {
+				return true
+			}
+		}
+		return false
+	case n.field == "title":
+		return containsFold(task.Title, n.value.(string))
+	case n.field == "description":
+		return containsFold(task.Description, n.value.(string))
+	}
+
+	var column *time.Time
+	switch n.field {
+	case "due":
+		column = task.DueDate
+	case "created":
+		column = &task.CreatedAt
+	case "updated":
+		column = &task.UpdatedAt
+	}
+	return matchDate(m, task, column, n.op, n.value.(dateValue))
+}
+
+func containsFold(text, value string) bool {
+	return strings.Contains(strings.ToLower(text), value)
+}
+
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return a == b
+}
+
+// matchDate follows compileDate: a missing date only matches none.
+func matchDate(m *matcher, task models.Task, column *time.Time, op string, value dateValue) bool {
+	switch value.kind {
+	case dateNone:
+		return column == nil
+	case dateAny:
+		return column != nil
+	}
+	if column == nil {
+		return false
+	}
+	at := column.UTC()
+
+	switch value.kind {
+	case dateOverdue:
+		return at.Before(m.now) && !task.Status
+	case dateRelativeDay, dateDay:
+		start := value.time
+		if value.kind == dateRelativeDay {
+			today := time.Date(m.now.Year(), m.now.Month(), m.now.Day(), 0, 0, 0, 0, time.UTC)
+			start = today.AddDate(0, 0, value.days)
+		}
+		return inRange(at, op, start, start.AddDate(0, 0, 1))
+	case dateOffset:
+		offset := m.now.Add(value.offset)
+		if op == "=" {
+			if value.offset < 0 {
+				return inRange(at, op, offset, m.now)
+			}
+			return inRange(at, op, m.now, offset)
+		}
+		return compareTimes(at, op, offset)
+	}
+	return compareTimes(at, op, value.time)
+}
+
+// inRange follows compileRange.
+func inRange(at time.Time, op string, start, end time.Time) bool {
+	switch op {
+	case "<":
+		return at.Before(start)
+	case "<=":
+		return at.Before(end)
+	case ">":
+		return !at.Before(end)
+	case ">=":
+		return !at.Before(start)
+	}
+	return !at.Before(start) && at.Before(end)
+}
+
+func compareTimes(at time.Time, op string, value time.Time) bool {
+	switch op {
+	case "<":
+		return at.Before(value)
+	case "<=":
+		return !at.After(value)
+	case ">":
+		return at.After(value)
+	case ">=":
+		return !at.Before(value)
+	}
+	return at.Equal(value)
+}
@@ -0,0 +1,333 @@
+// Package taskquery implements the task filter language, e.g.
+//
+//	due:<7d AND title:report AND NOT status:done
+//
+// A query is a sequence of terms combined with AND, OR, NOT and parentheses.
+// Adjacent terms are implicitly ANDed, NOT binds tightest and OR loosest. A
+// term is either field:value, with an optional comparison (field:<value,
+// field:<=value, field:>value, field:>=value), or a bare word or "quoted
+// phrase" matched against the title and description. Fields:
+//
+//	status       done|completed|true or open|todo|false
+//	priority     none, low, medium, high or 0-3; supports comparisons
+//	label        a label, or none for tasks without labels
+//	project      a project name, case-insensitive, or none
+//	title        substring, case-insensitive
+//	description  substring, case-insensitive
+//	due          none, any, overdue, today, tomorrow, yesterday,
+//	created      a date (2006-01-02), an RFC 3339 time,
+//	updated      or an offset from now such as 7d, -2w or 12h
+//
+// A day compared with = matches the whole day; a positive offset compared
+// with = matches from now until the offset, a negative one from the offset
+// until now. Queries compile to parameterized SQL: values are never
+// interpolated into the statement. Match evaluates a query against a task
+// in memory instead.
+package taskquery
+
+import (
+	"fmt"
+	"strings"
+	"to_do_api/models"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds the size of a query, and with it the nesting depth.
+const MaxLength = 1024
+
+// SyntaxError reports where a query stopped making sense.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query position %d: %s", e.Pos, e.Msg)
+}
+
+// Node is a parsed query.
+type Node interface {
+	compile(c *compiler) string
+	match(m *matcher, task models.Task) bool
+}
+
+type and struct{ left, right Node }
+
+type or struct{ left, right Node }
+
+type not struct{ operand Node }
+
+// term is a single comparison; value has already been validated for field.
+type term struct {
+	field string
+	op    string
+	value interface{}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenTerm
+)
+
+type token struct {
+	kind  tokenKind
+	pos   int
+	field string
+	op    string
+	value string
+}
+
+// And combines two queries into one that matches what both match.
+func And(left, right Node) Node {
+	return and{left, right}
+}
+
+// Words returns the bare words and phrases every task matching node
+// contains: those joined to the rest of the query by AND alone, outside OR
+// and NOT.
+func Words(node Node) []string {
+	switch n := node.(type) {
+	case and:
+		return append(Words(n.left), Words(n.right)...)
+	case term:
+		if n.field == "" {
+			return []string{n.value.(string)}
+		}
+	}
+	return nil
+}
+
+// Parse parses and validates a query.
+func Parse(query string) (Node, error) {
+	if len(query) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("query longer than %d bytes", MaxLength)}
+	}
+
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "empty query"}
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: next.pos, Msg: "unexpected )"}
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenNot, tokenLParen, tokenTerm:
+			// Adjacent terms are implicitly ANDed.
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+}
+
+func (p *parser) parseNot() (Node, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: "missing )"}
+		}
+		return node, nil
+	case tokenTerm:
+		return newTerm(t)
+	case tokenEOF:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected end of query"}
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a term"}
+	}
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for {
+		for i < len(query) {
+			r, size := utf8.DecodeRuneInString(query[i:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			i += size
+		}
+		if i == len(query) {
+			return append(tokens, token{kind: tokenEOF, pos: i}), nil
+		}
+
+		start := i
+		switch query[i] {
+		case '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: start})
+			i++
+			continue
+		case ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: start})
+			i++
+			continue
+		case '"':
+			value, end, err := lexQuoted(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenTerm, pos: start, value: value})
+			i = end
+			continue
+		}
+
+		for i < len(query) && !isDelimiter(query[i:]) && query[i] != ':' {
+			i++
+		}
+		word := query[start:i]
+
+		if i == len(query) || query[i] != ':' {
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd, pos: start})
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr, pos: start})
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenTerm, pos: start, value: word})
+			}
+			continue
+		}
+
+		// field:[op]value
+		i++
+		op := "="
+		for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+			if strings.HasPrefix(query[i:], candidate) {
+				op = candidate
+				i += len(candidate)
+				break
+			}
+		}
+
+		var value string
+		if i < len(query) && query[i] == '"' {
+			var err error
+			if value, i, err = lexQuoted(query, i); err != nil {
+				return nil, err
+			}
+		} else {
+			valueStart := i
+			for i < len(query) && !isDelimiter(query[i:]) {
+				i++
+			}
+			value = query[valueStart:i]
+		}
+		if value == "" {
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("missing value for %s", word)}
+		}
+		tokens = append(tokens, token{kind: tokenTerm, pos: start, field: strings.ToLower(word), op: op, value: value})
+	}
+}
+
+// lexQuoted reads a double-quoted string starting at query[start], where \"
+// and \\ are escapes. It returns the unquoted value and the index after the
+// closing quote.
+func lexQuoted(query string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if i+1 < len(query) {
+				i++
+				b.WriteByte(query[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(query[i])
+		}
+	}
+	return "", 0, &SyntaxError{Pos: start, Msg: "unterminated quote"}
+}
+
+// isDelimiter reports whether s starts with a character that ends a word.
+// It decodes the character, since bytes of multi-byte characters such as
+// 0xA0 in "à" would otherwise read as spaces.
+func isDelimiter(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '(' || r == ')' || unicode.IsSpace(r)
+}
@@ -0,0 +1,145 @@
+package taskquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+)
+
+type dateKind int
+
+const (
+	dateNone dateKind = iota
+	dateAny
+	dateOverdue
+	// dateRelativeDay is a calendar day counted from today.
+	dateRelativeDay
+	dateDay
+	dateInstant
+	dateOffset
+)
+
+type dateValue struct {
+	kind   dateKind
+	days   int
+	time   time.Time
+	offset time.Duration
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-]?\d{1,4})([hdw])$`)
+
+var offsetUnits = map[string]time.Duration{
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+var relativeDays = map[string]int{
+	"yesterday": -1,
+	"today":     0,
+	"tomorrow":  1,
+}
+
+// dateColumns and textColumns whitelist the fields a query may reference;
+// only these column names ever reach the SQL.
+var dateColumns = map[string]string{
+	"due":     "due_date",
+	"created": "created_at",
+	"updated": "updated_at",
+}
+
+var textColumns = map[string]string{
+	"title":       "title",
+	"description": "description",
+}
+
+func newTerm(t token) (Node, error) {
+	fail := func(format string, args ...interface{}) (Node, error) {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	switch {
+	case t.field == "":
+		return term{value: strings.ToLower(t.value)}, nil
+
+	case t.field == "status":
+		if t.op != "=" {
+			return fail("status does not support %s", t.op)
+		}
+		switch strings.ToLower(t.value) {
+		case "done", "completed", "complete", "true":
+			return term{field: "status", op: t.op, value: true}, nil
+		case "open", "todo", "pending", "incomplete", "false":
+			return term{field: "status", op: t.op, value: false}, nil
+		}
+		return fail("invalid status %q", t.value)
+
+	case t.field == "priority":
+		priority, ok := models.PriorityNames[strings.ToLower(t.value)]
+		if !ok {
+			n, err := strconv.Atoi(t.value)
+			if err != nil || n < models.PriorityNone || n > models.PriorityHigh {
+				return fail("invalid priority %q", t.value)
+			}
+			priority = n
+		}
+		return term{field: "priority", op: t.op, value: priority}, nil
+
+	case t.field == "label" || t.field == "project":
+		if t.op != "=" {
+			return fail("%s does not support %s", t.field, t.op)
+		}
+		return term{field: t.field, op: t.op, value: strings.ToLower(strings.TrimPrefix(t.value, "#"))}, nil
+
+	case textColumns[t.field] != "":
+		if t.op != "=" {
+			return fail("%s does not support %s", t.field, t.op)
+		}
+		return term{field: t.field, op: t.op, value: strings.ToLower(t.value)}, nil
+
+	case dateColumns[t.field] != "":
+		value, err := parseDate(t.field, t.value)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if (value.kind == dateNone || value.kind == dateAny || value.kind == dateOverdue) && t.op != "=" {
+			return fail("%s:%s does not support %s", t.field, t.value, t.op)
+		}
+		return term{field: t.field, op: t.op, value: value}, nil
+	}
+
+	return fail("unknown field %q", t.field)
+}
+
+func parseDate(field, value string) (dateValue, error) {
+	lower := strings.ToLower(value)
+	switch lower {
+	case "none":
+		return dateValue{kind: dateNone}, nil
+	case "any":
+		return dateValue{kind: dateAny}, nil
+	case "overdue":
+		if field != "due" {
+			return dateValue{}, fmt.Errorf("only due can be overdue")
+		}
+		return dateValue{kind: dateOverdue}, nil
+	}
+
+	if days, ok := relativeDays[lower]; ok {
+		return dateValue{kind: dateRelativeDay, days: days}, nil
+	}
+	if match := offsetPattern.FindStringSubmatch(lower); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return dateValue{kind: dateOffset, offset: time.Duration(n) * offsetUnits[match[2]]}, nil
+	}
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return dateValue{kind: dateDay, time: day}, nil
+	}
+	if instant, err := time.Parse(time.RFC3339, value); err == nil {
+		return dateValue{kind: dateInstant, time: instant.UTC()}, nil
+	}
+	return dateValue{}, fmt.Errorf("invalid %s date %q", field, value)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	"to_do_api/models"
+	"to_do_api/transfer"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const todoistExport = "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\r\n" +
+	"meta,view_style=list,,,,,,,,\r\n" +
+	"task,Pay rent @finance,Before the 1st,1,1,Ada (1),,every month,en,Europe/Berlin\r\n" +
+	"note,Transfer from the joint account,,,,Ada (1),,,,\r\n" +
+	",,,,,,,,,\r\n" +
+	"section,Weekend Chores,,,,,,,,\r\n" +
+	"task,Clean the flat,,4,1,Ada (1),,2026-10-24,en,\r\n" +
+	"task,Vacuum @home,,3,2,Ada (1),,,en,\r\n" +
+	"task,Under the sofa,,4,3,Ada (1),,,en,\r\n" +
+	"task,Laundry,,2,1,Ada (1),,,en,\r\n"
+
+const trelloExport = `{
+  "name": "Launch",
+  "lists": [
+    {"id": "l1", "name": "Doing", "closed": false},
+    {"id": "l2", "name": "Old", "closed": true}
+  ],
+  "cards": [
+    {"id": "c1", "name": "Write post", "desc": "Draft first", "idList": "l1", "closed": false,
+     "due": "2026-11-02T09:00:00.000Z", "dueComplete": false,
+     "labels": [{"name": "Marketing team", "color": "green"}, {"name": "", "color": "red"}]},
+    {"id": "c2", "name": "Archived", "idList": "l1", "closed": true},
+    {"id": "c3", "name": "In old list", "idList": "l2", "closed": false},
+    {"id": "c4", "name": "Ship it", "idList": "l1", "closed": false, "due": "2026-11-01T09:00:00.000Z", "dueComplete": true}
+  ],
+  "checklists": [
+    {"id": "k1", "idCard": "c1", "name": "Steps", "pos": 1, "checkItems": [
+      {"name": "Proofread", "state": "incomplete", "pos": 2},
+      {"name": "Outline", "state": "complete", "pos": 1}
+    ]}
+  ],
+  "actions": [
+    {"type": "commentCard", "date": "2026-10-02T10:00:00.000Z", "data": {"text": "Looks good", "card": {"id": "c1"}}, "memberCreator": {"fullName": "Grace"}},
+    {"type": "commentCard", "date": "2026-10-01T10:00:00.000Z", "data": {"text": "First draft\nis up", "card": {"id": "c1"}}, "memberCreator": {"fullName": "Ada"}},
+    {"type": "updateCard", "date": "2026-10-01T09:00:00.000Z", "data": {"card": {"id": "c1"}}}
+  ]
+}`
+
+const microsoftTodoExport = "List,Title,Notes,Due Date,Status,Importance,Categories,Steps\n" +
+	"Groceries,Buy milk,Oat,10/25/2026,NotStarted,High,Errands; Shop run,\"[x] Check fridge\n[ ] Write list\"\n" +
+	",,,,,,,\n" +
+	"Work,File report,,2026-10-30,Completed,Normal,,\n"
+
+func TestTodoistImporter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	rows, problems, report := transfer.AppImporters["todoist"].Read(transfer.AppSource{
+		Data: []byte(todoistExport), Filename: "Home.csv", Location: time.UTC, Now: now,
+	})
+	require.Empty(t, problems)
+	require.Len(t, rows, 5)
+
+	rent := rows[0]
+	assert.Equal(t, "Pay rent", rent.Title)
+	assert.Equal(t, "Home", rent.Project)
+	assert.Equal(t, []string{"finance"}, rent.Labels)
+	assert.Equal(t, models.PriorityHigh, rent.Priority)
+	assert.Equal(t, "FREQ=MONTHLY", rent.Recurrence)
+	require.NotNil(t, rent.DueDate)
+	assert.Contains(t, rent.Description, "Before the 1st\n\nComments:\n- Ada (1): Transfer from the joint account")
+
+	clean, vacuum, sofa, laundry := rows[1], rows[2], rows[3], rows[4]
+	assert.Equal(t, []string{"Weekend-Chores"}, clean.Labels)
+	assert.Equal(t, models.PriorityNone, clean.Priority)
+	require.NotNil(t, clean.DueDate)
+	assert.Equal(t, time.Date(2026, 10, 24, 23, 59, 0, 0, time.UTC), *clean.DueDate)
+	assert.Equal(t, clean.Line, vacuum.ParentLine)
+	assert.Equal(t, []string{"home", "Weekend-Chores"}, vacuum.Labels)
+	assert.Equal(t, vacuum.Line, sofa.ParentLine)
+	assert.Zero(t, laundry.ParentLine)
+	assert.Equal(t, models.PriorityMedium, laundry.Priority)
+
+	assert.Equal(t, 3, report.Tasks)
+	assert.Equal(t, 2, report.Subtasks)
+	assert.Equal(t, 1, report.Comments)
+	assert.Equal(t, []string{"Home"}, report.Projects)
+	assert.Equal(t, []string{"Weekend-Chores", "finance", "home"}, report.Labels)
+
+	_, problems, _ = transfer.AppImporters["todoist"].Read(transfer.AppSource{Data: []byte("Title\nx\n"), Location: time.UTC})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error, "not a Todoist export")
+
+	_, problems, _ = transfer.AppImporters["todoist"].Read(transfer.AppSource{
+		Data: []byte("TYPE,CONTENT,DATE\ntask,Oops,whenever works\n"), Location: time.UTC, Now: now,
+	})
+	require.Len(t, problems, 1)
+	assert.Equal(t, transfer.Problem{Line: 2, Field: "due_date", Error: `cannot read date "whenever works"`}, problems[0])
+}
+
+func TestTrelloImporter(t *testing.T) {
+	rows, problems, report := transfer.AppImporters["trello"].Read(transfer.AppSource{Data: []byte(trelloExport), Location: time.UTC})
+	require.Empty(t, problems)
+	require.Len(t, rows, 4)
+
+	post := rows[0]
+	assert.Equal(t, "Write post", post.Title)
+	assert.Equal(t, "Doing", post.Project)
+	assert.Equal(t, []string{"Marketing-team", "red"}, post.Labels)
+	require.NotNil(t, post.DueDate)
+	assert.Equal(t, time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC), *post.DueDate)
+	assert.Equal(t, "Draft first\n\nComments:\n- Ada, 2026-10-01: First draft\n  is up\n- Grace, 2026-10-02: Looks good", post.Description)
+
+	assert.Equal(t, "Outline", rows[1].Title)
+	assert.True(t, rows[1].Status)
+	assert.Equal(t, post.Line, rows[1].ParentLine)
+	assert.Equal(t, "Proofread", rows[2].Title)
+	assert.False(t, rows[2].Status)
+	assert.Equal(t, "Ship it", rows[3].Title)
+	assert.True(t, rows[3].Status)
+
+	assert.Equal(t, 2, report.Tasks)
+	assert.Equal(t, 2, report.Subtasks)
+	assert.Equal(t, 2, report.Comments)
+	assert.Equal(t, map[string]int{"archived card": 1, "card in an archived list": 1}, report.Skipped)
+	assert.Equal(t, "project", report.Mapping["list"])
+}
+
+func TestMicrosoftTodoImporter(t *testing.T) {
+	rows, problems, report := transfer.AppImporters["microsoft-todo"].Read(transfer.AppSource{
+		Data: []byte(microsoftTodoExport), Location: time.UTC,
+	})
+	require.Empty(t, problems)
+	require.Len(t, rows, 4)
+
+	milk := rows[0]
+	assert.Equal(t, "Buy milk", milk.Title)
+	assert.Equal(t, "Groceries", milk.Project)
+	assert.Equal(t, "Oat", milk.Description)
+	assert.Equal(t, models.PriorityHigh, milk.Priority)
+	assert.Equal(t, []string{"Errands", "Shop-run"}, milk.Labels)
+	require.NotNil(t, milk.DueDate)
+	assert.Equal(t, time.Date(2026, 10, 25, 23, 59, 0, 0, time.UTC), *milk.DueDate)
+
+	assert.Equal(t, "Check fridge", rows[1].Title)
+	assert.True(t, rows[1].Status)
+	assert.Equal(t, milk.Line, rows[1].ParentLine)
+	assert.Equal(t, "Write list", rows[2].Title)
+	assert.False(t, rows[2].Status)
+
+	filed := rows[3]
+	assert.Equal(t, "File report", filed.Title)
+	assert.True(t, filed.Status)
+	assert.Equal(t, models.PriorityNone, filed.Priority)
+	assert.Greater(t, rows[1].Line, filed.Line, "steps are numbered after the last line")
+	assert.Equal(t, []string{"Groceries", "Work"}, report.Projects)
+
+	_, problems, _ = transfer.AppImporters["microsoft-todo"].Read(transfer.AppSource{
+		Data: []byte("Subject,Due Date,Priority\nCall,13/45/2026,Urgent\n"), Location: time.UTC,
+	})
+	assert.Len(t, problems, 2)
+}
+
+func TestImportTasks_App(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+
+	w := uploadFile(t, router, "/tasks/import", "board.json", trelloExport, map[string]string{"app": "asana"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "microsoft-todo, todoist, trello")
+
+	w = uploadFile(t, router, "/tasks/import", "board.json", trelloExport, map[string]string{"app": "trello", "dry_run": "true"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		importResponse
+		App       string             `json:"app"`
+		AppReport transfer.AppReport `json:"app_report"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "trello", response.App)
+	assert.Equal(t, 2, response.AppReport.Subtasks)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+
+	w = uploadFile(t, router, "/tasks/import", "board.json", trelloExport, map[string]string{"app": "trello"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, int64(4), countTasks(db, userID))
+
+	var outline models.Task
+	require.NoError(t, db.Where("user_id = ? AND title = ?", userID, "Outline").First(&outline).Error)
+	require.NotNil(t, outline.ParentID)
+	var post models.Task
+	require.NoError(t, db.First(&post, "id = ?", *outline.ParentID).Error)
+	assert.Equal(t, "Write post", post.Title)
+	assert.Equal(t, "Doing", post.Project)
+
+	// Todoist projects are named after the file.
+	w = uploadFile(t, router, "/tasks/import", "Home.csv", todoistExport, map[string]string{"app": "todoist"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var count int64
+	db.Model(&models.Task{}).Where("user_id = ? AND project = ?", userID, "Home").Count(&count)
+	assert.Equal(t, int64(5), count)
+}
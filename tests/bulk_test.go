@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type bulkResponse struct {
+	Results []struct {
+		Status  string      `json:"status"`
+		Error   string      `json:"error"`
+		TaskIDs []uuid.UUID `json:"task_ids"`
+		Changes []struct {
+			TaskID uuid.UUID    `json:"task_id"`
+			Change string       `json:"change"`
+			Task   *models.Task `json:"task"`
+			Fields map[string]struct {
+				From json.RawMessage `json:"from"`
+				To   json.RawMessage `json:"to"`
+			} `json:"fields"`
+		} `json:"changes"`
+	} `json:"results"`
+}
+
+func runBulk(t *testing.T, db *gorm.DB, userID uuid.UUID, body map[string]interface{}) (int, bulkResponse) {
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks/bulk", controllers.BulkTasks(taskService(db), events.NewMemoryBroker()))
+
+	bodyBytes, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/tasks/bulk", bytes.NewBuffer(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp bulkResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return w.Code, resp
+}
+
+func seedTasks(t *testing.T, db *gorm.DB, userID uuid.UUID, done ...bool) []models.Task {
+	var tasks []models.Task
+	for i, status := range done {
+		task := models.Task{Title: "Task " + string(rune('A'+i)), Status: status, UserID: userID}
+		require.NoError(t, db.Create(&task).Error)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func countTasks(db *gorm.DB, userID uuid.UUID) int64 {
+	var count int64
+	db.Model(&models.Task{}).Where("user_id = ?", userID).Count(&count)
+	return count
+}
+
+func TestBulkTasks_MixedOperations(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	tasks := seedTasks(t, db, userID, false, false, true)
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{
+			{"op": "create", "task": gin.H{"title": "New"}},
+			{"op": "update", "filter": gin.H{"status": "false"}, "set": gin.H{"status": true, "description": "done in bulk"}},
+			{"op": "delete", "ids": []uuid.UUID{tasks[2].ID}},
+		},
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "ok", resp.Results[0].Status)
+	assert.ElementsMatch(t, []uuid.UUID{tasks[0].ID, tasks[1].ID, resp.Results[0].TaskIDs[0]}, resp.Results[1].TaskIDs)
+	assert.Equal(t, []uuid.UUID{tasks[2].ID}, resp.Results[2].TaskIDs)
+
+	var open int64
+	db.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, false).Count(&open)
+	assert.Equal(t, int64(0), open)
+	assert.Equal(t, int64(3), countTasks(db, userID))
+}
+
+func TestBulkTasks_DryRun(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	tasks := seedTasks(t, db, userID, false, true)
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"dry_run":    true,
+		"operations": []gin.H{{"op": "delete", "filter": gin.H{"status": "true"}}},
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uuid.UUID{tasks[1].ID}, resp.Results[0].TaskIDs)
+	require.Len(t, resp.Results[0].Changes, 1)
+	assert.Equal(t, "delete", resp.Results[0].Changes[0].Change)
+	require.NotNil(t, resp.Results[0].Changes[0].Task)
+	assert.Equal(t, tasks[1].Title, resp.Results[0].Changes[0].Task.Title)
+	assert.Equal(t, int64(2), countTasks(db, userID))
+
+	code, resp = runBulk(t, db, userID, map[string]interface{}{
+		"dry_run": true,
+		"operations": []gin.H{
+			{"op": "update", "ids": []uuid.UUID{tasks[0].ID, tasks[1].ID}, "set": gin.H{"status": true, "title": "Renamed"}},
+			{"op": "create", "task": gin.H{"title": "New"}},
+		},
+	})
+	require.Equal(t, http.StatusOK, code)
+	changes := resp.Results[0].Changes
+	require.Len(t, changes, 2)
+	assert.Equal(t, tasks[0].ID, changes[0].TaskID)
+	assert.Equal(t, "update", changes[0].Change)
+	assert.JSONEq(t, `"Task A"`, string(changes[0].Fields["title"].From))
+	assert.JSONEq(t, `"Renamed"`, string(changes[0].Fields["title"].To))
+	assert.JSONEq(t, `false`, string(changes[0].Fields["status"].From))
+	assert.JSONEq(t, `true`, string(changes[0].Fields["status"].To))
+	assert.NotContains(t, changes[0].Fields, "version")
+	// The second task is already done, so only its title would change.
+	assert.Len(t, changes[1].Fields, 1)
+	require.Len(t, resp.Results[1].Changes, 1)
+	assert.Equal(t, "create", resp.Results[1].Changes[0].Change)
+	assert.Equal(t, "New", resp.Results[1].Changes[0].Task.Title)
+	assert.Equal(t, int64(2), countTasks(db, userID))
+
+	var logged int64
+	db.Model(&models.TaskEvent{}).Count(&logged)
+	assert.Equal(t, int64(0), logged)
+}
+
+func TestBulkTasks_AtomicRollsBack(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	tasks := seedTasks(t, db, userID, false)
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{
+			{"op": "delete", "ids": []uuid.UUID{tasks[0].ID}},
+			{"op": "delete", "ids": []uuid.UUID{uuid.New()}},
+		},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "rolled_back", resp.Results[0].Status)
+	assert.Equal(t, "error", resp.Results[1].Status)
+	assert.Equal(t, int64(1), countTasks(db, userID))
+}
+
+func TestBulkTasks_PartialReportsPerItem(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	tasks := seedTasks(t, db, userID, false)
+	otherUsersTask := seedTasks(t, db, uuid.New(), false)[0]
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"atomic": false,
+		"operations": []gin.H{
+			{"op": "delete", "ids": []uuid.UUID{otherUsersTask.ID}},
+			{"op": "update", "ids": []uuid.UUID{tasks[0].ID}, "set": gin.H{"due_date": nil, "title": "Renamed"}},
+			{"op": "update", "ids": []uuid.UUID{tasks[0].ID}, "set": gin.H{"user_id": uuid.New()}},
+		},
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "error", resp.Results[0].Status)
+	assert.Equal(t, "ok", resp.Results[1].Status)
+	assert.Equal(t, "error", resp.Results[2].Status)
+
+	var stored models.Task
+	require.NoError(t, db.First(&stored, "id = ?", tasks[0].ID).Error)
+	assert.Equal(t, "Renamed", stored.Title)
+	assert.Equal(t, userID, stored.UserID)
+}
+
+func TestBulkTasks_EditLabelsAndProject(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Labelled", UserID: userID, Labels: []string{"old", "keep"}}).Error)
+	seedTasks(t, db, userID, false)
+
+	code, _ := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{
+			{"op": "update", "all": true, "set": gin.H{"add_labels": []string{"#New"}, "remove_labels": []string{"old"}, "project": "Work"}},
+		},
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	var stored []models.Task
+	require.NoError(t, db.Order("created_at, id").Find(&stored).Error)
+	assert.Equal(t, []string{"keep", "new"}, stored[0].Labels)
+	assert.Equal(t, []string{"new"}, stored[1].Labels)
+	assert.Equal(t, "Work", stored[1].Project)
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{
+			{"op": "update", "all": true, "set": gin.H{"labels": []string{"a"}, "add_labels": []string{"b"}}},
+		},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "error", resp.Results[0].Status)
+}
+
+func TestBulkTasks_FilterMustSelect(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	seedTasks(t, db, userID, false, true)
+
+	for _, op := range []gin.H{
+		{"op": "delete", "filter": gin.H{}},
+		{"op": "delete", "filter": gin.H{"stauts": "true"}},
+		{"op": "delete", "filter": gin.H{"status": ""}},
+		{"op": "delete", "filter": gin.H{"status": "true"}, "all": true},
+		{"op": "delete"},
+	} {
+		code, resp := runBulk(t, db, userID, map[string]interface{}{"operations": []gin.H{op}})
+		assert.Equal(t, http.StatusUnprocessableEntity, code, op)
+		assert.Equal(t, "error", resp.Results[0].Status, op)
+	}
+	assert.Equal(t, int64(2), countTasks(db, userID))
+
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{{"op": "delete", "all": true}},
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, resp.Results[0].TaskIDs, 2)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+}
+
+func TestBulkTasks_DeleteSubtasks(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	tasks := seedTasks(t, db, userID, true, false)
+	parent := tasks[0]
+	child := models.Task{Title: "Child", Status: true, ParentID: &parent.ID, UserID: userID}
+	require.NoError(t, db.Create(&child).Error)
+
+	// Deleting the parent alone leaves its subtask behind, so it fails.
+	code, resp := runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{{"op": "delete", "ids": []uuid.UUID{parent.ID}}},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "error", resp.Results[0].Status)
+	assert.Contains(t, resp.Results[0].Error, "subtasks")
+	assert.Equal(t, int64(3), countTasks(db, userID))
+
+	// Selected together, the subtask is deleted first.
+	code, resp = runBulk(t, db, userID, map[string]interface{}{
+		"operations": []gin.H{{"op": "delete", "filter": gin.H{"status": "true"}}},
+	})
+	require.Equal(t, http.StatusOK, code, resp.Results[0].Error)
+	assert.ElementsMatch(t, []uuid.UUID{parent.ID, child.ID}, resp.Results[0].TaskIDs)
+	assert.Equal(t, int64(1), countTasks(db, userID))
+}
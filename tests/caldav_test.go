@@ -0,0 +1,342 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/middleware"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const caldavEmail = "ada@example.com"
+
+func newCalDAVRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := gin.New()
+	authorized := router.Group("/")
+	authorized.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.String())
+		c.Next()
+	})
+	authorized.POST("/app-passwords", controllers.CreateAppPassword(db))
+	authorized.GET("/app-passwords", controllers.ListAppPasswords(db))
+	authorized.DELETE("/app-passwords/:id", controllers.DeleteAppPassword(db))
+
+	dav := router.Group("/caldav")
+	dav.Use(middleware.AppPasswordAuth(db, "CalDAV"))
+	handler := controllers.CalDAV(db, taskService(db), events.NewMemoryBroker())
+	for _, method := range controllers.CalDAVMethods {
+		dav.Handle(method, "/*path", handler)
+	}
+	return router
+}
+
+// setupCalDAV creates a user with an app password and returns a function
+// making authenticated CalDAV requests.
+func setupCalDAV(t *testing.T) (*gorm.DB, uuid.UUID, *gin.Engine, func(method, path, body string, headers ...string) *httptest.ResponseRecorder) {
+	db := setupTestTaskDB(t)
+	user := models.User{Email: caldavEmail, Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newCalDAVRouter(db, user.ID)
+	password := createAppPassword(t, router, "Phone")
+
+	dav := func(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth("Ada@Example.com", password)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	return db, user.ID, router, dav
+}
+
+func createAppPassword(t *testing.T, router *gin.Engine, name string) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/app-passwords", strings.NewReader(`{"name":"`+name+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		AppPassword models.AppPassword `json:"app_password"`
+		Password    string             `json:"password"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotEmpty(t, response.Password)
+	assert.Equal(t, name, response.AppPassword.Name)
+	assert.NotContains(t, w.Body.String(), "password_hash")
+	return response.Password
+}
+
+func vtodo(uid, summary string, extra ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//EN", "BEGIN:VTODO", "UID:" + uid, "SUMMARY:" + summary}
+	lines = append(lines, extra...)
+	lines = append(lines, "BEGIN:VALARM", "ACTION:DISPLAY", "TRIGGER:-PT15M", "END:VALARM", "END:VTODO", "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+const propfindBody = `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><d:resourcetype/><d:displayname/><d:getetag/><cs:getctag/></d:prop>
+</d:propfind>`
+
+func TestAppPasswordAuth(t *testing.T) {
+	db := setupTestTaskDB(t)
+	user := models.User{Email: caldavEmail, Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newCalDAVRouter(db, user.ID)
+	password := createAppPassword(t, router, "Phone")
+
+	request := func(configure func(*http.Request)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("OPTIONS", "/caldav/", nil)
+		configure(req)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(func(*http.Request) {})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `Basic realm="CalDAV"`)
+
+	w = request(func(req *http.Request) { req.SetBasicAuth(caldavEmail, password) })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("DAV"), "calendar-access")
+	assert.Contains(t, w.Header().Get("Allow"), "REPORT")
+
+	w = request(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+password) })
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request(func(req *http.Request) { req.SetBasicAuth("someone@example.com", password) })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = request(func(req *http.Request) { req.SetBasicAuth(caldavEmail, "wrong") })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var appPassword models.AppPassword
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&appPassword).Error)
+	assert.NotNil(t, appPassword.LastUsedAt)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/app-passwords", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Phone"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/app-passwords/"+appPassword.ID.String(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request(func(req *http.Request) { req.SetBasicAuth(caldavEmail, password) })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCalDAV_Discovery(t *testing.T) {
+	db, userID, _, dav := setupCalDAV(t)
+	require.NoError(t, db.Create(&models.Task{Title: "Write report", UserID: userID, Project: "Work"}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Not mine", UserID: uuid.New(), Project: "Other"}).Error)
+
+	w := dav("PROPFIND", "/caldav/", "", "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "/caldav/principal/")
+
+	w = dav("PROPFIND", "/caldav/principal/", "", "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "/caldav/calendars/")
+	assert.Contains(t, w.Body.String(), "mailto:"+caldavEmail)
+
+	work := "/caldav/calendars/" + base64.RawURLEncoding.EncodeToString([]byte("Work")) + "/"
+	w = dav("PROPFIND", "/caldav/calendars/", propfindBody, "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<d:href>/caldav/calendars/inbox/</d:href>")
+	assert.Contains(t, body, "<d:href>"+work+"</d:href>")
+	assert.Contains(t, body, "<d:displayname>Work</d:displayname>")
+	assert.Contains(t, body, "calendar")
+	assert.Contains(t, body, "getctag")
+	assert.NotContains(t, body, "Other")
+
+	w = dav("PROPFIND", work, propfindBody, "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "getetag")
+	assert.Contains(t, w.Body.String(), ".ics</d:href>")
+
+	w = dav("PROPFIND", "/caldav/calendars/"+base64.RawURLEncoding.EncodeToString([]byte("Other"))+"/", propfindBody, "Depth", "0")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = dav("PROPFIND", "/caldav/calendars/inbox/", propfindBody, "Depth", "0")
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+	w = dav("PROPPATCH", work, `<d:propertyupdate xmlns:d="DAV:"><d:set><d:prop><d:displayname>New</d:displayname></d:prop></d:set></d:propertyupdate>`)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "403")
+}
+
+func TestCalDAV_PutGetDelete(t *testing.T) {
+	db, userID, _, dav := setupCalDAV(t)
+	href := "/caldav/calendars/" + base64.RawURLEncoding.EncodeToString([]byte("Home")) + "/groceries.ics"
+
+	w := dav("PROPFIND", "/caldav/calendars/inbox/", "", "Depth", "0")
+	ctag := w.Body.String()
+
+	w = dav("PUT", href, vtodo("client-uid-1", "Buy milk", "DUE:20261102T153000Z", "PRIORITY:1", "CATEGORIES:errands"),
+		"If-None-Match", "*")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	var task models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).First(&task).Error)
+	assert.Equal(t, "Buy milk", task.Title)
+	assert.Equal(t, "Home", task.Project)
+	assert.Equal(t, models.PriorityHigh, task.Priority)
+	assert.Equal(t, []string{"errands"}, []string(task.Labels))
+	require.NotNil(t, task.DueDate)
+	assert.True(t, task.DueDate.Equal(time.Date(2026, 11, 2, 15, 30, 0, 0, time.UTC)))
+
+	w = dav("GET", href, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/calendar")
+	assert.Contains(t, w.Body.String(), "UID:client-uid-1")
+	assert.NotContains(t, w.Body.String(), "VALARM")
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = dav("GET", href, "", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = dav("PROPFIND", "/caldav/calendars/inbox/", "", "Depth", "0")
+	assert.NotEqual(t, ctag, w.Body.String(), "the ctag changes when a task does")
+
+	// Creating it again, or updating with a stale ETag, fails.
+	w = dav("PUT", href, vtodo("client-uid-1", "Buy milk"), "If-None-Match", "*")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = dav("PUT", href, vtodo("client-uid-1", "Buy oat milk", "STATUS:COMPLETED"), "If-Match", etag)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	w = dav("PUT", href, vtodo("client-uid-1", "Buy soy milk"), "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	require.NoError(t, db.First(&task, "id = ?", task.ID).Error)
+	assert.Equal(t, "Buy oat milk", task.Title)
+	assert.True(t, task.Status)
+
+	// UIDs cannot change or be reused.
+	w = dav("PUT", href, vtodo("client-uid-2", "Buy milk"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "no-uid-conflict")
+	w = dav("PUT", strings.Replace(href, "groceries", "other", 1), vtodo("client-uid-1", "Buy milk"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "no-uid-conflict")
+
+	// A subtask refers to its parent by UID.
+	subtaskUID := uuid.New().String()
+	w = dav("PUT", "/caldav/calendars/inbox/"+subtaskUID+".ics", vtodo(subtaskUID, "Check the fridge", "RELATED-TO:client-uid-1"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var subtask models.Task
+	require.NoError(t, db.First(&subtask, "id = ?", subtaskUID).Error)
+	require.NotNil(t, subtask.ParentID)
+	assert.Equal(t, task.ID, *subtask.ParentID)
+	w = dav("GET", "/caldav/calendars/inbox/"+subtaskUID+".ics", "")
+	assert.Contains(t, w.Body.String(), "RELATED-TO:client-uid-1")
+	var resources int64
+	db.Model(&models.CalDAVResource{}).Count(&resources)
+	assert.EqualValues(t, 1, resources, "a UUID UID with the default name needs no resource row")
+
+	w = dav("DELETE", href, "", "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = dav("DELETE", href, "")
+	assert.Equal(t, http.StatusConflict, w.Code, "a task with subtasks stays")
+	w = dav("DELETE", "/caldav/calendars/inbox/"+subtaskUID+".ics", "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = dav("DELETE", href, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+	db.Model(&models.CalDAVResource{}).Count(&resources)
+	assert.EqualValues(t, 0, resources)
+
+	w = dav("GET", href, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCalDAV_PutInvalid(t *testing.T) {
+	_, _, _, dav := setupCalDAV(t)
+
+	for name, body := range map[string]string{
+		"not iCalendar": "hello",
+		"an event":      strings.Replace(vtodo("a", "Party"), "VTODO", "VEVENT", -1),
+		"no title":      vtodo("b", ""),
+		"ending rule":   vtodo("c", "Water plants", "RRULE:FREQ=DAILY;COUNT=3"),
+	} {
+		w := dav("PUT", "/caldav/calendars/inbox/x.ics", body)
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+		assert.Contains(t, w.Body.String(), "valid-calendar-data", name)
+	}
+
+	w := dav("PUT", "/caldav/calendars/", vtodo("d", "Oops"))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = dav("DELETE", "/caldav/calendars/inbox/", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = dav("GET", "/caldav/elsewhere/", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCalDAV_Reports(t *testing.T) {
+	db, userID, _, dav := setupCalDAV(t)
+	early := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	late := time.Date(2026, 12, 1, 9, 0, 0, 0, time.UTC)
+	open := models.Task{Title: "Open early", UserID: userID, DueDate: &early}
+	done := models.Task{Title: "Done late", UserID: userID, DueDate: &late, Status: true}
+	undated := models.Task{Title: "Someday", UserID: userID}
+	for _, task := range []*models.Task{&open, &done, &undated} {
+		require.NoError(t, db.Create(task).Error)
+	}
+	inbox := "/caldav/calendars/inbox/"
+
+	w := dav("REPORT", inbox, `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>`+inbox+open.ID.String()+`.ics</d:href>
+  <d:href>`+inbox+`missing.ics</d:href>
+</c:calendar-multiget>`)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "SUMMARY:Open early")
+	assert.NotContains(t, body, "Done late")
+	assert.Contains(t, body, inbox+"missing.ics</d:href><d:status>HTTP/1.1 404 Not Found")
+
+	query := func(filter string) string {
+		w := dav("REPORT", inbox, `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO">`+filter+`</c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`)
+		require.Equal(t, http.StatusMultiStatus, w.Code)
+		return w.Body.String()
+	}
+
+	body = query(`<c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter>`)
+	assert.Contains(t, body, open.ID.String())
+	assert.Contains(t, body, undated.ID.String())
+	assert.NotContains(t, body, done.ID.String())
+
+	body = query(`<c:time-range start="20261115T000000Z" end="20270101T000000Z"/>`)
+	assert.NotContains(t, body, open.ID.String())
+	assert.Contains(t, body, done.ID.String())
+	assert.Contains(t, body, undated.ID.String())
+
+	w = dav("REPORT", inbox, `<d:sync-collection xmlns:d="DAV:"/>`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "supported-report")
+}
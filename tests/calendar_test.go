@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/ical"
+	"to_do_api/models"
+	"to_do_api/transfer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newCalendarRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := gin.New()
+	router.GET("/calendar/feed/:token", controllers.ServeCalendarFeed(db, taskService(db)))
+	authorized := router.Group("/")
+	authorized.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.String())
+		c.Next()
+	})
+	authorized.POST("/calendar/feed", controllers.CreateCalendarFeed(db))
+	authorized.GET("/calendar/feed", controllers.GetCalendarFeed(db))
+	authorized.DELETE("/calendar/feed", controllers.DeleteCalendarFeed(db))
+	return router
+}
+
+func createCalendarFeed(t *testing.T, router *gin.Engine) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/calendar/feed", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotEmpty(t, response.Token)
+	assert.Equal(t, "/calendar/feed/"+response.Token+".ics", response.URL)
+	return response.URL
+}
+
+func getFeed(router *gin.Engine, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", url, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCalendarFeed(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	due := time.Date(2026, 11, 2, 15, 30, 0, 0, time.UTC)
+	parent := models.Task{Title: "Plan trip", UserID: userID}
+	require.NoError(t, db.Create(&parent).Error)
+	require.NoError(t, db.Create(&models.Task{
+		Title: "Book flights, hotel; car", Description: "Window seat\nAisle is fine too",
+		UserID: userID, DueDate: &due, Priority: models.PriorityHigh, Labels: []string{"travel"},
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO", ParentID: &parent.ID,
+	}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Done already", Status: true, UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Not mine", UserID: uuid.New()}).Error)
+	router := newCalendarRouter(db, userID)
+
+	w := getFeed(router, "/calendar/feed")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	url := createCalendarFeed(t, router)
+	w = getFeed(router, url)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/calendar")
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 3, strings.Count(body, "BEGIN:VTODO"))
+	assert.NotContains(t, body, "Not mine")
+	assert.NotContains(t, body, "BEGIN:VEVENT")
+	assert.Contains(t, body, `SUMMARY:Book flights\, hotel\; car`)
+	assert.Contains(t, body, `DESCRIPTION:Window seat\nAisle is fine too`)
+	assert.Contains(t, body, "DUE:20261102T153000Z")
+	assert.Contains(t, body, "PRIORITY:1")
+	assert.Contains(t, body, "CATEGORIES:travel")
+	assert.Contains(t, body, "RRULE:FREQ=WEEKLY;BYDAY=MO")
+	assert.Contains(t, body, "RELATED-TO:"+parent.ID.String())
+	assert.Contains(t, body, "STATUS:COMPLETED")
+
+	w = getFeed(router, url+"?events=true&status=false")
+	require.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VTODO"))
+	assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT"))
+	assert.Contains(t, body, "DTSTART:20261102T153000Z")
+	assert.NotContains(t, body, "Done already")
+
+	w = getFeed(router, "/calendar/feed")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	rotated := createCalendarFeed(t, router)
+	assert.Equal(t, http.StatusNotFound, getFeed(router, url).Code)
+	assert.Equal(t, http.StatusOK, getFeed(router, rotated).Code)
+	assert.Equal(t, http.StatusNotFound, getFeed(router, "/calendar/feed/guess.ics").Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/calendar/feed", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, getFeed(router, rotated).Code)
+}
+
+func TestICalWriterFolding(t *testing.T) {
+	var buf bytes.Buffer
+	w := ical.NewWriter(&buf)
+	summary := strings.Repeat("Überprüfung, ", 20)
+	w.Begin("VCALENDAR")
+	w.Text("SUMMARY", summary)
+	w.End("VCALENDAR")
+	require.NoError(t, w.Flush())
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+
+	components, err := ical.Decode(&buf)
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+	property, ok := components[0].Get("SUMMARY")
+	require.True(t, ok)
+	assert.Equal(t, summary, ical.UnescapeText(property.Value))
+}
+
+const outlookCalendar = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//Microsoft Corporation//Outlook 16.0 MIMEDIR//EN\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:W. Europe Standard Time\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16011028T030000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:16010325T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:+0100\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:parent@example.com\r\n" +
+	"SUMMARY:Summer deadline\r\n" +
+	"DUE;TZID=W. Europe Standard Time:20260715T090000\r\n" +
+	"PRIORITY:3\r\n" +
+	"CATEGORIES:Work Stuff,Q3\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:child@example.com\r\n" +
+	"RELATED-TO;RELTYPE=PARENT:parent@example.com\r\n" +
+	"SUMMARY:Winter deadline with a summary that is long enough to be folded o\r\n" +
+	" nto a second line\r\n" +
+	"DUE;TZID=\"W. Europe Standard Time\":20261215T090000\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"PRIORITY:7\r\n" +
+	"RRULE:FREQ=MONTHLY;INTERVAL=2;WKST=SU\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:child@example.com\r\n" +
+	"RECURRENCE-ID:20261215T090000Z\r\n" +
+	"SUMMARY:Moved occurrence\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:event@example.com\r\n" +
+	"SUMMARY:Exam\r\n" +
+	"DTSTART;VALUE=DATE:20261120\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestReadICalendar(t *testing.T) {
+	rows, problems := transfer.ReadICalendar([]byte(outlookCalendar), time.UTC, false)
+	assert.Empty(t, problems)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, "Summer deadline", rows[0].Title)
+	require.NotNil(t, rows[0].DueDate)
+	assert.Equal(t, time.Date(2026, 7, 15, 7, 0, 0, 0, time.UTC), *rows[0].DueDate)
+	assert.Equal(t, models.PriorityHigh, rows[0].Priority)
+	assert.Equal(t, []string{"Work-Stuff", "Q3"}, rows[0].Labels)
+
+	assert.Equal(t, "Winter deadline with a summary that is long enough to be folded onto a second line", rows[1].Title)
+	require.NotNil(t, rows[1].DueDate)
+	assert.Equal(t, time.Date(2026, 12, 15, 8, 0, 0, 0, time.UTC), *rows[1].DueDate)
+	assert.True(t, rows[1].Status)
+	assert.Equal(t, models.PriorityLow, rows[1].Priority)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2", rows[1].Recurrence)
+	assert.Equal(t, rows[0].Line, rows[1].ParentLine)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	rows, problems = transfer.ReadICalendar([]byte(outlookCalendar), berlin, true)
+	assert.Empty(t, problems)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Exam", rows[2].Title)
+	assert.Equal(t, time.Date(2026, 11, 20, 23, 59, 0, 0, berlin).UTC(), *rows[2].DueDate)
+
+	calendar := "BEGIN:VCALENDAR\nVERSION:2.0\n" +
+		"BEGIN:VTODO\nSUMMARY:Floating\nDUE:20261101T100000\nEND:VTODO\n" +
+		"BEGIN:VTODO\nSUMMARY:Named zone\nDUE;TZID=America/New_York:20261101T100000\nEND:VTODO\n" +
+		"BEGIN:VTODO\nSUMMARY:Ends\nRRULE:FREQ=DAILY;COUNT=3\nEND:VTODO\n" +
+		"BEGIN:VTODO\nSUMMARY:Nowhere\nDUE;TZID=Nowhere/Special:20261101T100000\nEND:VTODO\n" +
+		"END:VCALENDAR\n"
+	rows, problems = transfer.ReadICalendar([]byte(calendar), berlin, false)
+	require.Len(t, rows, 2)
+	assert.Equal(t, time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC), *rows[0].DueDate)
+	assert.Equal(t, time.Date(2026, 11, 1, 15, 0, 0, 0, time.UTC), *rows[1].DueDate)
+	require.Len(t, problems, 2)
+	assert.Equal(t, transfer.Problem{Line: 11, Field: "recurrence", Error: "recurrences that end (COUNT or UNTIL) are not supported"}, problems[0])
+	assert.Equal(t, "due_date", problems[1].Field)
+
+	_, problems = transfer.ReadICalendar([]byte("BEGIN:VCALENDAR\nBEGIN:VTODO\nEND:VCALENDAR\n"), time.UTC, false)
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error, "unexpected END:VCALENDAR")
+}
+
+func TestImportTasks_ICalendarRoundTrip(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	due := time.Date(2026, 11, 2, 15, 30, 0, 0, time.UTC)
+	parent := models.Task{Title: "Plan trip", UserID: userID}
+	require.NoError(t, db.Create(&parent).Error)
+	require.NoError(t, db.Create(&models.Task{
+		Title: "Book flights, hotel; car", Description: "Window seat\nAisle is fine too",
+		UserID: userID, DueDate: &due, Priority: models.PriorityMedium, Labels: []string{"travel", "q4"},
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,FR", ParentID: &parent.ID,
+	}).Error)
+	router := newTransferRouter(db, userID)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=ics", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "tasks.ics")
+
+	otherUser := uuid.New()
+	w = uploadFile(t, newTransferRouter(db, otherUser), "/tasks/import", "calendar.ics", w.Body.String(), nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var imported []models.Task
+	require.NoError(t, db.Where("user_id = ?", otherUser).Order("created_at").Find(&imported).Error)
+	require.Len(t, imported, 2)
+	assert.Equal(t, "Plan trip", imported[0].Title)
+	child := imported[1]
+	assert.Equal(t, "Book flights, hotel; car", child.Title)
+	assert.Equal(t, "Window seat\nAisle is fine too", child.Description)
+	require.NotNil(t, child.DueDate)
+	assert.True(t, due.Equal(*child.DueDate))
+	assert.Equal(t, models.PriorityMedium, child.Priority)
+	assert.Equal(t, []string{"travel", "q4"}, child.Labels)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,FR", child.Recurrence)
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, imported[0].ID, *child.ParentID)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateTask_IfMatch(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
+
+	body, _ := json.Marshal(map[string]interface{}{"title": "v1"})
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	var task models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	assert.Equal(t, 1, task.Version)
+
+	update := func(title, ifMatch string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"title": title})
+		req, _ := http.NewRequest("PUT", "/tasks/"+task.ID.String(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := update("from client A", etag)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.NotEqual(t, etag, first.Header().Get("ETag"))
+
+	second := update("from client B", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, second.Code)
+
+	require.NoError(t, db.First(&task, "id = ?", task.ID).Error)
+	assert.Equal(t, "from client A", task.Title)
+	assert.Equal(t, 2, task.Version)
+}
+
+func TestDeleteTask_IfMatchStale(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := models.Task{Title: "Keep me", UserID: userID, Version: 3}
+	require.NoError(t, db.Create(&task).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), events.NewMemoryBroker()))
+
+	req, _ := http.NewRequest("DELETE", "/tasks/"+task.ID.String(), nil)
+	req.Header.Set("If-Match", `"`+task.ID.String()+`-2"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	var count int64
+	db.Model(&models.Task{}).Where("id = ?", task.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestListTasks_IfNoneMatch(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "One", UserID: userID}).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	req, _ := http.NewRequest("GET", "/tasks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req, _ = http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	require.NoError(t, db.Create(&models.Task{Title: "Two", UserID: userID}).Error)
+
+	req, _ = http.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"to_do_api/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeEnv(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func writeFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "to_do.yaml", `
+app_env: development
+port: 9000
+db_host: db.internal
+db_max_open_conns: 50
+db_statement_timeout: 5s
+db_read_replicas:
+  - replica-1
+  - replica-2:5433
+auto_migrate: false
+tracing_sample_ratio: 0.25
+`)
+	env := fakeEnv(map[string]string{"CONFIG_FILE": path, "PORT": "9001", "DB_HOST": "db.env"})
+
+	cfg, args, err := config.Load([]string{"-port", "9002", "-auto-migrate", "migrate", "up"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, "9002", cfg.PORT, "flags override the environment")
+	assert.Equal(t, "db.env", cfg.DB_HOST, "the environment overrides the file")
+	assert.Equal(t, 50, cfg.DB_MAX_OPEN_CONNS)
+	assert.Equal(t, 5*time.Second, cfg.DB_STATEMENT_TIMEOUT)
+	assert.Equal(t, []string{"replica-1", "replica-2:5433"}, cfg.DB_READ_REPLICAS)
+	assert.True(t, cfg.AUTO_MIGRATE)
+	assert.Equal(t, 0.25, cfg.TRACING_SAMPLE_RATIO)
+	assert.Equal(t, "db", cfg.DB_NAME, "unset settings keep their defaults")
+}
+
+func TestLoad_TOMLAndSecretFiles(t *testing.T) {
+	secret := writeFile(t, "jwt_secret", "0123456789abcdef0123456789abcdef\n")
+	password := writeFile(t, "db_password", "hunter2-hunter2\n")
+	path := writeFile(t, "to_do.toml", `
+jwt_secret_file = "`+secret+`"
+idempotency_ttl = "1h"
+db_read_replicas = "replica-1, replica-2"
+`)
+
+	cfg, _, err := config.Load([]string{"-config", path}, fakeEnv(map[string]string{"DB_PASSWORD_FILE": password}))
+	require.NoError(t, err)
+	assert.Equal(t, "production", cfg.APP_ENV)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", cfg.JWT_SECRET)
+	assert.Equal(t, "hunter2-hunter2", cfg.DB_PASSWORD)
+	assert.Equal(t, time.Hour, cfg.IDEMPOTENCY_TTL)
+	assert.Equal(t, []string{"replica-1", "replica-2"}, cfg.DB_READ_REPLICAS)
+
+	_, _, err = config.Load(nil, fakeEnv(map[string]string{
+		"APP_ENV": "development", "JWT_SECRET": "x", "JWT_SECRET_FILE": secret,
+	}))
+	assert.ErrorContains(t, err, "set JWT_SECRET or JWT_SECRET_FILE, not both")
+
+	_, _, err = config.Load(nil, fakeEnv(map[string]string{
+		"APP_ENV": "development", "JWT_SECRET_FILE": filepath.Join(t.TempDir(), "missing"),
+	}))
+	assert.ErrorContains(t, err, "JWT_SECRET_FILE")
+}
+
+func TestLoad_RefusesInsecureDefaults(t *testing.T) {
+	_, _, err := config.Load(nil, fakeEnv(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "JWT_SECRET is the built-in default")
+	assert.Contains(t, err.Error(), "DB_PASSWORD is the built-in default")
+
+	_, _, err = config.Load(nil, fakeEnv(map[string]string{"JWT_SECRET": "short", "DB_PASSWORD": "hunter2"}))
+	assert.ErrorContains(t, err, "JWT_SECRET must be at least 32 bytes")
+
+	// SQLite has no password.
+	_, _, err = config.Load(nil, fakeEnv(map[string]string{
+		"DB_DRIVER": "sqlite", "JWT_SECRET": "0123456789abcdef0123456789abcdef",
+	}))
+	assert.NoError(t, err)
+
+	cfg, _, err := config.Load(nil, fakeEnv(map[string]string{"APP_ENV": "development"}))
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultJWTSecret, cfg.JWT_SECRET)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		env  map[string]string
+		args []string
+		want string
+	}{
+		"count":       {env: map[string]string{"DB_MAX_OPEN_CONNS": "lots"}, want: `invalid DB_MAX_OPEN_CONNS "lots"`},
+		"negative":    {env: map[string]string{"DB_MAX_IDLE_CONNS": "-1"}, want: "cannot be negative"},
+		"duration":    {args: []string{"-db-statement-timeout", "soon"}, want: `invalid DB_STATEMENT_TIMEOUT "soon"`},
+		"port":        {env: map[string]string{"PORT": "http"}, want: `invalid PORT "http"`},
+		"driver":      {env: map[string]string{"DB_DRIVER": "mysql"}, want: `unknown DB_DRIVER "mysql"`},
+		"broker":      {env: map[string]string{"DB_DRIVER": "sqlite", "EVENT_BROKER": "postgres"}, want: "EVENT_BROKER=postgres needs DB_DRIVER=postgres"},
+		"replicas":    {env: map[string]string{"DB_DRIVER": "sqlite", "DB_READ_REPLICAS": "replica-1"}, want: "DB_READ_REPLICAS needs DB_DRIVER=postgres"},
+		"metrics":     {env: map[string]string{"PORT": "9090"}, want: "METRICS_ADDR must differ from PORT"},
+		"exporter":    {env: map[string]string{"TRACING_EXPORTER": "jaeger"}, want: `unknown TRACING_EXPORTER "jaeger"`},
+		"ratio":       {args: []string{"-tracing-sample-ratio", "half"}, want: `invalid TRACING_SAMPLE_RATIO "half"`},
+		"origin":      {env: map[string]string{"STREAM_ALLOWED_ORIGINS": "https://app.example.com, app.example.com"}, want: `invalid STREAM_ALLOWED_ORIGINS entry "app.example.com"`},
+		"ratio range": {env: map[string]string{"TRACING_SAMPLE_RATIO": "1.5"}, want: "TRACING_SAMPLE_RATIO must be between 0 and 1"},
+		"unknown key": {env: map[string]string{"CONFIG_FILE": writeFile(t, "to_do.yml", "db_hots: db\n")}, want: "unknown setting DB_HOTS"},
+		"format":      {args: []string{"-config", writeFile(t, "to_do.ini", "port=1\n")}, want: "must be .yaml, .yml or .toml"},
+		"flag":        {args: []string{"-no-such-flag"}, want: "flag provided but not defined"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := map[string]string{"APP_ENV": "development"}
+			for key, value := range tc.env {
+				env[key] = value
+			}
+			_, _, err := config.Load(tc.args, fakeEnv(env))
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+	"to_do_api/config"
+	"to_do_api/database"
+	"to_do_api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sqliteConfig(t *testing.T) *config.Config {
+	return &config.Config{
+		DB_DRIVER:            "sqlite",
+		DB_PATH:              filepath.Join(t.TempDir(), "to_do.db"),
+		DB_MAX_OPEN_CONNS:    4,
+		DB_MAX_IDLE_CONNS:    2,
+		DB_CONN_MAX_LIFETIME: 30 * time.Minute,
+		DB_CONNECT_TIMEOUT:   time.Second,
+		DB_STATEMENT_TIMEOUT: 100 * time.Millisecond,
+	}
+}
+
+// slowQuery counts to a billion, which takes SQLite far longer than the
+// statement timeout.
+const slowQuery = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000) SELECT count(*) FROM n"
+
+func TestRetry_SucceedsOnceReady(t *testing.T) {
+	attempts := 0
+	err := database.Retry(context.Background(), database.Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Timeout: time.Second}, func() error {
+		if attempts++; attempts < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestRetry_GivesUpAfterTimeout(t *testing.T) {
+	attempts := 0
+	start := time.Now()
+	err := database.Retry(context.Background(), database.Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond, Timeout: 100 * time.Millisecond}, func() error {
+		attempts++
+		return errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Greater(t, attempts, 1)
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = database.Retry(ctx, database.Backoff{Initial: time.Hour, Max: time.Hour, Timeout: 2 * time.Hour}, func() error {
+		attempts++
+		return errors.New("connection refused")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts, "a cancelled context stops retrying")
+}
+
+func TestConnect_AppliesSettings(t *testing.T) {
+	db, err := database.Connect(sqliteConfig(t))
+	require.NoError(t, err)
+	// Migrations are not held to the statement timeout.
+	require.NoError(t, database.Migrate(db))
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 4, sqlDB.Stats().MaxOpenConnections)
+
+	var count int64
+	err = db.Raw(slowQuery).Find(&count).Error
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The connection is usable afterwards, and the timeout applies per
+	// statement rather than to the handle.
+	require.NoError(t, db.Create(&models.Task{Title: "After"}).Error)
+	require.NoError(t, db.Model(&models.Task{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// A shorter deadline from the caller, such as a request's, wins.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = db.WithContext(ctx).Raw(slowQuery).Find(&count).Error
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// Without read replicas, ReadReplica reads from the primary.
+	var titles []string
+	require.NoError(t, database.ReadReplica(db).Model(&models.Task{}).Pluck("title", &titles).Error)
+	assert.Equal(t, []string{"After"}, titles)
+}
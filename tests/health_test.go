@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"to_do_api/controllers"
+	"to_do_api/database"
+	"to_do_api/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func probe(t *testing.T, handler gin.HandlerFunc) (int, healthResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/probe", handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/probe", nil))
+
+	var body healthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealthz(t *testing.T) {
+	code, body := probe(t, controllers.Healthz())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body.Status)
+}
+
+func TestReadyz(t *testing.T) {
+	db := setupTestTaskDB(t)
+	draining := false
+	checks := map[string]controllers.HealthCheck{
+		"database": func(ctx context.Context) error { return database.Ping(ctx, db) },
+		"server": func(context.Context) error {
+			if draining {
+				return errors.New("shutting down")
+			}
+			return nil
+		},
+	}
+
+	code, body := probe(t, controllers.Readyz(checks))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body.Status)
+	assert.Equal(t, map[string]string{"database": "ok", "server": "ok"}, body.Checks)
+
+	draining = true
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	code, body = probe(t, controllers.Readyz(checks))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body.Status)
+	assert.Equal(t, "shutting down", body.Checks["server"])
+	assert.Contains(t, body.Checks["database"], "closed")
+}
+
+func TestPostgresBroker_NotReadyUntilListening(t *testing.T) {
+	broker := events.NewPostgresBroker(setupTestTaskDB(t), "")
+	assert.EqualError(t, broker.Ready(), "not listening for events")
+}
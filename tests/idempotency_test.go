@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/middleware"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestIdempotentRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := newTestTaskRouter(userID.String())
+	router.Use(middleware.Idempotency(db, time.Hour))
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestIdempotentRouter(db, userID)
+
+	first := postWithKey(router, "abc", `{"title": "Only once"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	retry := postWithKey(router, "abc", `{"title": "Only once"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+	assert.Equal(t, int64(1), countTasks(db, userID))
+
+	// Keys are per user.
+	other := newTestIdempotentRouter(db, uuid.New())
+	assert.Equal(t, http.StatusCreated, postWithKey(other, "abc", `{"title": "Only once"}`).Code)
+}
+
+func TestIdempotency_DifferentBody(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestIdempotentRouter(db, uuid.New())
+
+	require.Equal(t, http.StatusCreated, postWithKey(router, "abc", `{"title": "One"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(router, "abc", `{"title": "Two"}`).Code)
+}
+
+func TestIdempotency_InFlightDuplicate(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestIdempotentRouter(db, userID)
+
+	// Simulate the original request still running by claiming the key first.
+	first := postWithKey(router, "abc", `{"title": "Slow"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "abc").Update("status", models.IdempotencyInProgress).Error)
+
+	w := postWithKey(router, "abc", `{"title": "Slow"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, int64(1), countTasks(db, userID))
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.Use(middleware.Idempotency(db, time.Hour))
+	calls := 0
+	router.POST("/flaky", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	send := func() int {
+		req, _ := http.NewRequest("POST", "/flaky", nil)
+		req.Header.Set("Idempotency-Key", "retry-me")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_ExpiredKeyRunsAgain(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestIdempotentRouter(db, userID)
+
+	require.Equal(t, http.StatusCreated, postWithKey(router, "abc", `{"title": "Again"}`).Code)
+	require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "abc").Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error)
+
+	w := postWithKey(router, "abc", `{"title": "Again"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int64(2), countTasks(db, userID))
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.Use(gin.Recovery(), middleware.Idempotency(db, time.Hour))
+	calls := 0
+	router.POST("/fragile", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	send := func() int {
+		req, _ := http.NewRequest("POST", "/fragile", nil)
+		req.Header.Set("Idempotency-Key", "retry-me")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_AbandonedKeyIsReclaimed(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestIdempotentRouter(db, userID)
+
+	// A process that died mid-request leaves its claim behind until the
+	// lease runs out, not for the whole ttl.
+	require.Equal(t, http.StatusCreated, postWithKey(router, "abc", `{"title": "Crashed"}`).Code)
+	require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "abc").Updates(map[string]interface{}{
+		"status":     models.IdempotencyInProgress,
+		"expires_at": time.Now().UTC().Add(-time.Second),
+	}).Error)
+
+	w := postWithKey(router, "abc", `{"title": "Crashed"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+	var record models.IdempotencyKey
+	require.NoError(t, db.Where("idempotency_key = ?", "abc").First(&record).Error)
+	assert.Equal(t, models.IdempotencyCompleted, record.Status)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), record.ExpiresAt, time.Minute, "completed responses are kept for the full ttl")
+}
+
+func TestIdempotency_QueryIsPartOfRequest(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.Use(middleware.Idempotency(db, time.Hour))
+	router.POST("/import", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"dry_run": c.Query("dry_run")})
+	})
+
+	send := func(query string) int {
+		req, _ := http.NewRequest("POST", "/import?"+query, nil)
+		req.Header.Set("Idempotency-Key", "import-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, send("dry_run=true&format=csv"))
+	assert.Equal(t, http.StatusOK, send("format=csv&dry_run=true"), "parameter order does not matter")
+	assert.Equal(t, http.StatusUnprocessableEntity, send("dry_run=false&format=csv"))
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestIdempotentRouter(db, uuid.New())
+
+	w := postWithKey(router, "abc", `{"title": "`+strings.Repeat("x", 17<<20)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestIdempotency_LostClaimIsLeftAlone(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.Use(middleware.Idempotency(db, time.Hour))
+	takeover := time.Now().UTC().Add(time.Minute).Truncate(time.Microsecond)
+	status := http.StatusOK
+	router.POST("/slow", func(c *gin.Context) {
+		// While this request runs, its lease runs out and a retry claims
+		// the key.
+		require.NoError(t, db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "slow").Update("expires_at", takeover).Error)
+		c.JSON(status, gin.H{"ok": true})
+	})
+
+	send := func() int {
+		req, _ := http.NewRequest("POST", "/slow", nil)
+		req.Header.Set("Idempotency-Key", "slow")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Neither storing the response nor releasing the key after a server
+	// error touches the retry's claim.
+	for _, status = range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		require.Equal(t, status, send())
+
+		var record models.IdempotencyKey
+		require.NoError(t, db.Where("idempotency_key = ?", "slow").First(&record).Error)
+		assert.Equal(t, models.IdempotencyInProgress, record.Status)
+		assert.True(t, takeover.Equal(record.ExpiresAt))
+		require.NoError(t, db.Where("idempotency_key = ?", "slow").Delete(&models.IdempotencyKey{}).Error)
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"to_do_api/metrics"
+	"to_do_api/middleware"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue scrapes the metrics and returns the sample for series, such as
+// `http_requests_total{method="GET"}`, or 0 if there is none.
+func metricValue(t *testing.T, series string) float64 {
+	w := httptest.NewRecorder()
+	metrics.Handler("").ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), series+" "); ok {
+			number, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return number
+		}
+	}
+	return 0
+}
+
+func TestMetrics_HandlerToken(t *testing.T) {
+	handler := metrics.Handler("scrape-token")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-token")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+func TestMetrics_HTTPRequestsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Metrics())
+	r.GET("/metrics-test/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/no-such-route"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2.0, metricValue(t, `http_requests_total{method="GET",route="/metrics-test/:id",status="204"}`))
+	assert.Equal(t, 2.0, metricValue(t, `http_request_duration_seconds_count{method="GET",route="/metrics-test/:id",status="204"}`))
+	assert.GreaterOrEqual(t, metricValue(t, `http_requests_total{method="GET",route="unmatched",status="404"}`), 1.0)
+}
+
+func TestMetrics_Database(t *testing.T) {
+	db := setupTestTaskDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(3)
+	require.NoError(t, metrics.InstrumentDB(db, "metrics_test"))
+
+	series := `db_query_duration_seconds_count{operation="query",table="tasks"}`
+	before := metricValue(t, series)
+	var tasks []models.Task
+	require.NoError(t, db.Find(&tasks).Error)
+	assert.Equal(t, before+1, metricValue(t, series))
+
+	assert.Equal(t, 3.0, metricValue(t, `go_sql_max_open_connections{db_name="metrics_test"}`))
+}
+
+func TestMetrics_BusinessEvents(t *testing.T) {
+	ctx := context.Background()
+	tasks := service.NewTaskService(repository.NewMemoryTaskRepository())
+	userID := uuid.New()
+
+	created := metricValue(t, "todo_tasks_created_total")
+	completed := metricValue(t, "todo_tasks_completed_total")
+
+	task, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Ship it"})
+	require.NoError(t, err)
+	task, _, err = tasks.Update(ctx, userID, task, service.TaskInput{Title: "Ship it", Status: true})
+	require.NoError(t, err)
+	// Saving a task that is already done does not complete it again.
+	_, _, err = tasks.Update(ctx, userID, task, service.TaskInput{Title: "Shipped", Status: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, created+1, metricValue(t, "todo_tasks_created_total"))
+	assert.Equal(t, completed+1, metricValue(t, "todo_tasks_completed_total"))
+
+	// Changes in a transaction count only once it commits, so rolled back
+	// ones, such as those of a dry run, never do.
+	errRollback := errors.New("roll back")
+	err = tasks.Transaction(ctx, func(ctx context.Context) error {
+		_, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Dry run", Status: true})
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	assert.Equal(t, created+1, metricValue(t, "todo_tasks_created_total"))
+
+	err = tasks.Transaction(ctx, func(ctx context.Context) error {
+		task, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Kept"})
+		require.NoError(t, err)
+		err = tasks.Transaction(ctx, func(ctx context.Context) error {
+			_, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Rolled back"})
+			require.NoError(t, err)
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+		_, _, err = tasks.Update(ctx, userID, task, service.TaskInput{Title: "Kept", Status: true})
+		require.NoError(t, err)
+		assert.Equal(t, created+1, metricValue(t, "todo_tasks_created_total"), "not before the commit")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, created+2, metricValue(t, "todo_tasks_created_total"))
+	assert.Equal(t, completed+2, metricValue(t, "todo_tasks_completed_total"))
+
+	users := service.NewUserService(repository.NewMemoryUserRepository())
+	succeeded := metricValue(t, `todo_logins_total{result="succeeded"}`)
+	failed := metricValue(t, `todo_logins_total{result="failed"}`)
+
+	_, err = users.Register(ctx, "metrics@example.com", "password123")
+	require.NoError(t, err)
+	_, err = users.Authenticate(ctx, "metrics@example.com", "password123")
+	require.NoError(t, err)
+	_, err = users.Authenticate(ctx, "metrics@example.com", "wrong")
+	require.ErrorIs(t, err, service.ErrInvalidCredentials)
+	_, err = users.Authenticate(ctx, "nobody@example.com", "password123")
+	require.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	assert.Equal(t, succeeded+1, metricValue(t, `todo_logins_total{result="succeeded"}`))
+	assert.Equal(t, failed+2, metricValue(t, `todo_logins_total{result="failed"}`))
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+	"to_do_api/database"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openEmptyDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+func TestMigrate_SchemaMatchesModels(t *testing.T) {
+	db := setupTestTaskDB(t)
+
+	for _, model := range []interface{}{
+		&models.User{}, &models.Task{}, &models.Reminder{}, &models.Notification{},
+		&models.NotificationPreference{}, &models.Webhook{}, &models.WebhookDelivery{},
+		&models.TaskEvent{}, &models.IdempotencyKey{}, &models.SmartList{},
+		&models.SmartListShare{}, &models.CalendarFeed{}, &models.AppPassword{},
+		&models.CalDAVResource{}, &models.StreamTicket{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		require.True(t, db.Migrator().HasTable(stmt.Schema.Table), stmt.Schema.Table)
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+
+	// Migrating again is a no-op.
+	require.NoError(t, database.Migrate(db))
+	statuses, err := database.NewMigrator(db, database.Migrations).Status()
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, status.Name)
+	}
+}
+
+// baselineTask and baselineUser are the models as they were before any
+// schema changes, which AutoMigrate created the first deployments from.
+type baselineTask struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Title       string    `gorm:"not null"`
+	Description string
+	Status      bool      `gorm:"default:false"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null"`
+}
+
+func (baselineTask) TableName() string { return "tasks" }
+
+type baselineUser struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
+	Email    string    `gorm:"unique"`
+	Password string    `gorm:"not null"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+func TestMigrate_AdoptsBaselineSchema(t *testing.T) {
+	db := openEmptyDB(t)
+	require.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineTask{}))
+	userID := uuid.New()
+	require.NoError(t, db.Create(&baselineUser{ID: userID, Email: "ada@example.com", Password: "hash"}).Error)
+	require.NoError(t, db.Create(&baselineTask{ID: uuid.New(), Title: "From before", UserID: userID}).Error)
+
+	require.NoError(t, database.Migrate(db))
+
+	stmt := &gorm.Statement{DB: db}
+	require.NoError(t, stmt.Parse(&models.Task{}))
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" {
+			assert.True(t, db.Migrator().HasColumn(&models.Task{}, field.DBName), field.DBName)
+		}
+	}
+	assert.True(t, db.Migrator().HasIndex(&models.Task{}, "idx_tasks_project"))
+
+	var task models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).First(&task).Error)
+	assert.Equal(t, "From before", task.Title)
+	assert.Equal(t, 1, task.Version)
+	assert.Equal(t, models.PriorityNone, task.Priority)
+}
+
+func TestMigrations_DialectsInStep(t *testing.T) {
+	postgres, err := database.ReadMigrations(database.Migrations, "postgres")
+	require.NoError(t, err)
+	sqlite, err := database.ReadMigrations(database.Migrations, "sqlite")
+	require.NoError(t, err)
+
+	require.Equal(t, len(postgres), len(sqlite))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	db := openEmptyDB(t)
+	migrations := fstest.MapFS{
+		"sqlite/1_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id integer PRIMARY KEY, body text);\nINSERT INTO notes (body) VALUES ('hello; world');\n")},
+		"sqlite/1_notes.down.sql": {Data: []byte("DROP TABLE notes;\n")},
+		// Renaming a column and backfilling are what AutoMigrate could not do.
+		"sqlite/2_rename_body.up.sql":   {Data: []byte("-- keep the text\nALTER TABLE notes RENAME COLUMN body TO text;\nUPDATE notes SET text = upper(text);\n")},
+		"sqlite/2_rename_body.down.sql": {Data: []byte("ALTER TABLE notes RENAME COLUMN text TO body;\n")},
+	}
+	migrator := database.NewMigrator(db, migrations)
+
+	applied, err := migrator.Up(1)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "notes", applied[0].Name)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	applied, err = migrator.Up(0)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	var text string
+	require.NoError(t, db.Raw("SELECT text FROM notes").Scan(&text).Error)
+	assert.Equal(t, "HELLO; WORLD", text)
+
+	applied, err = migrator.Up(0)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+	assert.True(t, db.Migrator().HasColumn("notes", "body"))
+
+	reverted, err = migrator.Down(0)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.False(t, db.Migrator().HasTable("notes"))
+
+	// A version recorded without files is reported, and cannot be rolled back.
+	require.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", 99, "elsewhere", time.Now()).Error)
+	statuses, err = migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[2].Missing)
+	_, err = migrator.Down(1)
+	assert.ErrorContains(t, err, "no files")
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	db := openEmptyDB(t)
+	migrator := database.NewMigrator(db, fstest.MapFS{
+		"sqlite/1_broken.up.sql":   {Data: []byte("CREATE TABLE half (id integer);\nTHIS IS NOT SQL;\n")},
+		"sqlite/1_broken.down.sql": {Data: []byte("DROP TABLE half;\n")},
+	})
+
+	_, err := migrator.Up(0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1_broken up")
+	assert.False(t, db.Migrator().HasTable("half"))
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Nil(t, statuses[0].AppliedAt)
+}
+
+func TestReadMigrations_Invalid(t *testing.T) {
+	_, err := database.ReadMigrations(fstest.MapFS{
+		"sqlite/1_notes.up.sql": {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+
+	_, err = database.ReadMigrations(fstest.MapFS{
+		"sqlite/notes.sql": {Data: []byte("SELECT 1;")},
+	}, "sqlite")
+	assert.ErrorContains(t, err, "unexpected migration file")
+
+	_, err = database.ReadMigrations(fstest.MapFS{}, "mysql")
+	assert.ErrorContains(t, err, `no migrations for database dialect "mysql"`)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "postgres"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sqlite"), 0o755))
+	now := time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC)
+
+	paths, err := database.CreateMigration(dir, "Add task Color", now)
+	require.NoError(t, err)
+	assert.Len(t, paths, 4)
+	assert.Contains(t, paths, filepath.Join(dir, "sqlite", "20261020083000_add_task_color.down.sql"))
+
+	migrations, err := database.ReadMigrations(os.DirFS(dir), "postgres")
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, int64(20261020083000), migrations[0].Version)
+	assert.Equal(t, "add_task_color", migrations[0].Name)
+
+	_, err = database.CreateMigration(dir, "add task color", now)
+	assert.Error(t, err, "existing files are not overwritten")
+	_, err = database.CreateMigration(dir, "drop; tables", now)
+	assert.ErrorContains(t, err, "invalid migration name")
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"to_do_api/controllers"
+	"to_do_api/models"
+	"to_do_api/notify"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListNotifications_UnreadAndPagination(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+	readAt := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&models.Notification{UserID: userID, Type: models.NotificationTypeReminder, Title: "unread"}).Error)
+	}
+	require.NoError(t, db.Create(&models.Notification{UserID: userID, Type: models.NotificationTypeReminder, Title: "read", ReadAt: &readAt}).Error)
+	require.NoError(t, db.Create(&models.Notification{UserID: uuid.New(), Type: models.NotificationTypeReminder, Title: "other"}).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/notifications", controllers.ListNotifications(db))
+
+	req, _ := http.NewRequest("GET", "/notifications?unread=true&page=1&page_size=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Notifications []models.Notification `json:"notifications"`
+		Total         int64                 `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Notifications, 2)
+	assert.Equal(t, int64(3), resp.Total)
+}
+
+func TestMarkNotificationRead_Forbidden(t *testing.T) {
+	db := setupTestReminderDB(t)
+	notification := models.Notification{UserID: uuid.New(), Type: models.NotificationTypeReminder, Title: "x"}
+	require.NoError(t, db.Create(&notification).Error)
+
+	router := newTestTaskRouter(uuid.New().String())
+	router.POST("/notifications/:id/read", controllers.MarkNotificationRead(db))
+
+	req, _ := http.NewRequest("POST", "/notifications/"+notification.ID.String()+"/read", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&models.Notification{UserID: userID, Type: models.NotificationTypeReminder, Title: "x"}).Error)
+	}
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/notifications/read-all", controllers.MarkAllNotificationsRead(db))
+	router.POST("/notifications/:id/read", controllers.MarkNotificationRead(db))
+
+	req, _ := http.NewRequest("POST", "/notifications/read-all", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var unread int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error)
+	assert.Equal(t, int64(0), unread)
+}
+
+func TestNotificationPreferences_DisableType(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+
+	router := newTestTaskRouter(userID.String())
+	router.PUT("/notifications/preferences", controllers.UpdateNotificationPreferences(db))
+
+	body, _ := json.Marshal(map[string]bool{"reminder": false})
+	req, _ := http.NewRequest("PUT", "/notifications/preferences", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var preferences map[string]bool
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preferences))
+	assert.False(t, preferences["reminder"])
+	assert.True(t, preferences["mention"])
+
+	require.NoError(t, notify.Publish(db, models.Notification{UserID: userID, Type: models.NotificationTypeReminder, Title: "muted"}))
+	require.NoError(t, notify.Publish(db, models.Notification{UserID: userID, Type: models.NotificationTypeMention, Title: "kept"}))
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", userID).Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "kept", notifications[0].Title)
+}
+
+func TestNotificationPreferences_UnknownType(t *testing.T) {
+	db := setupTestReminderDB(t)
+
+	router := newTestTaskRouter(uuid.New().String())
+	router.PUT("/notifications/preferences", controllers.UpdateNotificationPreferences(db))
+
+	body, _ := json.Marshal(map[string]bool{"bogus": false})
+	req, _ := http.NewRequest("PUT", "/notifications/preferences", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"to_do_api/outbound"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboundClient_RefusesNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := outbound.NewClient(time.Second)
+	for _, url := range []string{
+		server.URL,
+		"http://10.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://0.0.0.0/",
+		"http://100.64.0.1/",
+	} {
+		_, err := client.Get(url)
+		require.Error(t, err, url)
+		assert.ErrorIs(t, err, outbound.ErrForbiddenAddress, url)
+	}
+}
+
+func TestOutboundClient_DoesNotFollowRedirects(t *testing.T) {
+	client := outbound.NewClient(time.Second)
+	require.NotNil(t, client.CheckRedirect)
+	assert.ErrorIs(t, client.CheckRedirect(nil, nil), http.ErrUseLastResponse)
+}
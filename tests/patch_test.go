@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestPatchRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks/:id", controllers.GetTask(taskService(db)))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
+	router.PATCH("/tasks/:id", controllers.PatchTask(taskService(db), events.NewMemoryBroker()))
+	return router
+}
+
+func createCompletedTask(t *testing.T, db *gorm.DB, userID uuid.UUID) models.Task {
+	due := time.Date(2030, 5, 1, 9, 0, 0, 0, time.UTC)
+	task := models.Task{Title: "Done", Description: "Details", Status: true, DueDate: &due, UserID: userID}
+	require.NoError(t, db.Create(&task).Error)
+	return task
+}
+
+func patchTask(router *gin.Engine, task models.Task, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PATCH", "/tasks/"+task.ID.String(), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetTask(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := createCompletedTask(t, db, userID)
+	router := newTestPatchRouter(db, userID)
+
+	req, _ := http.NewRequest("GET", "/tasks/"+task.ID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+
+	req, _ = http.NewRequest("GET", "/tasks/"+task.ID.String(), nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	other := newTestPatchRouter(db, uuid.New())
+	req, _ = http.NewRequest("GET", "/tasks/"+task.ID.String(), nil)
+	w = httptest.NewRecorder()
+	other.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestPatchTask_MergePatchClearsFields(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := createCompletedTask(t, db, userID)
+	router := newTestPatchRouter(db, userID)
+
+	w := patchTask(router, task, "application/merge-patch+json", `{"status": false, "description": null, "due_date": null}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var stored models.Task
+	require.NoError(t, db.First(&stored, "id = ?", task.ID).Error)
+	assert.Equal(t, "Done", stored.Title)
+	assert.False(t, stored.Status)
+	assert.Empty(t, stored.Description)
+	assert.Nil(t, stored.DueDate)
+	assert.Equal(t, 2, stored.Version)
+}
+
+func TestPatchTask_JSONPatch(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := createCompletedTask(t, db, userID)
+	router := newTestPatchRouter(db, userID)
+
+	w := patchTask(router, task, "application/json-patch+json", `[
+		{"op": "test", "path": "/title", "value": "Done"},
+		{"op": "replace", "path": "/title", "value": "Renamed"},
+		{"op": "remove", "path": "/due_date"}
+	]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var stored models.Task
+	require.NoError(t, db.First(&stored, "id = ?", task.ID).Error)
+	assert.Equal(t, "Renamed", stored.Title)
+	assert.Nil(t, stored.DueDate)
+	assert.True(t, stored.Status)
+
+	w = patchTask(router, task, "application/json-patch+json", `[{"op": "test", "path": "/title", "value": "Done"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestPatchTask_ReadOnlyFields(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := createCompletedTask(t, db, userID)
+	router := newTestPatchRouter(db, userID)
+
+	w := patchTask(router, task, "application/merge-patch+json", `{"user_id": "`+uuid.New().String()+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = patchTask(router, task, "application/json-patch+json", `[{"op": "replace", "path": "/id", "value": "`+uuid.New().String()+`"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = patchTask(router, task, "application/merge-patch+json", `{"title": null}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	require.NoError(t, db.First(&task, "id = ?", task.ID).Error)
+	assert.Equal(t, userID, task.UserID)
+	assert.Equal(t, 1, task.Version)
+}
+
+func TestUpdateTask_FullReplacement(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	task := createCompletedTask(t, db, userID)
+	router := newTestPatchRouter(db, userID)
+
+	body, _ := json.Marshal(map[string]interface{}{"title": "Reopened", "id": uuid.New(), "user_id": uuid.New()})
+	req, _ := http.NewRequest("PUT", "/tasks/"+task.ID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Task
+	require.NoError(t, db.First(&stored, "id = ?", task.ID).Error)
+	assert.Equal(t, "Reopened", stored.Title)
+	assert.False(t, stored.Status)
+	assert.Empty(t, stored.Description)
+	assert.Nil(t, stored.DueDate)
+	assert.Equal(t, userID, stored.UserID)
+}
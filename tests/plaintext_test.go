@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/transfer"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTodoTxt(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	data := []byte("(A) Call mom +family @phone due:2026-10-25 rec:2w\n" +
+		"\n" +
+		"x 2026-10-18 2026-10-01 File taxes +admin pri:B\n" +
+		"Buy milk key:value +one +two\n" +
+		"Water plants due:tomorrow\n")
+
+	rows, problems := transfer.ReadTodoTxt(data, loc)
+	require.Len(t, rows, 3)
+	require.Len(t, problems, 1)
+	assert.Equal(t, 5, problems[0].Line)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "Call mom", rows[0].Title)
+	assert.Equal(t, models.PriorityHigh, rows[0].Priority)
+	assert.Equal(t, "family", rows[0].Project)
+	assert.Equal(t, []string{"phone"}, rows[0].Labels)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2", rows[0].Recurrence)
+	require.NotNil(t, rows[0].DueDate)
+	assert.Equal(t, time.Date(2026, 10, 25, 23, 59, 0, 0, loc), rows[0].DueDate.In(loc))
+
+	assert.Equal(t, 3, rows[1].Line)
+	assert.True(t, rows[1].Status)
+	assert.Equal(t, "File taxes", rows[1].Title)
+	assert.Equal(t, models.PriorityMedium, rows[1].Priority)
+
+	assert.Equal(t, "Buy milk key:value +two", rows[2].Title)
+	assert.Equal(t, "one", rows[2].Project)
+}
+
+func TestTodoTxtLine(t *testing.T) {
+	due := time.Date(2026, 10, 25, 23, 30, 0, 0, time.UTC)
+	task := models.Task{
+		Title:      "Call  mom",
+		Priority:   models.PriorityLow,
+		Project:    "family stuff",
+		Labels:     []string{"phone"},
+		DueDate:    &due,
+		Recurrence: "FREQ=MONTHLY",
+	}
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	assert.Equal(t, "(C) Call mom +family_stuff @phone due:2026-10-26 rec:1m", transfer.TodoTxtLine(task, loc))
+
+	task.Status = true
+	task.Recurrence = "FREQ=WEEKLY;BYDAY=MO"
+	assert.Equal(t, "x Call mom +family_stuff @phone due:2026-10-25 pri:C", transfer.TodoTxtLine(task, time.UTC))
+}
+
+func TestTodoTxtLine_RoundTripsSpecialWords(t *testing.T) {
+	for _, tt := range []struct {
+		title string
+		line  string
+	}{
+		{"x marks the spot", `\x marks the spot`},
+		{"(A) grade papers", `\(A) grade papers`},
+		{"2026-10-19 retrospective", `\2026-10-19 retrospective`},
+		{"Call +1 555 1234", `Call \+1 555 1234`},
+		{"Email @bob", `Email \@bob`},
+		{"due:tomorrow", `\due:tomorrow`},
+		{`Escape \+ and \n`, `Escape \\+ and \\n`},
+		{"Read https://example.com", "Read https://example.com"},
+	} {
+		for _, task := range []models.Task{
+			{Title: tt.title},
+			{Title: tt.title, Priority: models.PriorityHigh},
+			{Title: tt.title, Status: true},
+		} {
+			line := transfer.TodoTxtLine(task, time.UTC)
+			if task.Priority == models.PriorityNone && !task.Status {
+				assert.Equal(t, tt.line, line)
+			}
+
+			rows, problems := transfer.ReadTodoTxt([]byte(line+"\n"), time.UTC)
+			require.Empty(t, problems, line)
+			require.Len(t, rows, 1, line)
+			assert.Equal(t, tt.title, rows[0].Title, line)
+			assert.Equal(t, task.Status, rows[0].Status, line)
+			assert.Equal(t, task.Priority, rows[0].Priority, line)
+			assert.Empty(t, rows[0].Project, line)
+			assert.Empty(t, rows[0].Labels, line)
+			assert.Nil(t, rows[0].DueDate, line)
+		}
+	}
+}
+
+func TestReadMarkdown(t *testing.T) {
+	data := []byte("# Trip\n" +
+		"\n" +
+		"- [ ] Plan trip\n" +
+		"  Book before the end of the month.\n" +
+		"    Indented detail.\n" +
+		"  - [x] Book flights\n" +
+		"  * [X] Book hotel\n" +
+		"    - [ ] Ask about parking\n" +
+		"- [ ] Pack\n" +
+		"  \\- [ ] not a task\n" +
+		"Some closing prose.\n")
+
+	rows, problems := transfer.ReadMarkdown(data)
+	assert.Empty(t, problems)
+	require.Len(t, rows, 5)
+
+	assert.Equal(t, "Plan trip", rows[0].Title)
+	assert.Equal(t, "Book before the end of the month.\n  Indented detail.", rows[0].Description)
+	assert.Zero(t, rows[0].ParentLine)
+
+	assert.Equal(t, "Book flights", rows[1].Title)
+	assert.True(t, rows[1].Status)
+	assert.Equal(t, rows[0].Line, rows[1].ParentLine)
+
+	assert.Equal(t, "Book hotel", rows[2].Title)
+	assert.True(t, rows[2].Status)
+	assert.Equal(t, rows[0].Line, rows[2].ParentLine)
+
+	assert.Equal(t, "Ask about parking", rows[3].Title)
+	assert.Equal(t, rows[2].Line, rows[3].ParentLine)
+
+	assert.Equal(t, "Pack", rows[4].Title)
+	assert.Equal(t, "- [ ] not a task", rows[4].Description)
+	assert.Zero(t, rows[4].ParentLine)
+}
+
+func TestImportTasks_MarkdownSubtasksRoundTrip(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+
+	checklist := "- [ ] Plan trip\n" +
+		"  Book before the end of the month.\n" +
+		"  - [x] Book flights\n" +
+		"  - [ ] Book hotel\n" +
+		"    - [ ] Ask about parking\n" +
+		"- [ ] Pack\n"
+
+	w := uploadFile(t, router, "/tasks/import", "trip.md", checklist, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var report importResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 5, report.Created)
+
+	var tasks []models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).Find(&tasks).Error)
+	byTitle := map[string]models.Task{}
+	for _, task := range tasks {
+		byTitle[task.Title] = task
+	}
+	assert.Nil(t, byTitle["Plan trip"].ParentID)
+	require.NotNil(t, byTitle["Book flights"].ParentID)
+	assert.Equal(t, byTitle["Plan trip"].ID, *byTitle["Book flights"].ParentID)
+	require.NotNil(t, byTitle["Ask about parking"].ParentID)
+	assert.Equal(t, byTitle["Book hotel"].ID, *byTitle["Ask about parking"].ParentID)
+	assert.True(t, byTitle["Book flights"].Status)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=md&sort=created_at&order=asc", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "tasks.md")
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/markdown")
+	assert.Equal(t, checklist, w.Body.String())
+}
+
+func TestImportTasks_TodoTxt(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+
+	w := uploadFile(t, router, "/tasks/import", "todo.txt", "(B) Call mom +family @phone due:2026-10-25\nx Take out trash\n", map[string]string{"timezone": "America/New_York"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var task models.Task
+	require.NoError(t, db.Where("user_id = ? AND title = ?", userID, "Call mom").First(&task).Error)
+	assert.Equal(t, models.PriorityMedium, task.Priority)
+	assert.Equal(t, "family", task.Project)
+	assert.Equal(t, []string{"phone"}, task.Labels)
+	ny, _ := time.LoadLocation("America/New_York")
+	require.NotNil(t, task.DueDate)
+	assert.Equal(t, time.Date(2026, 10, 25, 23, 59, 0, 0, ny).Unix(), task.DueDate.Unix())
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=todotxt&timezone=America/New_York&status=false", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "todo.txt")
+	assert.Equal(t, "(B) Call mom +family @phone due:2026-10-25\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/tasks/export?format=todotxt&timezone=Mars/Olympus", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = uploadFile(t, router, "/tasks/import", "todo.txt", "Fine\nBad due:someday\n", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestImportTasks_CSVParentCycle(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+
+	csvData := "id,title,parent_id\na,First,b\nb,Second,a\n"
+	w := uploadFile(t, router, "/tasks/import", "tasks.csv", csvData, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "cycle")
+	assert.Zero(t, countTasks(db, userID))
+}
+
+func TestSubtasks_ParentValidation(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestTaskRouter(userID.String())
+	broker := events.NewMemoryBroker()
+	router.POST("/tasks", controllers.CreateTask(taskService(db), broker))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), broker))
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), broker))
+
+	create := func(body map[string]interface{}) (*httptest.ResponseRecorder, models.Task) {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var task models.Task
+		json.Unmarshal(w.Body.Bytes(), &task)
+		return w, task
+	}
+
+	_, parent := create(map[string]interface{}{"title": "Parent"})
+	w, child := create(map[string]interface{}{"title": "Child", "parent_id": parent.ID})
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, parent.ID, *child.ParentID)
+
+	w, _ = create(map[string]interface{}{"title": "Orphan", "parent_id": uuid.New()})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	other := models.Task{Title: "Not mine", UserID: uuid.New()}
+	require.NoError(t, db.Create(&other).Error)
+	w, _ = create(map[string]interface{}{"title": "Sneaky", "parent_id": other.ID})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	payload, _ := json.Marshal(map[string]interface{}{"title": "Parent", "parent_id": child.ID})
+	req, _ := http.NewRequest("PUT", "/tasks/"+parent.ID.String(), bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// A task with subtasks cannot be deleted until they are.
+	remove := func(task models.Task) int {
+		req, _ := http.NewRequest("DELETE", "/tasks/"+task.ID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusConflict, remove(parent))
+	assert.Equal(t, int64(2), countTasks(db, userID))
+	assert.Equal(t, http.StatusOK, remove(child))
+	assert.Equal(t, http.StatusOK, remove(parent))
+	assert.Zero(t, countTasks(db, userID))
+}
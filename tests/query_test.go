@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/models"
+	"to_do_api/taskquery"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTasks_Query(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	now := time.Now().UTC()
+	soon := now.Add(2 * 24 * time.Hour)
+	later := now.Add(30 * 24 * time.Hour)
+	past := now.Add(-24 * time.Hour)
+	for _, task := range []models.Task{
+		{Title: "Write report", Description: "For work", UserID: userID, DueDate: &soon},
+		{Title: "Plan holiday", UserID: userID, DueDate: &later},
+		{Title: "Pay rent", UserID: userID, DueDate: &past},
+		{Title: "Old report", Description: "For work", Status: true, UserID: userID, DueDate: &soon},
+		{Title: "Someday", UserID: userID},
+	} {
+		require.NoError(t, db.Create(&task).Error)
+	}
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	titles := func(q string) []string {
+		req, _ := http.NewRequest("GET", "/tasks?q="+url.QueryEscape(q), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, q)
+
+		var tasks []models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Write report", "Pay rent"}, titles("due:<7d AND NOT status:done"))
+	assert.Equal(t, []string{"Write report", "Old report"}, titles("due:7d"))
+	assert.Equal(t, []string{"Pay rent"}, titles("due:overdue"))
+	assert.Equal(t, []string{"Someday"}, titles("due:none"))
+	assert.Equal(t, []string{"Write report", "Old report"}, titles(`description:work title:"report"`))
+	assert.Equal(t, []string{"Plan holiday", "Pay rent", "Someday"}, titles("NOT due:<7d OR due:<0h status:open"))
+	assert.Equal(t, []string{"Plan holiday", "Someday"}, titles("NOT (report OR rent)"))
+	assert.Equal(t, []string{"Write report"}, titles("report status:false"))
+}
+
+func TestListTasks_QueryIsParameterized(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Safe", UserID: userID}).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	for _, q := range []string{`title:"x') OR 1=1 --"`, `"%"`, `title:_`} {
+		req, _ := http.NewRequest("GET", "/tasks?q="+url.QueryEscape(q), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, q)
+		assert.JSONEq(t, "[]", w.Body.String(), q)
+	}
+
+	var count int64
+	db.Model(&models.Task{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTaskQuery_ParseErrors(t *testing.T) {
+	for _, q := range []string{
+		"",
+		"status:maybe",
+		"assignee:me",
+		"priority:urgent",
+		"label:<work",
+		"due:<none",
+		"created:overdue",
+		"due:soon",
+		"title:<x",
+		"(status:done",
+		"status:done)",
+		"AND status:done",
+		`title:"unterminated`,
+		"title:",
+	} {
+		_, err := taskquery.Parse(q)
+		assert.Error(t, err, q)
+	}
+
+	_, err := taskquery.Parse("due:>=2026-01-01 AND updated:<2026-01-01T10:00:00Z")
+	assert.NoError(t, err)
+}
+
+func TestTaskQuery_MultiByteWords(t *testing.T) {
+	now := time.Now().UTC()
+	task := models.Task{Title: "Voilà le café", Description: "Åland"}
+
+	// "à" ends in the byte 0xA0 and "Å" in 0x85, which are spaces when read
+	// as Latin-1.
+	for _, q := range []string{"voilà", "title:café", "åland", "voilà\u3000café"} {
+		node, err := taskquery.Parse(q)
+		require.NoError(t, err, q)
+		assert.True(t, taskquery.Match(node, task, now), q)
+	}
+
+	node, err := taskquery.Parse("voil")
+	require.NoError(t, err)
+	assert.True(t, taskquery.Match(node, task, now))
+	node, err = taskquery.Parse("voilà\u3000thé")
+	require.NoError(t, err)
+	assert.False(t, taskquery.Match(node, task, now), "an ideographic space separates terms")
+}
+
+func TestListTasks_QueryLabelsProjectPriority(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	for _, task := range []models.Task{
+		{Title: "Deploy", UserID: userID, Labels: []string{"work", "urgent"}, Project: "Ops", Priority: models.PriorityHigh},
+		{Title: "Review", UserID: userID, Labels: []string{"work_item"}, Project: "ops", Priority: models.PriorityLow},
+		{Title: "Garden", UserID: userID, Labels: []string{}},
+	} {
+		require.NoError(t, db.Create(&task).Error)
+	}
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	titles := func(q string) []string {
+		req, _ := http.NewRequest("GET", "/tasks?q="+url.QueryEscape(q), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, q)
+
+		var tasks []models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Deploy"}, titles("label:work"))
+	assert.Equal(t, []string{"Deploy"}, titles("label:#URGENT"))
+	assert.Equal(t, []string{"Garden"}, titles("label:none"))
+	assert.Equal(t, []string{"Deploy", "Review"}, titles("project:OPS"))
+	assert.Equal(t, []string{"Garden"}, titles("project:none"))
+	assert.Equal(t, []string{"Deploy"}, titles("priority:high"))
+	assert.Equal(t, []string{"Deploy", "Review"}, titles("priority:>=1"))
+	assert.Equal(t, []string{"Review", "Garden"}, titles("NOT priority:>medium"))
+}
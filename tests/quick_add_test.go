@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/quickadd"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickAddParse(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// A Wednesday morning.
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, newYork)
+	utc := func(year int, month time.Month, day, hour, minute int) *time.Time {
+		t := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		text     string
+		expected quickadd.Result
+	}{
+		{
+			text: "Call mom tomorrow 5pm #family !high every sunday",
+			expected: quickadd.Result{
+				// The explicit date wins over the first Sunday.
+				Title: "Call mom", DueDate: utc(2026, 10, 22, 21, 0), HasTime: true,
+				Priority: models.PriorityHigh, Labels: []string{"family"}, Recurrence: "FREQ=WEEKLY;BYDAY=SU",
+			},
+		},
+		{
+			text:     "Pay rent on oct 1 +home",
+			expected: quickadd.Result{Title: "Pay rent", DueDate: utc(2027, 10, 2, 3, 59), Project: "home", Labels: []string{}},
+		},
+		{
+			text: "Standup at 9am every weekday",
+			expected: quickadd.Result{
+				Title: "Standup", DueDate: utc(2026, 10, 22, 13, 0), HasTime: true,
+				Labels: []string{}, Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			},
+		},
+		{
+			text:     `Submit "next week" report by friday @work @WORK !!`,
+			expected: quickadd.Result{Title: "Submit next week report", DueDate: utc(2026, 10, 24, 3, 59), Priority: models.PriorityMedium, Labels: []string{"work"}},
+		},
+		{
+			text:     "Lunch noon",
+			expected: quickadd.Result{Title: "Lunch", DueDate: utc(2026, 10, 21, 16, 0), HasTime: true, Labels: []string{}},
+		},
+		{
+			text:     "Coffee 9:30 am",
+			expected: quickadd.Result{Title: "Coffee", DueDate: utc(2026, 10, 22, 13, 30), HasTime: true, Labels: []string{}},
+		},
+		{
+			text:     "Check oven in 2 hours",
+			expected: quickadd.Result{Title: "Check oven", DueDate: utc(2026, 10, 21, 16, 0), HasTime: true, Labels: []string{}},
+		},
+		{
+			text:     "Water plants every other week",
+			expected: quickadd.Result{Title: "Water plants", DueDate: utc(2026, 10, 22, 3, 59), Labels: []string{}, Recurrence: "FREQ=WEEKLY;INTERVAL=2"},
+		},
+		{
+			text:     "Renew passport 3rd of march 2028",
+			expected: quickadd.Result{Title: "Renew passport", DueDate: utc(2028, 3, 4, 4, 59), Labels: []string{}},
+		},
+		{
+			text:     "Fix the sat nav",
+			expected: quickadd.Result{Title: "Fix the sat nav", Labels: []string{}},
+		},
+		{
+			text:     "Email Sun Microsystems",
+			expected: quickadd.Result{Title: "Email Sun Microsystems", Labels: []string{}},
+		},
+		{
+			text:     "Buy wed cake",
+			expected: quickadd.Result{Title: "Buy wed cake", Labels: []string{}},
+		},
+		{
+			text:     "Dentist on wed",
+			expected: quickadd.Result{Title: "Dentist", DueDate: utc(2026, 10, 22, 3, 59), Labels: []string{}},
+		},
+		{
+			text:     "Order cake next sat",
+			expected: quickadd.Result{Title: "Order cake", DueDate: utc(2026, 10, 25, 3, 59), Labels: []string{}},
+		},
+		{
+			text:     "Gym every mon and thu",
+			expected: quickadd.Result{Title: "Gym", DueDate: utc(2026, 10, 23, 3, 59), Labels: []string{}, Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		},
+		{
+			text:     "Work on report at home",
+			expected: quickadd.Result{Title: "Work on report at home", Labels: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			result := quickadd.Parse(tt.text, now)
+			result.Spans = nil
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestQuickAddParse_Spans(t *testing.T) {
+	text := "Call mom tomorrow at 5pm #family"
+	result := quickadd.Parse(text, time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, []quickadd.Span{
+		{Kind: quickadd.KindDate, Text: "tomorrow", Start: 9, End: 17},
+		{Kind: quickadd.KindTime, Text: "at 5pm", Start: 18, End: 24},
+		{Kind: quickadd.KindLabel, Text: "#family", Start: 25, End: 32},
+	}, result.Spans)
+}
+
+func TestQuickAddTask(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks/quick", controllers.QuickAddTask(taskService(db), events.NewMemoryBroker()))
+
+	post := func(body gin.H) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/tasks/quick", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(gin.H{"text": "Call mom every sunday 5pm #family", "timezone": "Europe/Berlin", "preview": true})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), countTasks(db, userID), "preview must not create a task")
+
+	w = post(gin.H{"text": "Call mom every sunday 5pm #family +Home !high", "timezone": "Europe/Berlin"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	var resp struct {
+		Parsed quickadd.Result `json:"parsed"`
+		Task   models.Task     `json:"task"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	var stored models.Task
+	require.NoError(t, db.First(&stored, resp.Task.ID).Error)
+	assert.Equal(t, "Call mom", stored.Title)
+	assert.Equal(t, []string{"family"}, stored.Labels)
+	assert.Equal(t, "Home", stored.Project)
+	assert.Equal(t, models.PriorityHigh, stored.Priority)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=SU", stored.Recurrence)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	require.NotNil(t, stored.DueDate)
+	due := stored.DueDate.In(berlin)
+	assert.Equal(t, time.Sunday, due.Weekday())
+	assert.Equal(t, 17, due.Hour())
+
+	assert.Equal(t, http.StatusBadRequest, post(gin.H{"text": "x", "timezone": "Mars/Olympus"}).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, post(gin.H{"text": "tomorrow #work"}).Code)
+}
+
+func TestCreateTask_ValidatesNewFields(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
+
+	post := func(body gin.H) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(gin.H{"title": "Tidy", "labels": []string{"#Home", "home", "chores"}, "recurrence": "freq=weekly;byday=sa,mo"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var task models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	assert.Equal(t, []string{"home", "chores"}, task.Labels)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,SA", task.Recurrence)
+
+	assert.Equal(t, http.StatusBadRequest, post(gin.H{"title": "x", "priority": 7}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(gin.H{"title": "x", "labels": []string{"two words"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(gin.H{"title": "x", "recurrence": "FREQ=HOURLY"}).Code)
+}
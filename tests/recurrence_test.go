@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"to_do_api/recurrence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// occurrences steps through the series of rule that starts at start.
+func occurrences(t *testing.T, value string, start time.Time, n int) []string {
+	rule, err := recurrence.Parse(value)
+	require.NoError(t, err)
+
+	var dates []string
+	for at := start; len(dates) < n; {
+		at = rule.Next(start, at)
+		dates = append(dates, at.Format("2006-01-02 15:04"))
+	}
+	return dates
+}
+
+func TestRecurrence_MonthEndDoesNotDrift(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2025-02-28 09:00", "2025-03-31 09:00", "2025-04-30 09:00", "2025-05-31 09:00"},
+		occurrences(t, "FREQ=MONTHLY", start, 4))
+	assert.Equal(t, []string{"2025-03-31 09:00", "2025-05-31 09:00", "2025-07-31 09:00"},
+		occurrences(t, "FREQ=MONTHLY;INTERVAL=2", start, 3))
+}
+
+func TestRecurrence_LeapDayYearly(t *testing.T) {
+	start := time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2025-02-28 09:00", "2026-02-28 09:00", "2027-02-28 09:00", "2028-02-29 09:00"},
+		occurrences(t, "FREQ=YEARLY", start, 4))
+}
+
+func TestRecurrence_DailyAndWeekly(t *testing.T) {
+	// A Friday.
+	start := time.Date(2026, 10, 16, 18, 30, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2026-10-19 18:30", "2026-10-22 18:30"}, occurrences(t, "FREQ=DAILY;INTERVAL=3", start, 2))
+	assert.Equal(t, []string{"2026-10-30 18:30", "2026-11-13 18:30"}, occurrences(t, "FREQ=WEEKLY;INTERVAL=2", start, 2))
+	assert.Equal(t, []string{"2026-10-17 18:30", "2026-10-26 18:30", "2026-10-31 18:30", "2026-11-09 18:30"},
+		occurrences(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SA", start, 4))
+
+	// Asked from between occurrences, Next gives the one after.
+	rule, err := recurrence.Parse("FREQ=MONTHLY")
+	require.NoError(t, err)
+	monthEnd := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC), rule.Next(monthEnd, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,358 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"to_do_api/controllers"
+	"to_do_api/database"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/notify"
+	"to_do_api/scheduler"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type recordingChannel struct {
+	sent []notify.Message
+	err  error
+}
+
+func (r *recordingChannel) Send(ctx context.Context, msg notify.Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func setupTestReminderDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	return db
+}
+
+func TestCreateReminder_Offset(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+	due := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	task := models.Task{Title: "Pay rent", UserID: userID, DueDate: &due}
+	require.NoError(t, db.Create(&task).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks/:id/reminders", controllers.CreateReminder(db, taskService(db)))
+
+	body, _ := json.Marshal(map[string]interface{}{"offset_minutes": 30, "channel": "in_app"})
+	req, _ := http.NewRequest("POST", "/tasks/"+task.ID.String()+"/reminders", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var reminder models.Reminder
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reminder))
+	assert.True(t, due.Add(-30*time.Minute).Equal(reminder.FireAt))
+}
+
+func TestCreateReminder_OffsetWithoutDueDate(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+	task := models.Task{Title: "Someday", UserID: userID}
+	require.NoError(t, db.Create(&task).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks/:id/reminders", controllers.CreateReminder(db, taskService(db)))
+
+	body, _ := json.Marshal(map[string]interface{}{"offset_minutes": 30, "channel": "email"})
+	req, _ := http.NewRequest("POST", "/tasks/"+task.ID.String()+"/reminders", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateTask_ReschedulesOffsetReminders(t *testing.T) {
+	db := setupTestReminderDB(t)
+	userID := uuid.New()
+	due := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	task := models.Task{Title: "Pay rent", UserID: userID, DueDate: &due}
+	require.NoError(t, db.Create(&task).Error)
+	offset := 60
+	reminder := models.Reminder{TaskID: task.ID, UserID: userID, OffsetMinutes: &offset, FireAt: due.Add(-time.Hour), Channel: models.ReminderChannelInApp}
+	require.NoError(t, db.Create(&reminder).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
+
+	newDue := due.Add(24 * time.Hour)
+	body, _ := json.Marshal(map[string]interface{}{"title": "Pay rent", "due_date": newDue})
+	req, _ := http.NewRequest("PUT", "/tasks/"+task.ID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, db.First(&reminder, "id = ?", reminder.ID).Error)
+	assert.True(t, newDue.Add(-time.Hour).Equal(reminder.FireAt))
+}
+
+func TestReminderScheduler_FiresOnce(t *testing.T) {
+	db := setupTestReminderDB(t)
+	user := models.User{Email: "remind@example.com", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	task := models.Task{Title: "Call mom", UserID: user.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := models.Reminder{TaskID: task.ID, UserID: user.ID, FireAt: now.Add(-time.Minute), Channel: "test"}
+	later := models.Reminder{TaskID: task.ID, UserID: user.ID, FireAt: now.Add(time.Hour), Channel: "test"}
+	require.NoError(t, db.Create(&due).Error)
+	require.NoError(t, db.Create(&later).Error)
+
+	channel := &recordingChannel{}
+	s := scheduler.NewReminderScheduler(db, map[string]notify.Channel{"test": channel})
+	s.Now = func() time.Time { return now }
+
+	sent, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	sent, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	require.Len(t, channel.sent, 1)
+	assert.Equal(t, "Reminder: Call mom", channel.sent[0].Subject)
+
+	require.NoError(t, db.First(&due, "id = ?", due.ID).Error)
+	assert.Equal(t, models.ReminderStatusSent, due.Status)
+	assert.NotNil(t, due.SentAt)
+}
+
+func TestReminderScheduler_RetriesAfterLease(t *testing.T) {
+	db := setupTestReminderDB(t)
+	user := models.User{Email: "retry@example.com", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	task := models.Task{Title: "Flaky", UserID: user.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	reminder := models.Reminder{TaskID: task.ID, UserID: user.ID, FireAt: now, Channel: "test"}
+	require.NoError(t, db.Create(&reminder).Error)
+
+	channel := &recordingChannel{err: errors.New("smtp down")}
+	s := scheduler.NewReminderScheduler(db, map[string]notify.Channel{"test": channel})
+	s.Now = func() time.Time { return now }
+
+	sent, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	channel.err = nil
+	sent, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "retry must wait for the lease to expire")
+
+	s.Now = func() time.Time { return now.Add(s.Lease + time.Second) }
+	sent, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestInAppChannel_StoresNotification(t *testing.T) {
+	db := setupTestReminderDB(t)
+	user := models.User{Email: "inbox@example.com", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+
+	channel := notify.NewInAppChannel(db)
+	err := channel.Send(context.Background(), notify.Message{User: user, Task: models.Task{ID: uuid.New()}, Subject: "Reminder: x"})
+	require.NoError(t, err)
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "reminder", notifications[0].Type)
+}
+
+func TestReminderScheduler_DoesNotResendWhenRecordingFails(t *testing.T) {
+	db := setupTestReminderDB(t)
+	user := models.User{Email: "record@example.com", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	task := models.Task{Title: "Water plants", UserID: user.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	reminder := models.Reminder{TaskID: task.ID, UserID: user.ID, FireAt: now, Channel: "test"}
+	require.NoError(t, db.Create(&reminder).Error)
+
+	// The first attempt to mark the reminder sent fails.
+	failures := 1
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_sent", func(tx *gorm.DB) {
+		if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok && updates["status"] == models.ReminderStatusSent && failures > 0 {
+			failures--
+			tx.AddError(errors.New("connection reset"))
+		}
+	}))
+
+	channel := &recordingChannel{}
+	s := scheduler.NewReminderScheduler(db, map[string]notify.Channel{"test": channel})
+	s.Now = func() time.Time { return now }
+
+	sent, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.NoError(t, db.First(&reminder, "id = ?", reminder.ID).Error)
+	assert.Equal(t, models.ReminderStatusSent, reminder.Status)
+	assert.Empty(t, reminder.LastError)
+
+	s.Now = func() time.Time { return now.Add(s.Lease + time.Second) }
+	sent, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, channel.sent, 1)
+}
+
+func TestEmailChannel_KeepsSubjectOnOneLine(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost\r\n")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				received <- data.String()
+				fmt.Fprint(conn, "250 queued\r\n")
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	channel := &notify.EmailChannel{Host: host, Port: port, From: "todo@example.com"}
+	err = channel.Send(context.Background(), notify.Message{
+		User:    models.User{Email: "ada@example.com"},
+		Subject: "Reminder: Café\r\nBcc: victim@example.com\r\n\r\nInjected",
+		Body:    "Hello",
+	})
+	require.NoError(t, err)
+
+	message := <-received
+	header, body, _ := strings.Cut(message, "\r\n\r\n")
+	assert.NotContains(t, header, "\r\nBcc:")
+	assert.Contains(t, header, "Subject: =?UTF-8?q?Reminder:_Caf=C3=A9_Bcc:_victim@example.com_Injected?=")
+	assert.Equal(t, "Hello\r\n", body)
+}
+
+func TestEmailChannel_GivesUpOnStalledServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// The server accepts the connection but never greets.
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	channel := &notify.EmailChannel{Host: host, Port: port, From: "todo@example.com"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = channel.Send(ctx, notify.Message{User: models.User{Email: "ada@example.com"}, Subject: "Reminder", Body: "Hello"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+// leaseCheckingChannel records, for each reminder it sends, whether the
+// claim's lease still runs, and lets the clock pass a whole lease per send.
+type leaseCheckingChannel struct {
+	db       *gorm.DB
+	now      *time.Time
+	lease    time.Duration
+	leaseRan []bool
+}
+
+func (c *leaseCheckingChannel) Send(ctx context.Context, msg notify.Message) error {
+	var reminder models.Reminder
+	if err := c.db.First(&reminder, "id = ?", msg.Reminder.ID).Error; err != nil {
+		return err
+	}
+	c.leaseRan = append(c.leaseRan, reminder.LockedUntil != nil && reminder.LockedUntil.After(*c.now))
+	*c.now = c.now.Add(c.lease)
+	return nil
+}
+
+func TestReminderScheduler_LeaseStartsAtClaim(t *testing.T) {
+	db := setupTestReminderDB(t)
+	user := models.User{Email: "lease@example.com", Password: "x"}
+	require.NoError(t, db.Create(&user).Error)
+	task := models.Task{Title: "Slow batch", UserID: user.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		reminder := models.Reminder{TaskID: task.ID, UserID: user.ID, FireAt: now.Add(-time.Duration(2-i) * time.Minute), Channel: "test"}
+		require.NoError(t, db.Create(&reminder).Error)
+	}
+
+	s := scheduler.NewReminderScheduler(db, nil)
+	channel := &leaseCheckingChannel{db: db, now: &now, lease: s.Lease}
+	s.Channels = map[string]notify.Channel{"test": channel}
+	s.Now = func() time.Time { return now }
+
+	sent, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Equal(t, []bool{true, true}, channel.leaseRan, "the second reminder's lease must not have run out before its send")
+}
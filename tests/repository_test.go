@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+	"to_do_api/repository"
+	"to_do_api/repository/repositorytest"
+)
+
+func TestGormTaskRepository(t *testing.T) {
+	repositorytest.TestTaskRepository(t, func(t *testing.T) repository.TaskRepository {
+		return repository.NewGormTaskRepository(setupTestTaskDB(t))
+	})
+}
+
+func TestGormUserRepository(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		return repository.NewGormUserRepository(setupTestTaskDB(t))
+	})
+}
+
+func TestMemoryTaskRepository(t *testing.T) {
+	repositorytest.TestTaskRepository(t, func(t *testing.T) repository.TaskRepository {
+		return repository.NewMemoryTaskRepository()
+	})
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+		return repository.NewMemoryUserRepository()
+	})
+}
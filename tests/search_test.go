@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"to_do_api/controllers"
+	"to_do_api/models"
+	"to_do_api/search"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type searchResponse struct {
+	Results []search.Result `json:"results"`
+}
+
+func runSearch(t *testing.T, db *gorm.DB, userID uuid.UUID, params url.Values) (int, searchResponse) {
+	router := newTestTaskRouter(userID.String())
+	router.GET("/search", controllers.SearchTasks(db))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?"+params.Encode(), nil)
+	router.ServeHTTP(w, req)
+
+	var resp searchResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestSearchTasks_RanksAndHighlights(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Groceries", Description: "Buy milk and bread", UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Milk the cows", Description: "Farm chores", UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Laundry", UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Milk", UserID: uuid.New()}).Error)
+
+	code, resp := runSearch(t, db, userID, url.Values{"q": {"milk"}})
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "Milk the cows", resp.Results[0].Task.Title, "title matches rank first")
+	assert.Contains(t, resp.Results[0].Snippet, "<mark>Milk</mark>")
+	assert.Contains(t, resp.Results[1].Snippet, "Buy <mark>milk</mark> and bread")
+}
+
+func TestSearchTasks_PrefixAndAllTerms(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Team meeting", Description: "Quarterly planning", UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Meet Alex", UserID: userID}).Error)
+
+	_, resp := runSearch(t, db, userID, url.Values{"q": {"meet"}})
+	assert.Len(t, resp.Results, 2)
+
+	_, resp = runSearch(t, db, userID, url.Values{"q": {"meet plan"}})
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "Team meeting", resp.Results[0].Task.Title)
+}
+
+func TestSearchTasks_CombinesWithFilters(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Report draft", UserID: userID}).Error)
+	done := models.Task{Title: "Report final", Status: true, UserID: userID}
+	require.NoError(t, db.Create(&done).Error)
+
+	code, resp := runSearch(t, db, userID, url.Values{"q": {"report"}, "status": {"true"}})
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, done.ID, resp.Results[0].Task.ID)
+
+	code, _ = runSearch(t, db, userID, url.Values{"q": {"report"}, "status": {"maybe"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSearchTasks_CombinesWithTaskQuery(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	urgent := models.Task{Title: "Report draft", Priority: models.PriorityHigh, Labels: []string{"work"}, UserID: userID}
+	require.NoError(t, db.Create(&urgent).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Report final", Labels: []string{"work"}, UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Report card", Priority: models.PriorityHigh, UserID: userID}).Error)
+
+	code, resp := runSearch(t, db, userID, url.Values{"q": {"repo priority:high label:work"}})
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, urgent.ID, resp.Results[0].Task.ID)
+	assert.Contains(t, resp.Results[0].Snippet, "<mark>Repo")
+
+	// A query needs words to search for, and must parse.
+	for _, q := range []string{"priority:high", "NOT report", "report OR memo", "report AND (", "colour:red report"} {
+		code, _ = runSearch(t, db, userID, url.Values{"q": {q}})
+		assert.Equal(t, http.StatusBadRequest, code, q)
+	}
+}
+
+func TestSearchTasks_RejectsEmptyQuery(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "Anything", UserID: userID}).Error)
+
+	code, _ := runSearch(t, db, userID, url.Values{"q": {"  %_  "}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := runSearch(t, db, userID, url.Values{"q": {"nothing"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Results)
+}
+
+func TestSearchTasks_SnippetsAreEscaped(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: `<img src=x onerror="alert(1)"> milk`, UserID: userID}).Error)
+
+	_, resp := runSearch(t, db, userID, url.Values{"q": {"milk"}})
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>milk</mark>", resp.Results[0].Snippet)
+}
+
+func TestSearchTasks_SnippetsFoldCase(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	// The Kelvin sign lower-cases to a one-byte "k", so offsets into the
+	// lower-cased text do not line up with the original.
+	require.NoError(t, db.Create(&models.Task{Title: strings.Repeat("\u212A", 30) + " kelvin scale", UserID: userID}).Error)
+
+	_, resp := runSearch(t, db, userID, url.Values{"q": {"kelvin"}})
+	require.Len(t, resp.Results, 1)
+	assert.Contains(t, resp.Results[0].Snippet, "<mark>kelvin</mark> scale")
+
+	other := uuid.New()
+	require.NoError(t, db.Create(&models.Task{Title: "\u212Aelvin", Description: "kelvin", UserID: other}).Error)
+	_, resp = runSearch(t, db, other, url.Values{"q": {"kelvin"}})
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "<mark>\u212Aelvin</mark> — <mark>kelvin</mark>", resp.Results[0].Snippet)
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"to_do_api/repository"
+	"to_do_api/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_Ownership(t *testing.T) {
+	ctx := context.Background()
+	tasks := service.NewTaskService(repository.NewMemoryTaskRepository())
+	owner, stranger := uuid.New(), uuid.New()
+
+	task, recorded, err := tasks.Create(ctx, owner, service.TaskInput{Title: "Write report", Labels: []string{"#Work"}})
+	require.NoError(t, err)
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, []string{"work"}, task.Labels)
+
+	_, err = tasks.Get(ctx, stranger, task.ID)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = tasks.Get(ctx, owner, uuid.New())
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	_, _, err = tasks.Update(ctx, stranger, task, service.TaskInput{Title: "Mine now"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = tasks.Delete(ctx, stranger, task)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	listed, err := tasks.List(ctx, stranger, repository.TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	found, err := tasks.Get(ctx, owner, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Write report", found.Title)
+}
+
+func TestTaskService_Validation(t *testing.T) {
+	ctx := context.Background()
+	tasks := service.NewTaskService(repository.NewMemoryTaskRepository())
+	userID := uuid.New()
+
+	for _, input := range []service.TaskInput{
+		{Title: "   "},
+		{Title: "Too urgent", Priority: 7},
+		{Title: "Bad label", Labels: []string{"two words"}},
+		{Title: "Bad rule", Recurrence: "FREQ=SOMETIMES"},
+	} {
+		_, _, err := tasks.Create(ctx, userID, input)
+		var invalid service.ValidationError
+		assert.ErrorAs(t, err, &invalid, "%+v", input)
+	}
+
+	task, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Valid"})
+	require.NoError(t, err)
+	_, _, err = tasks.Update(ctx, userID, task, service.TaskInput{Title: ""})
+	var invalid service.ValidationError
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestTaskService_Parents(t *testing.T) {
+	ctx := context.Background()
+	tasks := service.NewTaskService(repository.NewMemoryTaskRepository())
+	userID := uuid.New()
+
+	parent, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Launch"})
+	require.NoError(t, err)
+	child, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: "Draft", ParentID: &parent.ID})
+	require.NoError(t, err)
+
+	// Nesting a task under its own subtask would create a cycle.
+	_, _, err = tasks.Update(ctx, userID, parent, service.TaskInput{Title: "Launch", ParentID: &child.ID})
+	assert.ErrorIs(t, err, service.ErrInvalidParent)
+
+	foreign, _, err := tasks.Create(ctx, uuid.New(), service.TaskInput{Title: "Someone else's"})
+	require.NoError(t, err)
+	_, _, err = tasks.Create(ctx, userID, service.TaskInput{Title: "Sneaky", ParentID: &foreign.ID})
+	assert.ErrorIs(t, err, service.ErrInvalidParent)
+
+	missing := uuid.New()
+	_, _, err = tasks.Create(ctx, userID, service.TaskInput{Title: "Orphan", ParentID: &missing})
+	assert.ErrorIs(t, err, service.ErrInvalidParent)
+
+	_, err = tasks.Delete(ctx, userID, parent)
+	assert.ErrorIs(t, err, service.ErrHasSubtasks)
+	_, err = tasks.Delete(ctx, userID, child)
+	require.NoError(t, err)
+	recorded, err := tasks.Delete(ctx, userID, parent)
+	require.NoError(t, err)
+	assert.Len(t, recorded, 1)
+}
+
+func TestUserService(t *testing.T) {
+	ctx := context.Background()
+	users := service.NewUserService(repository.NewMemoryUserRepository())
+
+	user, err := users.Register(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, "correct horse", user.Password)
+
+	_, err = users.Register(ctx, "ada@example.com", "another")
+	assert.ErrorIs(t, err, service.ErrEmailTaken)
+
+	authenticated, err := users.Authenticate(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+
+	_, err = users.Authenticate(ctx, "ada@example.com", "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	_, err = users.Authenticate(ctx, "grace@example.com", "correct horse")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+}
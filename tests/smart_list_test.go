@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"to_do_api/controllers"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newSmartListRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := newTestTaskRouter(userID.String())
+	router.POST("/smart-lists", controllers.CreateSmartList(db))
+	router.GET("/smart-lists", controllers.ListSmartLists(db))
+	router.GET("/smart-lists/:id", controllers.GetSmartList(db))
+	router.PUT("/smart-lists/:id", controllers.UpdateSmartList(db))
+	router.DELETE("/smart-lists/:id", controllers.DeleteSmartList(db))
+	router.GET("/smart-lists/:id/tasks", controllers.EvaluateSmartList(db, taskService(db)))
+	router.POST("/smart-lists/:id/shares", controllers.ShareSmartList(db))
+	return router
+}
+
+func doSmartListRequest(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSmartList_CreateAndEvaluate(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	seedTasks(t, db, userID, false, true, false)
+	router := newSmartListRouter(db, userID)
+
+	w := doSmartListRequest(router, "POST", "/smart-lists", gin.H{"name": "Open", "query": "status:open"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var list models.SmartList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+
+	w = doSmartListRequest(router, "GET", "/smart-lists/"+list.ID.String()+"/tasks", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tasks []models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 2)
+
+	w = doSmartListRequest(router, "GET", "/smart-lists/"+list.ID.String()+"/tasks?q=title:"+"\"Task C\"", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Task C", tasks[0].Title)
+
+	w = doSmartListRequest(router, "POST", "/smart-lists", gin.H{"name": "Broken", "query": "due:someday"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doSmartListRequest(router, "PUT", "/smart-lists/"+list.ID.String(), gin.H{"name": "Done", "query": "status:done"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doSmartListRequest(router, "GET", "/smart-lists/"+list.ID.String()+"/tasks", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 1)
+}
+
+func TestSmartList_Sharing(t *testing.T) {
+	db := setupTestTaskDB(t)
+	owner := models.User{Email: "owner@example.com", Password: "x"}
+	recipient := models.User{Email: "friend@example.com", Password: "x"}
+	stranger := models.User{Email: "stranger@example.com", Password: "x"}
+	for _, user := range []*models.User{&owner, &recipient, &stranger} {
+		require.NoError(t, db.Create(user).Error)
+	}
+	seedTasks(t, db, owner.ID, false)
+	seedTasks(t, db, recipient.ID, false, false)
+
+	ownerRouter := newSmartListRouter(db, owner.ID)
+	w := doSmartListRequest(ownerRouter, "POST", "/smart-lists", gin.H{"name": "Open", "query": "status:open"})
+	var list models.SmartList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	path := "/smart-lists/" + list.ID.String()
+
+	strangerRouter := newSmartListRouter(db, stranger.ID)
+	assert.Equal(t, http.StatusForbidden, doSmartListRequest(strangerRouter, "GET", path, nil).Code)
+
+	w = doSmartListRequest(ownerRouter, "POST", path+"/shares", gin.H{"email": recipient.Email})
+	require.Equal(t, http.StatusAccepted, w.Code)
+	shared := w.Body.String()
+	w = doSmartListRequest(ownerRouter, "POST", path+"/shares", gin.H{"email": recipient.Email})
+	require.Equal(t, http.StatusAccepted, w.Code, "sharing twice is harmless")
+
+	// Whether an email is registered cannot be told from the response.
+	w = doSmartListRequest(ownerRouter, "POST", path+"/shares", gin.H{"email": "nobody@example.com"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, strings.Replace(shared, recipient.Email, "nobody@example.com", 1), w.Body.String())
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", recipient.ID).Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationTypeShare, notifications[0].Type)
+
+	recipientRouter := newSmartListRouter(db, recipient.ID)
+	w = doSmartListRequest(recipientRouter, "GET", "/smart-lists", nil)
+	var lists []models.SmartList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lists))
+	require.Len(t, lists, 1)
+	assert.Equal(t, list.ID, lists[0].ID)
+
+	w = doSmartListRequest(recipientRouter, "GET", path+"/tasks", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tasks []models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 2, "a shared list evaluates against the recipient's own tasks")
+	for _, task := range tasks {
+		assert.Equal(t, recipient.ID, task.UserID)
+	}
+
+	assert.Equal(t, http.StatusForbidden, doSmartListRequest(recipientRouter, "PUT", path, gin.H{"name": "Mine", "query": "status:done"}).Code)
+	assert.Equal(t, http.StatusForbidden, doSmartListRequest(recipientRouter, "DELETE", path, nil).Code)
+
+	assert.Equal(t, http.StatusOK, doSmartListRequest(ownerRouter, "DELETE", path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, doSmartListRequest(recipientRouter, "GET", path, nil).Code)
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"to_do_api/config"
+	"to_do_api/database"
+	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/search"
+	"to_do_api/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_SQLiteFile(t *testing.T) {
+	cfg := &config.Config{DB_DRIVER: "sqlite", DB_PATH: filepath.Join(t.TempDir(), "to_do.db")}
+	db, err := database.Open(cfg)
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	var journalMode string
+	require.NoError(t, db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error)
+	assert.Equal(t, "wal", journalMode)
+	assert.IsType(t, search.Like{}, search.For(db))
+
+	ctx := context.Background()
+	tasks := service.NewTaskService(repository.NewGormTaskRepository(db))
+	userID := uuid.New()
+	berlin := time.FixedZone("CEST", 2*60*60)
+	early := time.Date(2026, 10, 20, 1, 0, 0, 0, berlin) // 2026-10-19 23:00 UTC
+	late := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC)
+	for title, due := range map[string]time.Time{"Early": early, "Late": late} {
+		_, _, err := tasks.Create(ctx, userID, service.TaskInput{Title: title, DueDate: &due})
+		require.NoError(t, err)
+	}
+
+	// Times are stored in UTC, so comparing them as text orders them.
+	var stored []string
+	require.NoError(t, db.Raw("SELECT CAST(due_date AS TEXT) FROM tasks ORDER BY due_date").Scan(&stored).Error)
+	require.Len(t, stored, 2)
+	for _, value := range stored {
+		assert.True(t, strings.HasSuffix(value, "+00:00"), value)
+	}
+	cutoff := time.Date(2026, 10, 20, 1, 15, 0, 0, berlin)
+	listed, err := tasks.List(ctx, userID, repository.TaskFilter{DueBefore: &cutoff})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "Early", listed[0].Title)
+
+	// The data outlives the connection.
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+	db, err = database.Open(cfg)
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	var count int64
+	require.NoError(t, db.Model(&models.Task{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestOpen_UnknownDriver(t *testing.T) {
+	_, err := database.Open(&config.Config{DB_DRIVER: "mysql"})
+	assert.ErrorContains(t, err, `unknown DB_DRIVER "mysql"`)
+}
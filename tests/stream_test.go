@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"to_do_api/auth"
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/middleware"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type streamEvent struct {
+	ID     string `json:"-"`
+	Type   string
+	TaskID string `json:"task_id"`
+}
+
+const testJWTSecret = "test-secret-test-secret-test-secret"
+
+func newTestStreamServer(t *testing.T, db *gorm.DB, broker events.Broker) *httptest.Server {
+	// The handlers run on the server's goroutines; keep them on the single
+	// in-memory database connection.
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authorized := r.Group("/")
+	authorized.Use(middleware.AuthMiddleware(testJWTSecret))
+	authorized.POST("/tasks", controllers.CreateTask(taskService(db), broker))
+	authorized.POST("/tasks/stream/tickets", controllers.CreateStreamTicket(db))
+
+	streaming := r.Group("/")
+	streaming.Use(middleware.StreamAuthMiddleware(db, testJWTSecret))
+	streaming.GET("/tasks/stream", controllers.StreamTasks(db, broker))
+	streaming.GET("/tasks/ws", controllers.StreamTasksWebSocket(db, broker, []string{"https://app.example.com"}))
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testToken(t *testing.T, userID uuid.UUID) string {
+	token, err := (&auth.DefaultAuthService{}).GenerateToken(userID, testJWTSecret)
+	require.NoError(t, err)
+	return token
+}
+
+func createTaskOverHTTP(t *testing.T, server *httptest.Server, token, title string) {
+	body, _ := json.Marshal(map[string]interface{}{"title": title})
+	req, _ := http.NewRequest("POST", server.URL+"/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func streamTicket(t *testing.T, server *httptest.Server, token string) string {
+	req, _ := http.NewRequest("POST", server.URL+"/tasks/stream/tickets", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var body struct {
+		Ticket string `json:"ticket"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body.Ticket
+}
+
+// readSSE returns the next event from an SSE stream, skipping comments.
+func readSSE(t *testing.T, reader *bufio.Reader) streamEvent {
+	var event streamEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event.ID != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		}
+	}
+}
+
+func TestStreamTasks_ReplayThenLive(t *testing.T) {
+	db := setupTestTaskDB(t)
+	broker := events.NewMemoryBroker()
+	server := newTestStreamServer(t, db, broker)
+	userID := uuid.New()
+	token := testToken(t, userID)
+
+	createTaskOverHTTP(t, server, token, "first")
+	createTaskOverHTTP(t, server, token, "second")
+	createTaskOverHTTP(t, server, testToken(t, uuid.New()), "someone else's")
+
+	var first models.TaskEvent
+	require.NoError(t, db.Where("user_id = ?", userID).Order("id").First(&first).Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/tasks/stream?ticket="+streamTicket(t, server, token), nil)
+	req.Header.Set("Last-Event-ID", jsonNumber(first.ID))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	replayed := readSSE(t, reader)
+	assert.Equal(t, models.EventTaskCreated, replayed.Type)
+	assert.Equal(t, jsonNumber(first.ID+1), replayed.ID)
+
+	createTaskOverHTTP(t, server, token, "third")
+	live := readSSE(t, reader)
+	assert.Equal(t, models.EventTaskCreated, live.Type)
+
+	var third models.Task
+	require.NoError(t, db.Where("title = ?", "third").First(&third).Error)
+	assert.Equal(t, third.ID.String(), live.TaskID)
+}
+
+func TestStreamTasks_RequiresToken(t *testing.T) {
+	db := setupTestTaskDB(t)
+	server := newTestStreamServer(t, db, events.NewMemoryBroker())
+
+	resp, err := http.Get(server.URL + "/tasks/stream?ticket=garbage")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A JWT is not accepted in the URL, where it would be logged.
+	resp, err = http.Get(server.URL + "/tasks/stream?access_token=" + testToken(t, uuid.New()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestStreamTicket_SingleUse(t *testing.T) {
+	db := setupTestTaskDB(t)
+	server := newTestStreamServer(t, db, events.NewMemoryBroker())
+	ticket := streamTicket(t, server, testToken(t, uuid.New()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/tasks/stream?ticket="+ticket, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	again, err := http.Get(server.URL + "/tasks/stream?ticket=" + ticket)
+	require.NoError(t, err)
+	again.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, again.StatusCode)
+}
+
+func TestStreamTasks_ReadsEventsInLogOrder(t *testing.T) {
+	db := setupTestTaskDB(t)
+	broker := events.NewMemoryBroker()
+	server := newTestStreamServer(t, db, broker)
+	userID := uuid.New()
+	token := testToken(t, userID)
+	createTaskOverHTTP(t, server, token, "before connecting")
+	latest, err := events.Latest(db, userID.String())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/tasks/stream", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Last-Event-ID", jsonNumber(latest))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// Only the later event's notification arrives, or it arrives first.
+	first, err := events.Record(db, models.EventTaskUpdated, models.Task{ID: uuid.New(), UserID: userID})
+	require.NoError(t, err)
+	second, err := events.Record(db, models.EventTaskUpdated, models.Task{ID: uuid.New(), UserID: userID})
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(ctx, second))
+
+	assert.Equal(t, jsonNumber(first.ID), readSSE(t, reader).ID)
+	assert.Equal(t, jsonNumber(second.ID), readSSE(t, reader).ID)
+}
+
+func TestStreamTasksWebSocket(t *testing.T) {
+	db := setupTestTaskDB(t)
+	server := newTestStreamServer(t, db, events.NewMemoryBroker())
+	userID := uuid.New()
+	token := testToken(t, userID)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/tasks/ws"
+	header := http.Header{"Authorization": []string{"Bearer " + token}}
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	createTaskOverHTTP(t, server, token, "over the socket")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message struct {
+		ID   uint64          `json:"id"`
+		Type string          `json:"type"`
+		Task json.RawMessage `json:"task"`
+	}
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, models.EventTaskCreated, message.Type)
+	assert.Contains(t, string(message.Task), "over the socket")
+}
+
+func TestStreamTasksWebSocket_ChecksOrigin(t *testing.T) {
+	db := setupTestTaskDB(t)
+	server := newTestStreamServer(t, db, events.NewMemoryBroker())
+	token := testToken(t, uuid.New())
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/tasks/ws"
+
+	for origin, allowed := range map[string]bool{
+		"https://evil.example":    false,
+		"https://app.example.com": true,
+		server.URL:                true,
+	} {
+		header := http.Header{"Origin": []string{origin}}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+streamTicket(t, server, token), header)
+		if allowed {
+			require.NoError(t, err, origin)
+			conn.Close()
+		} else {
+			require.Error(t, err, origin)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, origin)
+		}
+	}
+}
+
+func TestMemoryBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	userID := uuid.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := broker.Subscribe(ctx, userID.String())
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, broker.Publish(ctx, models.TaskEvent{ID: uint64(i + 1), UserID: userID}))
+	}
+
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Less(t, received, 1000)
+}
+
+func jsonNumber(id uint64) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
@@ -0,0 +1,302 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pullResponse struct {
+	Tasks     []models.Task `json:"tasks"`
+	Deleted   []uuid.UUID   `json:"deleted"`
+	SyncToken string        `json:"sync_token"`
+}
+
+type pushResponse struct {
+	Results []struct {
+		ID     uuid.UUID    `json:"id"`
+		Status string       `json:"status"`
+		Reason string       `json:"reason"`
+		Task   *models.Task `json:"task"`
+	} `json:"results"`
+}
+
+func pull(t *testing.T, router *gin.Engine, token string) pullResponse {
+	req, _ := http.NewRequest("GET", "/sync?sync_token="+token, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp pullResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func push(t *testing.T, router *gin.Engine, body map[string]interface{}) pushResponse {
+	bodyBytes, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/sync", bytes.NewBuffer(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp pushResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestSync_PullDeltaWithTombstones(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	broker := events.NewMemoryBroker()
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/sync", controllers.PullSync(db))
+	router.POST("/tasks", controllers.CreateTask(taskService(db), broker))
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), broker))
+
+	create := func(title string) models.Task {
+		body, _ := json.Marshal(map[string]interface{}{"title": title})
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var task models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		return task
+	}
+
+	create("kept")
+	doomed := create("doomed")
+
+	initial := pull(t, router, "")
+	assert.Len(t, initial.Tasks, 2)
+	require.NotEmpty(t, initial.SyncToken)
+
+	added := create("added")
+	req, _ := http.NewRequest("DELETE", "/tasks/"+doomed.ID.String(), nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	delta := pull(t, router, initial.SyncToken)
+	require.Len(t, delta.Tasks, 1)
+	assert.Equal(t, added.ID, delta.Tasks[0].ID)
+	assert.Equal(t, []uuid.UUID{doomed.ID}, delta.Deleted)
+
+	empty := pull(t, router, delta.SyncToken)
+	assert.Empty(t, empty.Tasks)
+	assert.Empty(t, empty.Deleted)
+}
+
+func TestSync_PushCreatesWithClientID(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/sync", controllers.PushSync(taskService(db), events.NewMemoryBroker()))
+
+	clientID := uuid.New()
+	resp := push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "upsert", "id": clientID, "title": "Made offline"},
+			{"op": "upsert", "id": uuid.New(), "title": ""},
+		},
+	})
+
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "applied", resp.Results[0].Status)
+	assert.Equal(t, "rejected", resp.Results[1].Status)
+
+	var task models.Task
+	require.NoError(t, db.First(&task, "id = ?", clientID).Error)
+	assert.Equal(t, userID, task.UserID)
+}
+
+func TestSync_PushConflictsOnVersion(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/sync", controllers.PushSync(taskService(db), events.NewMemoryBroker()))
+
+	task := models.Task{Title: "Original", UserID: userID, Version: 1}
+	require.NoError(t, db.Create(&task).Error)
+
+	// A second device edits the version both devices pulled.
+	resp := push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{{"op": "upsert", "id": task.ID, "base_version": 1, "title": "From device B"}},
+	})
+	require.Equal(t, "applied", resp.Results[0].Status)
+	require.NotNil(t, resp.Results[0].Task)
+	assert.Equal(t, 2, resp.Results[0].Task.Version)
+
+	// The first device's edit of version 1 conflicts, however recent it is.
+	resp = push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "upsert", "id": task.ID, "base_version": 1, "title": "From device A"},
+			{"op": "delete", "id": task.ID, "base_version": 1},
+		},
+	})
+	for _, result := range resp.Results {
+		require.Equal(t, "conflict", result.Status)
+		require.NotNil(t, result.Task)
+		assert.Equal(t, "From device B", result.Task.Title)
+	}
+
+	// Rebased on the server's copy, it applies.
+	resp = push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{{"op": "upsert", "id": task.ID, "base_version": 2, "title": "Latest"}},
+	})
+	require.Equal(t, "applied", resp.Results[0].Status)
+	require.NoError(t, db.First(&task, "id = ?", task.ID).Error)
+	assert.Equal(t, "Latest", task.Title)
+
+	// An edit of a task deleted on the server conflicts rather than
+	// bringing it back.
+	resp = push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "delete", "id": task.ID, "base_version": 3},
+			{"op": "upsert", "id": task.ID, "base_version": 3, "title": "Edited offline"},
+			{"op": "delete", "id": task.ID, "base_version": 3},
+		},
+	})
+	assert.Equal(t, "applied", resp.Results[0].Status)
+	assert.Equal(t, "conflict", resp.Results[1].Status)
+	assert.Equal(t, "applied", resp.Results[2].Status)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+}
+
+func TestSync_ProjectsAndLabels(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/sync", controllers.PullSync(db))
+	router.POST("/sync", controllers.PushSync(taskService(db), events.NewMemoryBroker()))
+
+	for _, task := range []models.Task{
+		{Title: "Report", Project: "Work", Labels: []string{"urgent", "writing"}},
+		{Title: "Slides", Project: "Work", Labels: []string{"urgent"}},
+		{Title: "Milk", Project: "Home", Labels: []string{"errand"}},
+	} {
+		task.UserID = userID
+		_, _, err := taskService(db).Insert(context.Background(), task)
+		require.NoError(t, err)
+	}
+
+	var names struct {
+		Projects        []string `json:"projects"`
+		Labels          []string `json:"labels"`
+		DeletedProjects []string `json:"deleted_projects"`
+		DeletedLabels   []string `json:"deleted_labels"`
+		SyncToken       string   `json:"sync_token"`
+	}
+	pullNames := func(token string) {
+		req, _ := http.NewRequest("GET", "/sync?sync_token="+token, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &names))
+	}
+
+	pullNames("")
+	assert.Equal(t, []string{"Home", "Work"}, names.Projects)
+	assert.Equal(t, []string{"errand", "urgent", "writing"}, names.Labels)
+	assert.Empty(t, names.DeletedProjects)
+	token := names.SyncToken
+
+	resp := push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "rename_project", "name": "Work", "new_name": "Office"},
+			{"op": "delete_project", "name": "Home"},
+			{"op": "rename_label", "name": "#Urgent", "new_name": "writing"},
+			{"op": "delete_label", "name": "errand"},
+			{"op": "rename_label", "name": "writing", "new_name": "not a label"},
+		},
+	})
+	require.Len(t, resp.Results, 5)
+	for _, result := range resp.Results[:4] {
+		assert.Equal(t, "applied", result.Status)
+	}
+	assert.Equal(t, "rejected", resp.Results[4].Status)
+
+	pullNames(token)
+	assert.Equal(t, []string{"Office"}, names.Projects)
+	assert.Equal(t, []string{"writing"}, names.Labels)
+	assert.Equal(t, []string{"Home", "Work"}, names.DeletedProjects)
+	assert.Equal(t, []string{"errand", "urgent"}, names.DeletedLabels)
+
+	var tasks []models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).Order("title").Find(&tasks).Error)
+	require.Len(t, tasks, 3)
+	assert.Equal(t, "", tasks[0].Project)
+	assert.Empty(t, tasks[0].Labels)
+	assert.Equal(t, []string{"writing"}, tasks[1].Labels)
+	assert.Equal(t, "Office", tasks[2].Project)
+}
+
+func TestSync_PushCannotTouchOtherUsersTask(t *testing.T) {
+	db := setupTestTaskDB(t)
+	other := models.Task{Title: "Not yours", UserID: uuid.New()}
+	require.NoError(t, db.Create(&other).Error)
+
+	router := newTestTaskRouter(uuid.New().String())
+	router.POST("/sync", controllers.PushSync(taskService(db), events.NewMemoryBroker()))
+
+	resp := push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{{"op": "delete", "id": other.ID}},
+	})
+	assert.Equal(t, "rejected", resp.Results[0].Status)
+
+	var count int64
+	db.Model(&models.Task{}).Where("id = ?", other.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSync_InvalidToken(t *testing.T) {
+	db := setupTestTaskDB(t)
+	router := newTestTaskRouter(uuid.New().String())
+	router.GET("/sync", controllers.PullSync(db))
+
+	req, _ := http.NewRequest("GET", "/sync?sync_token=not-a-token", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSync_PushDeleteWithSubtasks(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/sync", controllers.PushSync(taskService(db), events.NewMemoryBroker()))
+
+	parent := models.Task{Title: "Parent", UserID: userID, Version: 1}
+	require.NoError(t, db.Create(&parent).Error)
+	child := models.Task{Title: "Child", ParentID: &parent.ID, UserID: userID, Version: 1}
+	require.NoError(t, db.Create(&child).Error)
+
+	resp := push(t, router, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "delete", "id": parent.ID, "base_version": 1},
+			{"op": "delete", "id": child.ID, "base_version": 1},
+			{"op": "delete", "id": parent.ID, "base_version": 1},
+		},
+	})
+	assert.Equal(t, "rejected", resp.Results[0].Status)
+	assert.Contains(t, resp.Results[0].Reason, "subtasks")
+	assert.Equal(t, "applied", resp.Results[1].Status)
+	assert.Equal(t, "applied", resp.Results[2].Status)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+}
@@ -2,13 +2,20 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"to_do_api/controllers"
+	"to_do_api/database"
+	"to_do_api/events"
 	"to_do_api/models"
+	"to_do_api/repository"
+	"to_do_api/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -23,13 +30,21 @@ func setupTestTaskDB(t *testing.T) *gorm.DB {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&models.Task{}); err != nil {
+	if err := database.Migrate(db); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
 	return db
 }
 
+func taskService(db *gorm.DB) *service.TaskService {
+	return service.NewTaskService(repository.NewGormTaskRepository(db))
+}
+
+func userService(db *gorm.DB) *service.UserService {
+	return service.NewUserService(repository.NewGormUserRepository(db))
+}
+
 func newTestTaskRouter(userID string) *gin.Engine {
 	r := gin.New()
 	r.Use(func(c *gin.Context) {
@@ -43,7 +58,7 @@ func TestCreateTask(t *testing.T) {
 	db := setupTestTaskDB(t)
 	userID := uuid.New().String()
 	router := newTestTaskRouter(userID)
-	router.POST("/tasks", controllers.CreateTask(db))
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
 
 	taskBody := map[string]interface{}{
 		"title":       "Test Task",
@@ -67,7 +82,7 @@ func TestCreateTask_InvalidJSON(t *testing.T) {
 	db := setupTestTaskDB(t)
 	userID := uuid.New().String()
 	router := newTestTaskRouter(userID)
-	router.POST("/tasks", controllers.CreateTask(db))
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
 
 	req, err := http.NewRequest("POST", "/tasks", bytes.NewBufferString("invalid json"))
 	assert.NoError(t, err)
@@ -95,7 +110,7 @@ func TestListTasks(t *testing.T) {
 	}
 
 	router := newTestTaskRouter(userID.String())
-	router.GET("/tasks", controllers.ListTasks(db))
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
 
 	req, err := http.NewRequest("GET", "/tasks", nil)
 	assert.NoError(t, err)
@@ -122,7 +137,7 @@ func TestUpdateTask(t *testing.T) {
 	assert.NoError(t, err)
 
 	router := newTestTaskRouter(userID.String())
-	router.PUT("/tasks/:id", controllers.UpdateTask(db))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
 
 	updateBody := map[string]interface{}{
 		"title": "Updated Title",
@@ -154,7 +169,7 @@ func TestUpdateTask_Unauthorized(t *testing.T) {
 
 	// Set request context with a different user.
 	router := newTestTaskRouter(userID.String())
-	router.PUT("/tasks/:id", controllers.UpdateTask(db))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
 
 	updateBody := map[string]interface{}{
 		"title": "Updated Title",
@@ -175,7 +190,7 @@ func TestUpdateTask_Unauthorized(t *testing.T) {
 func TestUpdateTask_InvalidID(t *testing.T) {
 	db := setupTestTaskDB(t)
 	router := newTestTaskRouter(uuid.New().String())
-	router.PUT("/tasks/:id", controllers.UpdateTask(db))
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
 
 	updateBody := map[string]interface{}{
 		"title": "Updated Title",
@@ -204,7 +219,7 @@ func TestDeleteTask(t *testing.T) {
 	assert.NoError(t, err)
 
 	router := newTestTaskRouter(userID.String())
-	router.DELETE("/tasks/:id", controllers.DeleteTask(db))
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), events.NewMemoryBroker()))
 
 	req, err := http.NewRequest("DELETE", "/tasks/"+task.ID.String(), nil)
 	assert.NoError(t, err)
@@ -237,7 +252,7 @@ func TestDeleteTask_Unauthorized(t *testing.T) {
 	assert.NoError(t, err)
 
 	router := newTestTaskRouter(userID.String())
-	router.DELETE("/tasks/:id", controllers.DeleteTask(db))
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), events.NewMemoryBroker()))
 
 	req, err := http.NewRequest("DELETE", "/tasks/"+task.ID.String(), nil)
 	assert.NoError(t, err)
@@ -251,7 +266,7 @@ func TestDeleteTask_Unauthorized(t *testing.T) {
 func TestDeleteTask_InvalidID(t *testing.T) {
 	db := setupTestTaskDB(t)
 	router := newTestTaskRouter(uuid.New().String())
-	router.DELETE("/tasks/:id", controllers.DeleteTask(db))
+	router.DELETE("/tasks/:id", controllers.DeleteTask(taskService(db), events.NewMemoryBroker()))
 
 	req, err := http.NewRequest("DELETE", "/tasks/invalid-uuid", nil)
 	assert.NoError(t, err)
@@ -260,3 +275,86 @@ func TestDeleteTask_InvalidID(t *testing.T) {
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestListTasks_Filters(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	soon := time.Now().UTC().Add(24 * time.Hour)
+	later := time.Now().UTC().Add(30 * 24 * time.Hour)
+	tasks := []models.Task{
+		{Title: "Due soon", UserID: userID, DueDate: &soon},
+		{Title: "Due later", UserID: userID, DueDate: &later},
+		{Title: "Done", UserID: userID, Status: true},
+	}
+	for _, task := range tasks {
+		assert.NoError(t, db.Create(&task).Error)
+	}
+
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	list := func(query string) []models.Task {
+		req, err := http.NewRequest("GET", "/tasks?"+query, nil)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var respTasks []models.Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &respTasks))
+		return respTasks
+	}
+
+	assert.Len(t, list("status=false"), 2)
+	assert.Len(t, list("has_due_date=false"), 1)
+	weekFromNow := url.QueryEscape(time.Now().UTC().Add(7 * 24 * time.Hour).Format(time.RFC3339))
+	due := list("due_before=" + weekFromNow)
+	assert.Len(t, due, 1)
+	assert.Equal(t, "Due soon", due[0].Title)
+
+	req, err := http.NewRequest("GET", "/tasks?status=maybe", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTaskHandlers_MemoryService(t *testing.T) {
+	tasks := service.NewTaskService(repository.NewMemoryTaskRepository())
+	owner, other := uuid.New(), uuid.New()
+	send := func(userID uuid.UUID, method, path string, body interface{}) *httptest.ResponseRecorder {
+		router := newTestTaskRouter(userID.String())
+		router.POST("/tasks", controllers.CreateTask(tasks, events.NewMemoryBroker()))
+		router.GET("/tasks/:id", controllers.GetTask(tasks))
+		router.POST("/tasks/bulk", controllers.BulkTasks(tasks, events.NewMemoryBroker()))
+		bodyBytes, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(owner, "POST", "/tasks", gin.H{"title": "Kept"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var task models.Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+
+	assert.Equal(t, http.StatusOK, send(owner, "GET", "/tasks/"+task.ID.String(), nil).Code)
+	assert.Equal(t, http.StatusForbidden, send(other, "GET", "/tasks/"+task.ID.String(), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(owner, "GET", "/tasks/"+uuid.New().String(), nil).Code)
+
+	// An atomic batch that fails leaves the store as it was.
+	w = send(owner, "POST", "/tasks/bulk", gin.H{"operations": []gin.H{
+		{"op": "create", "task": gin.H{"title": "Rolled back"}},
+		{"op": "update", "ids": []uuid.UUID{task.ID}, "set": gin.H{"title": "Renamed"}},
+		{"op": "delete", "ids": []uuid.UUID{uuid.New()}},
+	}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	list, err := tasks.List(context.Background(), owner, repository.TaskFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "Kept", list[0].Title)
+		assert.Equal(t, 1, list[0].Version)
+	}
+}
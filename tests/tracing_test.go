@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"to_do_api/config"
+	"to_do_api/controllers"
+	"to_do_api/repository"
+	"to_do_api/service"
+	"to_do_api/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanRecorder installs a recording tracer provider. Tracers obtained before
+// the first provider is installed keep using it, so tests share one.
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+})
+
+// spansIn returns the ended spans of the trace, by name.
+func spansIn(traceID trace.TraceID) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spanRecorder().Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+	return spans
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_RequestAndQueries(t *testing.T) {
+	spanRecorder()
+	shutdown, err := tracing.Setup(context.Background(), config.Default())
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	db := setupTestTaskDB(t)
+	require.NoError(t, tracing.InstrumentDB(db))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(otelgin.Middleware("to_do_api"), func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+	})
+	r.GET("/tasks", controllers.ListTasks(taskService(db)))
+
+	req := httptest.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spans := spansIn(traceID)
+
+	server, ok := spans["/tasks"]
+	require.True(t, ok, "the request continues the caller's trace")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+
+	query, ok := spans["gorm.query"]
+	require.True(t, ok, "queries are traced under the request")
+	assert.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind())
+	assert.Equal(t, "tasks", spanAttribute(query, "db.collection.name").AsString())
+	assert.Contains(t, spanAttribute(query, "db.query.text").AsString(), "SELECT")
+}
+
+func TestTracing_PasswordHashing(t *testing.T) {
+	spanRecorder()
+	users := service.NewUserService(repository.NewMemoryUserRepository())
+
+	ctx, parent := otel.Tracer("tests").Start(context.Background(), "login")
+	_, err := users.Register(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	_, err = users.Authenticate(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	parent.End()
+
+	spans := spansIn(parent.SpanContext().TraceID())
+	for _, name := range []string{"bcrypt.GenerateFromPassword", "bcrypt.CompareHashAndPassword"} {
+		span, ok := spans[name]
+		require.True(t, ok, name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
+
+func TestTracing_UnknownExporter(t *testing.T) {
+	cfg := config.Default()
+	cfg.TRACING_EXPORTER = "jaeger"
+	_, err := tracing.Setup(context.Background(), cfg)
+	assert.ErrorContains(t, err, `unknown TRACING_EXPORTER "jaeger"`)
+}
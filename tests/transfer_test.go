@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/transfer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type importResponse struct {
+	DryRun   bool               `json:"dry_run"`
+	Rows     int                `json:"rows"`
+	Valid    bool               `json:"valid"`
+	Problems []transfer.Problem `json:"problems"`
+	Created  int                `json:"created"`
+	TaskIDs  []uuid.UUID        `json:"task_ids"`
+}
+
+func newTransferRouter(db *gorm.DB, userID uuid.UUID) *gin.Engine {
+	router := newTestTaskRouter(userID.String())
+	router.GET("/tasks/export", controllers.ExportTasks(taskService(db)))
+	router.GET("/tasks/:id", controllers.GetTask(taskService(db)))
+	router.POST("/tasks/import/preview", controllers.PreviewImport())
+	router.POST("/tasks/import", controllers.ImportTasks(taskService(db), events.NewMemoryBroker()))
+	return router
+}
+
+func uploadFile(t *testing.T, router *gin.Engine, path, filename, content string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	require.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportTasks(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	due := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&models.Task{Title: "Write, report", Description: "line one\nline two", UserID: userID, DueDate: &due, Labels: []string{"work", "q4"}, Priority: models.PriorityHigh}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Done already", Status: true, UserID: userID}).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Not mine", UserID: uuid.New()}).Error)
+	router := newTransferRouter(db, userID)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=csv", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "tasks.csv")
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, transfer.Columns, records[0])
+	assert.Equal(t, "Write, report", records[1][1])
+	assert.Equal(t, "line one\nline two", records[1][2])
+	assert.Equal(t, "2026-11-01T09:00:00Z", records[1][4])
+	assert.Equal(t, "3", records[1][5])
+	assert.Equal(t, "work,q4", records[1][7])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/tasks/export?status=true", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tasks []models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Done already", tasks[0].Title)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/tasks/export?q=title:nothing", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/tasks/export?format=xlsx", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportTasks_RoundTrip(t *testing.T) {
+	db := setupTestTaskDB(t)
+	source, target := uuid.New(), uuid.New()
+	due := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&models.Task{Title: "Deploy", UserID: source, DueDate: &due, Labels: []string{"ops"}, Project: "Infra", Recurrence: "FREQ=MONTHLY"}).Error)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=csv", nil)
+	newTransferRouter(db, source).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = uploadFile(t, newTransferRouter(db, target), "/tasks/import", "tasks.csv", w.Body.String(), nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var report importResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Created)
+
+	var imported models.Task
+	require.NoError(t, db.Where("user_id = ?", target).First(&imported).Error)
+	assert.Equal(t, "Deploy", imported.Title)
+	assert.True(t, due.Equal(*imported.DueDate))
+	assert.Equal(t, []string{"ops"}, imported.Labels)
+	assert.Equal(t, "Infra", imported.Project)
+	assert.Equal(t, "FREQ=MONTHLY", imported.Recurrence)
+}
+
+func TestExportTasks_EscapesFormulas(t *testing.T) {
+	db := setupTestTaskDB(t)
+	source, target := uuid.New(), uuid.New()
+	cells := map[string]string{
+		"=HYPERLINK(\"http://evil.example\")": "'=HYPERLINK(\"http://evil.example\")",
+		"+1 555 1234":                         "'+1 555 1234",
+		"-5 degrees":                          "'-5 degrees",
+		"@SUM(A1)":                            "'@SUM(A1)",
+		"'=already quoted":                    "''=already quoted",
+		"'tis the season":                     "'tis the season",
+		"Plain title":                         "Plain title",
+	}
+	for title := range cells {
+		require.NoError(t, db.Create(&models.Task{Title: title, Description: "\tindented", UserID: source}).Error)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/tasks/export?format=csv", nil)
+	newTransferRouter(db, source).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(cells)+1)
+	exported := map[string]bool{}
+	for _, record := range records[1:] {
+		exported[record[1]] = true
+		assert.Equal(t, "'\tindented", record[2])
+	}
+	for _, escaped := range cells {
+		assert.True(t, exported[escaped], escaped)
+	}
+
+	// Importing the export gives back the original text.
+	w = uploadFile(t, newTransferRouter(db, target), "/tasks/import", "tasks.csv", w.Body.String(), nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var imported []models.Task
+	require.NoError(t, db.Where("user_id = ?", target).Find(&imported).Error)
+	require.Len(t, imported, len(cells))
+	for _, task := range imported {
+		assert.Contains(t, cells, task.Title)
+		assert.Equal(t, "\tindented", task.Description)
+	}
+}
+
+func TestImportTasks_MappingAndDryRun(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+	file := "Task Name,Deadline,Done,Tags,Owner\nBuy milk,2026-11-02,no,home; errands,me\nFile taxes,2026-11-03 10:00,x,,me\n"
+
+	w := uploadFile(t, router, "/tasks/import/preview", "export.csv", file, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var preview struct {
+		Columns          []string          `json:"columns"`
+		SuggestedMapping map[string]string `json:"suggested_mapping"`
+		Rows             int               `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(t, []string{"Task Name", "Deadline", "Done", "Tags", "Owner"}, preview.Columns)
+	assert.Equal(t, map[string]string{"title": "Task Name", "due_date": "Deadline", "status": "Done", "labels": "Tags"}, preview.SuggestedMapping)
+	assert.Equal(t, 2, preview.Rows)
+
+	w = uploadFile(t, router, "/tasks/import", "export.csv", file, map[string]string{"dry_run": "true", "timezone": "Europe/Berlin"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var report importResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 2, report.Rows)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+
+	mapping := `{"title": "Task Name", "description": "Owner", "due_date": "Deadline"}`
+	w = uploadFile(t, router, "/tasks/import", "export.csv", file, map[string]string{"mapping": mapping, "timezone": "Europe/Berlin"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var tasks []models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).Order("title").Find(&tasks).Error)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "me", tasks[0].Description)
+	assert.False(t, tasks[1].Status, "status was not mapped")
+	assert.Equal(t, time.Date(2026, 11, 3, 9, 0, 0, 0, time.UTC), tasks[1].DueDate.UTC())
+
+	w = uploadFile(t, router, "/tasks/import", "export.csv", file, map[string]string{"mapping": `{"title": "Missing"}`})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportTasks_InvalidRowsRollBack(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	router := newTransferRouter(db, userID)
+
+	body := `[
+		{"title": "Fine", "priority": "high", "labels": ["a", "b"]},
+		{"title": "", "status": "maybe"},
+		{"title": "Bad date", "due_date": "next tuesday"},
+		{"title": "Bad label", "labels": "two words"}
+	]`
+	req, _ := http.NewRequest("POST", "/tasks/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var report importResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.Valid)
+	assert.Equal(t, 4, report.Rows)
+	lines := map[int]bool{}
+	for _, problem := range report.Problems {
+		lines[problem.Line] = true
+	}
+	assert.Equal(t, map[int]bool{2: true, 3: true, 4: true}, lines)
+	assert.Equal(t, int64(0), countTasks(db, userID))
+
+	req, _ = http.NewRequest("POST", "/tasks/import?format=json", strings.NewReader(`[{"title": "Fine", "priority": "high", "labels": ["a", "b"]}]`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var task models.Task
+	require.NoError(t, db.Where("user_id = ?", userID).First(&task).Error)
+	assert.Equal(t, models.PriorityHigh, task.Priority)
+	assert.Equal(t, []string{"a", "b"}, task.Labels)
+}
@@ -29,7 +29,7 @@ func TestRegister_Success(t *testing.T) {
 	require.NoError(t, db.AutoMigrate(&models.User{}))
 
 	router := newTestUserRouter()
-	router.POST("/register", controllers.Register(db))
+	router.POST("/register", controllers.Register(userService(db)))
 
 	reqBody := map[string]string{
 		"email":    "test@example.com",
@@ -50,7 +50,7 @@ func TestRegister_InvalidJSON(t *testing.T) {
 	require.NoError(t, err)
 
 	router := newTestUserRouter()
-	router.POST("/register", controllers.Register(db))
+	router.POST("/register", controllers.Register(userService(db)))
 
 	req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
@@ -81,7 +81,7 @@ func TestLogin_Success(t *testing.T) {
 	require.NoError(t, db.Create(&user).Error)
 
 	router := newTestUserRouter()
-	router.POST("/login", controllers.Login(db, &MockAuthService{}))
+	router.POST("/login", controllers.Login(userService(db), &MockAuthService{}, testJWTSecret))
 
 	reqBody := map[string]string{
 		"email":    "login@example.com",
@@ -104,7 +104,7 @@ func TestLogin_InvalidJSON(t *testing.T) {
 	require.NoError(t, err)
 
 	router := newTestUserRouter()
-	router.POST("/login", controllers.Login(db, &MockAuthService{}))
+	router.POST("/login", controllers.Login(userService(db), &MockAuthService{}, testJWTSecret))
 
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
@@ -120,7 +120,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 	require.NoError(t, db.AutoMigrate(&models.User{}))
 
 	router := newTestUserRouter()
-	router.POST("/login", controllers.Login(db, &MockAuthService{}))
+	router.POST("/login", controllers.Login(userService(db), &MockAuthService{}, testJWTSecret))
 
 	reqBody := map[string]string{
 		"email":    "notfound@example.com",
@@ -150,7 +150,7 @@ func TestLogin_InvalidPassword(t *testing.T) {
 	require.NoError(t, db.Create(&user).Error)
 
 	router := newTestUserRouter()
-	router.POST("/login", controllers.Login(db, &MockAuthService{}))
+	router.POST("/login", controllers.Login(userService(db), &MockAuthService{}, testJWTSecret))
 
 	reqBody := map[string]string{
 		"email":    "user@example.com",
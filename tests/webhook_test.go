@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"to_do_api/controllers"
+	"to_do_api/events"
+	"to_do_api/models"
+	"to_do_api/webhooks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createTestWebhook(t *testing.T, db *gorm.DB, userID uuid.UUID, url string, events ...string) models.Webhook {
+	webhook := models.Webhook{UserID: userID, URL: url, Secret: "s3cret", Events: events, Active: true}
+	require.NoError(t, db.Create(&webhook).Error)
+	return webhook
+}
+
+func TestCreateTask_WritesOutbox(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	webhook := createTestWebhook(t, db, userID, "http://example.com/hook", models.EventTaskCreated)
+	createTestWebhook(t, db, userID, "http://example.com/other", models.EventTaskDeleted)
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/tasks", controllers.CreateTask(taskService(db), events.NewMemoryBroker()))
+
+	body, _ := json.Marshal(map[string]interface{}{"title": "Ship it"})
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, db.Find(&deliveries).Error)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.ID, deliveries[0].WebhookID)
+	assert.Equal(t, models.EventTaskCreated, deliveries[0].EventType)
+	assert.Contains(t, deliveries[0].Payload, "Ship it")
+}
+
+func TestUpdateTask_EnqueuesCompleted(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	createTestWebhook(t, db, userID, "http://example.com/hook", models.EventTaskUpdated, models.EventTaskCompleted)
+	task := models.Task{Title: "Finish", UserID: userID}
+	require.NoError(t, db.Create(&task).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.PUT("/tasks/:id", controllers.UpdateTask(taskService(db), events.NewMemoryBroker()))
+
+	body, _ := json.Marshal(map[string]interface{}{"title": "Finish", "status": true})
+	req, _ := http.NewRequest("PUT", "/tasks/"+task.ID.String(), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var events []string
+	require.NoError(t, db.Model(&models.WebhookDelivery{}).Order("event_type").Pluck("event_type", &events).Error)
+	assert.Equal(t, []string{models.EventTaskCompleted, models.EventTaskUpdated}, events)
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := createTestWebhook(t, db, userID, server.URL, models.EventTaskCreated)
+	require.NoError(t, webhooks.Enqueue(db, userID, models.EventTaskCreated, models.Task{Title: "Hello"}))
+
+	dispatcher := webhooks.NewDispatcher(db)
+	dispatcher.Client = server.Client()
+	delivered, err := dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	timestamp, err := strconv.ParseInt(gotHeaders.Get("X-Webhook-Timestamp"), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, webhooks.Sign(webhook.Secret, timestamp, gotBody), gotHeaders.Get("X-Webhook-Signature"))
+	assert.Equal(t, models.EventTaskCreated, gotHeaders.Get("X-Webhook-Event"))
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.DeliveryStatusDelivered, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+
+	delivered, err = dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+}
+
+func TestDispatcher_BacksOffAndGivesUp(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	createTestWebhook(t, db, userID, server.URL, models.EventTaskDeleted)
+	require.NoError(t, webhooks.Enqueue(db, userID, models.EventTaskDeleted, models.Task{Title: "Gone"}))
+
+	now := time.Now().UTC()
+	dispatcher := webhooks.NewDispatcher(db)
+	dispatcher.Client = server.Client()
+	dispatcher.MaxAttempts = 2
+	dispatcher.Now = func() time.Time { return now }
+
+	_, err := dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.DeliveryStatusPending, delivery.Status)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.WithinDuration(t, now.Add(webhooks.Backoff(1)), delivery.NextAttemptAt, time.Second)
+
+	_, err = dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "must not retry before the backoff elapses")
+
+	now = now.Add(webhooks.Backoff(1) + time.Second)
+	_, err = dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.DeliveryStatusFailed, delivery.Status)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhooks.Backoff(1))
+	assert.Equal(t, time.Minute, webhooks.Backoff(2))
+	assert.Equal(t, 4*time.Minute, webhooks.Backoff(4))
+	assert.Equal(t, 6*time.Hour, webhooks.Backoff(50))
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	webhook := createTestWebhook(t, db, userID, "http://example.com/hook", models.EventTaskCreated)
+	require.NoError(t, webhooks.Enqueue(db, userID, models.EventTaskCreated, models.Task{Title: "Hello"}))
+	var original models.WebhookDelivery
+	require.NoError(t, db.First(&original).Error)
+	require.NoError(t, db.Model(&original).Update("status", models.DeliveryStatusFailed).Error)
+
+	router := newTestTaskRouter(userID.String())
+	router.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", controllers.RedeliverWebhook(db))
+
+	req, _ := http.NewRequest("POST", "/webhooks/"+webhook.ID.String()+"/deliveries/"+original.ID.String()+"/redeliver", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var pending []models.WebhookDelivery
+	require.NoError(t, db.Where("status = ?", models.DeliveryStatusPending).Find(&pending).Error)
+	require.Len(t, pending, 1)
+	assert.NotEqual(t, original.ID, pending[0].ID)
+
+	var before, after webhooks.Envelope
+	require.NoError(t, json.Unmarshal([]byte(original.Payload), &before))
+	require.NoError(t, json.Unmarshal([]byte(pending[0].Payload), &after))
+	assert.Equal(t, original.ID, before.ID)
+	assert.Equal(t, pending[0].ID, after.ID, "the body id matches X-Webhook-Delivery")
+	assert.Equal(t, before.Event, after.Event)
+	assert.Equal(t, before.Data, after.Data)
+	assert.True(t, before.CreatedAt.Equal(after.CreatedAt))
+}
+
+func TestCreateWebhook_UnknownEvent(t *testing.T) {
+	db := setupTestTaskDB(t)
+
+	router := newTestTaskRouter(uuid.New().String())
+	router.POST("/webhooks", controllers.CreateWebhook(db))
+
+	body, _ := json.Marshal(map[string]interface{}{"url": "https://example.com/hook", "events": []string{"task.exploded"}})
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	createTestWebhook(t, db, userID, server.URL, models.EventTaskCreated)
+	require.NoError(t, webhooks.Enqueue(db, userID, models.EventTaskCreated, models.Task{Title: "Hello"}))
+
+	delivered, err := webhooks.NewDispatcher(db).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, calls)
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Contains(t, delivery.LastError, "not publicly routable")
+}
+
+// cancelAfterResponse cancels the dispatcher's context once the endpoint
+// has answered, as shutdown might.
+type cancelAfterResponse struct {
+	cancel context.CancelFunc
+}
+
+func (c cancelAfterResponse) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	c.cancel()
+	return resp, err
+}
+
+func TestDispatcher_RecordsDeliveryDuringShutdown(t *testing.T) {
+	db := setupTestTaskDB(t)
+	userID := uuid.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	createTestWebhook(t, db, userID, server.URL, models.EventTaskCreated)
+	require.NoError(t, webhooks.Enqueue(db, userID, models.EventTaskCreated, models.Task{Title: "Hello"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatcher := webhooks.NewDispatcher(db)
+	dispatcher.Client = &http.Client{Transport: cancelAfterResponse{cancel: cancel}}
+	_, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, models.DeliveryStatusDelivered, delivery.Status)
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const statementSpan = "tracing:statement_span"
+
+var tracer = otel.Tracer("to_do_api/database")
+
+// InstrumentDB traces every statement run through db as a child of the span
+// in its context, so statements made with a request's context appear under
+// that request. The SQL is recorded with placeholders, not values.
+func InstrumentDB(db *gorm.DB) error {
+	var system attribute.KeyValue
+	switch db.Dialector.Name() {
+	case "postgres":
+		system = semconv.DBSystemPostgreSQL
+	case "sqlite":
+		system = semconv.DBSystemSqlite
+	default:
+		system = semconv.DBSystemKey.String(db.Dialector.Name())
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:start", startSpan("create", system)),
+		callbacks.Create().After("gorm:create").Register("tracing:end", endSpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:start", startSpan("query", system)),
+		callbacks.Query().After("gorm:query").Register("tracing:end", endSpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:start", startSpan("update", system)),
+		callbacks.Update().After("gorm:update").Register("tracing:end", endSpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:start", startSpan("delete", system)),
+		callbacks.Delete().After("gorm:delete").Register("tracing:end", endSpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:start", startSpan("row", system)),
+		callbacks.Row().After("gorm:row").Register("tracing:end", endSpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:start", startSpan("raw", system)),
+		callbacks.Raw().After("gorm:raw").Register("tracing:end", endSpan),
+	)
+}
+
+// startSpan leaves the statement's context alone: nothing below it is
+// traced, and other plugins swap the context too.
+func startSpan(operation string, system attribute.KeyValue) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			return
+		}
+		_, span := tracer.Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(system, semconv.DBOperationName(operation)),
+		)
+		db.InstanceSet(statementSpan, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, _ := db.InstanceGet(statementSpan)
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,73 @@
+// Package tracing sets up OpenTelemetry tracing: a span for each HTTP
+// request, database statement and password hash, exported over OTLP or
+// printed for local debugging, with W3C trace context propagated from
+// callers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"to_do_api/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the global tracer provider TRACING_EXPORTER selects and the
+// W3C trace context propagator, and returns a function that flushes
+// pending spans and stops the provider. With no exporter, spans are not
+// recorded but incoming trace context is still passed on.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var export sdktrace.TracerProviderOption
+	switch cfg.TRACING_EXPORTER {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		// Print each span as it ends rather than in batches.
+		export = sdktrace.WithSyncer(exporter)
+	case "otlp":
+		// Without an endpoint, the OTEL_EXPORTER_OTLP_* environment
+		// variables apply, defaulting to localhost:4318.
+		var options []otlptracehttp.Option
+		if cfg.TRACING_OTLP_ENDPOINT != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.TRACING_OTLP_ENDPOINT))
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		export = sdktrace.WithBatcher(exporter)
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q: use otlp or stdout", cfg.TRACING_EXPORTER)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.TRACING_SERVICE_NAME)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		export,
+		sdktrace.WithResource(res),
+		// Follow the caller's decision, so a trace is kept or dropped whole.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TRACING_SAMPLE_RATIO))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
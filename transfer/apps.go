@@ -0,0 +1,193 @@
+package transfer
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Other to-do apps' export files are read by AppImporters, which map the
+// app's lists, labels, checklists and comments onto task fields. Apps have
+// their own importer rather than a column mapping because their exports are
+// shaped around their own concepts: Todoist nests tasks by indentation,
+// Trello keeps checklists and comments apart from cards.
+
+// AppSource is an export file from another app.
+type AppSource struct {
+	Data []byte
+	// Filename is the uploaded file's name, if any. Todoist exports one
+	// project per file, named after it.
+	Filename string
+	// Location is the time zone of dates without one.
+	Location *time.Location
+	// Now is when relative dates such as "tomorrow" are read.
+	Now time.Time
+}
+
+// AppImporter reads one app's exports.
+type AppImporter interface {
+	// Format is the file format the app exports: FormatCSV or FormatJSON.
+	Format() string
+	// Read converts an export into rows, like the other readers, and says
+	// how it was mapped.
+	Read(source AppSource) ([]Row, []Problem, AppReport)
+}
+
+// AppImporters are the importers by app name.
+var AppImporters = map[string]AppImporter{
+	"todoist":        todoistImporter{},
+	"trello":         trelloImporter{},
+	"microsoft-todo": microsoftTodoImporter{},
+}
+
+// AppNames lists the apps there are importers for.
+func AppNames() []string {
+	names := make([]string, 0, len(AppImporters))
+	for name := range AppImporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AppReport says how an app's export was mapped onto tasks: which of the
+// app's concepts became which task fields, what the rows hold, and what was
+// left out and why.
+type AppReport struct {
+	Mapping  map[string]string `json:"mapping"`
+	Tasks    int               `json:"tasks"`
+	Subtasks int               `json:"subtasks"`
+	Comments int               `json:"comments"`
+	Projects []string          `json:"projects"`
+	Labels   []string          `json:"labels"`
+	Skipped  map[string]int    `json:"skipped"`
+}
+
+func newAppReport(mapping map[string]string) AppReport {
+	return AppReport{Mapping: mapping, Projects: []string{}, Labels: []string{}, Skipped: map[string]int{}}
+}
+
+// summarize counts the tasks, subtasks, projects and labels in rows.
+func (r *AppReport) summarize(rows []Row) {
+	projects := map[string]bool{}
+	labels := map[string]bool{}
+	for _, row := range rows {
+		if row.ParentLine != 0 {
+			r.Subtasks++
+		} else {
+			r.Tasks++
+		}
+		if row.Project != "" {
+			projects[row.Project] = true
+		}
+		for _, label := range row.Labels {
+			labels[label] = true
+		}
+	}
+	r.Projects = sortedKeys(projects)
+	r.Labels = sortedKeys(labels)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appLabel turns an app's label or section name into a label, which cannot
+// contain spaces.
+func appLabel(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// appComment is a comment on a task. Tasks have no comments of their own,
+// so comments are kept at the end of the description.
+type appComment struct {
+	Author string
+	Date   time.Time
+	Text   string
+}
+
+func appendComments(description string, comments []appComment) string {
+	if len(comments) == 0 {
+		return description
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].Date.Before(comments[j].Date) })
+
+	var b strings.Builder
+	if description = strings.TrimRight(description, "\n"); description != "" {
+		b.WriteString(description)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Comments:")
+	for _, comment := range comments {
+		b.WriteString("\n- ")
+		if comment.Author != "" {
+			b.WriteString(comment.Author)
+			if !comment.Date.IsZero() {
+				b.WriteString(", ")
+			}
+		}
+		if !comment.Date.IsZero() {
+			b.WriteString(comment.Date.UTC().Format(dateOnlyLayout))
+		}
+		if comment.Author != "" || !comment.Date.IsZero() {
+			b.WriteString(": ")
+		}
+		b.WriteString(strings.ReplaceAll(strings.TrimSpace(comment.Text), "\n", "\n  "))
+	}
+	return b.String()
+}
+
+// columnIndex finds columns by name case-insensitively, as apps and their
+// versions differ in capitalisation.
+type columnIndex map[string]string
+
+func newColumnIndex(columns []string) columnIndex {
+	index := columnIndex{}
+	for _, column := range columns {
+		key := strings.ToLower(strings.TrimSpace(column))
+		if _, ok := index[key]; !ok {
+			index[key] = column
+		}
+	}
+	return index
+}
+
+// has reports whether any of names is a column.
+func (i columnIndex) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := i[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the record's value in the first of names that is a column.
+func (i columnIndex) get(record Record, names ...string) string {
+	for _, name := range names {
+		if column, ok := i[name]; ok {
+			return strings.TrimSpace(text(record.Values[column]))
+		}
+	}
+	return ""
+}
+
+// fileProject names a project after an export file, without its extension.
+func fileProject(filename string) string {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if base == "." || base == "/" {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(base, path.Ext(base)))
+}
+
+func notAnExport(app string, err error) []Problem {
+	return []Problem{{Error: fmt.Sprintf("not a %s export: %v", app, err)}}
+}
@@ -0,0 +1,169 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+)
+
+// Columns is the CSV export header. Imports map onto the same names by
+// default, so an export can be imported unchanged.
+var Columns = []string{
+	"id", "title", "description", "status", "due_date", "priority",
+	"project", "labels", "recurrence", "parent_id", "created_at", "updated_at",
+}
+
+// Encoder writes tasks one at a time. Close finishes the document; it does
+// not close the underlying writer.
+type Encoder interface {
+	Encode(task models.Task) error
+	Close() error
+}
+
+// NewEncoder returns an encoder for format. Formats that only hold dates
+// write them as days in loc.
+func NewEncoder(format string, w io.Writer, loc *time.Location) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatTodoTxt:
+		return &todoTxtEncoder{w: w, loc: loc}, nil
+	case FormatMarkdown:
+		return &markdownEncoder{w: w}, nil
+	case FormatICalendar:
+		return NewICalendarEncoder(w, false), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// ContentType is the media type of an export in format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatICalendar:
+		return "text/calendar; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// formulaPrefixes are the characters that make spreadsheets evaluate a cell
+// as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes a cell that would be evaluated as a formula with an
+// apostrophe, which spreadsheets show as text and readCSV removes again. A
+// cell that already starts with an apostrophe before such a character gets
+// another, so the escape can be undone exactly.
+func escapeFormula(cell string) string {
+	if startsFormula(cell) || strings.HasPrefix(cell, "'") && startsFormula(cell[1:]) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeFormula undoes escapeFormula.
+func unescapeFormula(cell string) string {
+	if strings.HasPrefix(cell, "'") && (startsFormula(cell[1:]) || strings.HasPrefix(cell[1:], "'") && startsFormula(cell[2:])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+func startsFormula(cell string) bool {
+	return cell != "" && strings.IndexByte(formulaPrefixes, cell[0]) >= 0
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) Encode(task models.Task) error {
+	if !e.headerWritten {
+		if err := e.w.Write(Columns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	dueDate := ""
+	if task.DueDate != nil {
+		dueDate = task.DueDate.UTC().Format(time.RFC3339)
+	}
+	parentID := ""
+	if task.ParentID != nil {
+		parentID = task.ParentID.String()
+	}
+	record := []string{
+		task.ID.String(),
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Status),
+		dueDate,
+		strconv.Itoa(task.Priority),
+		task.Project,
+		strings.Join(task.Labels, ","),
+		task.Recurrence,
+		parentID,
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.headerWritten {
+		if err := e.w.Write(Columns); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonEncoder writes a JSON array without holding it in memory.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(task models.Task) error {
+	separator := ","
+	if e.count == 0 {
+		separator = "["
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
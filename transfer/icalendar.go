@@ -0,0 +1,366 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/ical"
+	"to_do_api/models"
+	"to_do_api/recurrence"
+)
+
+// iCalendar (RFC 5545) maps tasks onto VTODOs:
+//
+//	UID            the task ID
+//	SUMMARY        title
+//	DESCRIPTION    description
+//	STATUS         COMPLETED when done, otherwise NEEDS-ACTION
+//	DUE            the due date, in UTC
+//	PRIORITY       1 high, 5 medium, 9 low; on import 1-4, 5 and 6-9
+//	CATEGORIES     labels
+//	RRULE          recurrence
+//	RELATED-TO     the parent task
+//
+// Projects have no iCalendar equivalent and are not exported. Calendar apps
+// that ignore VTODOs, such as Google Calendar, can be given a VEVENT at each
+// due date as well.
+
+const (
+	icalProductID = "-//to_do_api//Tasks//EN"
+
+	// eventUIDSuffix tells a due date VEVENT's UID from its task's.
+	eventUIDSuffix = "-due"
+)
+
+// iCalendar priorities written for each task priority.
+var icalPriorities = map[int]string{
+	models.PriorityHigh:   "1",
+	models.PriorityMedium: "5",
+	models.PriorityLow:    "9",
+}
+
+// NewICalendarEncoder returns an iCalendar encoder. With events, each task
+// with a due date also gets a VEVENT at that time.
+func NewICalendarEncoder(w io.Writer, events bool) Encoder {
+	return &icalEncoder{w: ical.NewWriter(w), events: events}
+}
+
+type icalEncoder struct {
+	w       *ical.Writer
+	events  bool
+	started bool
+}
+
+func (e *icalEncoder) start() {
+	if e.started {
+		return
+	}
+	e.started = true
+	beginVCALENDAR(e.w)
+	e.w.Text("X-WR-CALNAME", "Tasks")
+}
+
+func beginVCALENDAR(w *ical.Writer) {
+	w.Begin("VCALENDAR")
+	w.Property("VERSION", "2.0")
+	w.Property("PRODID", icalProductID)
+	w.Property("CALSCALE", "GREGORIAN")
+}
+
+func (e *icalEncoder) Encode(task models.Task) error {
+	e.start()
+	parentUID := ""
+	if task.ParentID != nil {
+		parentUID = task.ParentID.String()
+	}
+	writeVTODO(e.w, task, task.ID.String(), parentUID)
+
+	if e.events && task.DueDate != nil {
+		// A VEVENT with a DTSTART time and no DTEND or DURATION is an
+		// instant; TRANSPARENT keeps it from blocking free/busy time.
+		e.w.Begin("VEVENT")
+		e.w.Property("UID", task.ID.String()+eventUIDSuffix)
+		writeCommon(e.w, task)
+		e.w.Property("DTSTART", ical.FormatDateTime(*task.DueDate))
+		e.w.Property("TRANSP", "TRANSPARENT")
+		e.w.End("VEVENT")
+	}
+	return e.w.Flush()
+}
+
+func writeVTODO(w *ical.Writer, task models.Task, uid, parentUID string) {
+	w.Begin("VTODO")
+	w.Property("UID", uid)
+	writeCommon(w, task)
+	w.Property("CREATED", ical.FormatDateTime(task.CreatedAt))
+	w.Property("LAST-MODIFIED", ical.FormatDateTime(task.UpdatedAt))
+	w.Property("SEQUENCE", strconv.Itoa(max(task.Version-1, 0)))
+	if task.Status {
+		w.Property("STATUS", "COMPLETED")
+	} else {
+		w.Property("STATUS", "NEEDS-ACTION")
+	}
+	if task.DueDate != nil {
+		w.Property("DUE", ical.FormatDateTime(*task.DueDate))
+	}
+	if priority, ok := icalPriorities[task.Priority]; ok {
+		w.Property("PRIORITY", priority)
+	}
+	if parentUID != "" {
+		w.Property("RELATED-TO", parentUID)
+	}
+	w.End("VTODO")
+}
+
+// writeCommon writes the properties a task's VTODO and VEVENT share.
+func writeCommon(w *ical.Writer, task models.Task) {
+	w.Property("DTSTAMP", ical.FormatDateTime(task.UpdatedAt))
+	w.Text("SUMMARY", task.Title)
+	if task.Description != "" {
+		w.Text("DESCRIPTION", task.Description)
+	}
+	if len(task.Labels) > 0 {
+		w.Property("CATEGORIES", ical.JoinText(task.Labels))
+	}
+	if task.Recurrence != "" {
+		w.Property("RRULE", task.Recurrence)
+	}
+}
+
+func (e *icalEncoder) Close() error {
+	e.start()
+	e.w.End("VCALENDAR")
+	return e.w.Flush()
+}
+
+// ReadICalendar reads the VTODOs of an iCalendar file and, with events, its
+// VEVENTs, which are due when they start. Times without a zone and DATE
+// values are in loc; a DATE is due at the end of that day. Overrides of
+// single occurrences (RECURRENCE-ID) are skipped, since tasks recur as a
+// whole. Cancelled tasks are imported as done, and categories become labels
+// with spaces replaced by dashes.
+func ReadICalendar(data []byte, loc *time.Location, events bool) ([]Row, []Problem) {
+	components, err := ical.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, []Problem{{Error: err.Error()}}
+	}
+
+	var rows []Row
+	var problems []Problem
+	lines := map[string]int{}
+	parents := map[int]string{}
+	for _, calendar := range components {
+		if calendar.Name != "VCALENDAR" {
+			continue
+		}
+		zones := ical.NewZones(calendar)
+		for _, component := range calendar.Components {
+			if component.Name != "VTODO" && (component.Name != "VEVENT" || !events) {
+				continue
+			}
+			if _, ok := component.Get("RECURRENCE-ID"); ok {
+				continue
+			}
+
+			row, field, err := readICalComponent(component, zones, loc)
+			if err != nil {
+				problems = append(problems, Problem{Line: component.Line, Field: field, Error: err.Error()})
+				continue
+			}
+			row.Line = component.Line
+			rows = append(rows, row)
+
+			if uid, ok := component.Get("UID"); ok && uid.Value != "" {
+				lines[uid.Value] = row.Line
+			}
+			if parentUID := icalParentUID(component); parentUID != "" {
+				parents[row.Line] = parentUID
+			}
+		}
+	}
+
+	for i := range rows {
+		uid, ok := parents[rows[i].Line]
+		if !ok {
+			continue
+		}
+		// A parent outside the file is dropped; the task is imported at the
+		// top level.
+		if line, ok := lines[uid]; ok && line != rows[i].Line {
+			rows[i].ParentLine = line
+		}
+	}
+	return rows, problems
+}
+
+// ICalendarTask is the VTODO of a CalDAV resource.
+type ICalendarTask struct {
+	Row
+	UID       string
+	ParentUID string
+}
+
+// ReadICalendarTask reads a CalDAV resource: a calendar holding one VTODO,
+// not counting overrides of single occurrences, which are ignored. Times
+// without a zone are in loc.
+func ReadICalendarTask(data []byte, loc *time.Location) (ICalendarTask, error) {
+	components, err := ical.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ICalendarTask{}, err
+	}
+
+	var calendar, todo *ical.Component
+	for _, component := range components {
+		if component.Name == "VCALENDAR" {
+			calendar = component
+			break
+		}
+	}
+	if calendar == nil {
+		return ICalendarTask{}, errors.New("no VCALENDAR")
+	}
+	for _, component := range calendar.Components {
+		switch component.Name {
+		case "VTODO":
+			if _, ok := component.Get("RECURRENCE-ID"); ok {
+				continue
+			}
+			if todo != nil {
+				return ICalendarTask{}, errors.New("a resource holds one VTODO")
+			}
+			todo = component
+		case "VEVENT", "VJOURNAL":
+			return ICalendarTask{}, fmt.Errorf("only VTODOs can be stored, not %s", component.Name)
+		}
+	}
+	if todo == nil {
+		return ICalendarTask{}, errors.New("no VTODO")
+	}
+
+	uid, _ := todo.Get("UID")
+	if strings.TrimSpace(uid.Value) == "" {
+		return ICalendarTask{}, errors.New("the VTODO has no UID")
+	}
+	row, _, err := readICalComponent(todo, ical.NewZones(calendar), loc)
+	if err != nil {
+		return ICalendarTask{}, err
+	}
+	return ICalendarTask{Row: row, UID: uid.Value, ParentUID: icalParentUID(todo)}, nil
+}
+
+// WriteICalendarTask writes a calendar holding task alone, as a CalDAV
+// resource. uid and parentUID stand in for the task and parent IDs, since
+// CalDAV clients pick their own UIDs.
+func WriteICalendarTask(w io.Writer, task models.Task, uid, parentUID string) error {
+	iw := ical.NewWriter(w)
+	beginVCALENDAR(iw)
+	writeVTODO(iw, task, uid, parentUID)
+	iw.End("VCALENDAR")
+	return iw.Flush()
+}
+
+// icalParentUID returns the UID of a component's parent, if it names one.
+func icalParentUID(component *ical.Component) string {
+	for _, related := range component.All("RELATED-TO") {
+		if reltype := related.Params["RELTYPE"]; reltype == "" || strings.EqualFold(reltype, "PARENT") {
+			return related.Value
+		}
+	}
+	return ""
+}
+
+func readICalComponent(component *ical.Component, zones *ical.Zones, loc *time.Location) (Row, string, error) {
+	var row Row
+	if summary, ok := component.Get("SUMMARY"); ok {
+		row.Title = strings.TrimSpace(ical.UnescapeText(summary.Value))
+	}
+	if description, ok := component.Get("DESCRIPTION"); ok {
+		row.Description = ical.UnescapeText(description.Value)
+	}
+
+	if status, ok := component.Get("STATUS"); ok {
+		switch strings.ToUpper(status.Value) {
+		case "COMPLETED", "CANCELLED":
+			row.Status = true
+		}
+	}
+	if _, ok := component.Get("COMPLETED"); ok && component.Name == "VTODO" {
+		row.Status = true
+	}
+
+	due, ok := component.Get("DUE")
+	if !ok || component.Name == "VEVENT" {
+		due, ok = component.Get("DTSTART")
+	}
+	if ok {
+		t, dateOnly, err := ical.ParseTime(due, zones, loc)
+		if err != nil {
+			return Row{}, "due_date", err
+		}
+		if dateOnly {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 0, 0, loc)
+		}
+		t = t.UTC()
+		row.DueDate = &t
+	}
+
+	if priority, ok := component.Get("PRIORITY"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(priority.Value))
+		if err != nil || n < 0 || n > 9 {
+			return Row{}, "priority", fmt.Errorf("invalid PRIORITY %q", priority.Value)
+		}
+		switch {
+		case n == 0:
+			row.Priority = models.PriorityNone
+		case n < 5:
+			row.Priority = models.PriorityHigh
+		case n == 5:
+			row.Priority = models.PriorityMedium
+		default:
+			row.Priority = models.PriorityLow
+		}
+	}
+
+	for _, categories := range component.All("CATEGORIES") {
+		for _, category := range ical.SplitText(categories.Value) {
+			if label := strings.Join(strings.Fields(category), "-"); label != "" {
+				row.Labels = append(row.Labels, label)
+			}
+		}
+	}
+
+	if rrule, ok := component.Get("RRULE"); ok {
+		value, err := icalRecurrence(rrule.Value)
+		if err != nil {
+			return Row{}, "recurrence", err
+		}
+		row.Recurrence = value
+	}
+	return row, "", nil
+}
+
+// icalRecurrence keeps the parts of an RRULE tasks support. WKST is dropped,
+// as weeks always start on Monday; COUNT and UNTIL would change which
+// occurrences there are, so they are reported instead.
+func icalRecurrence(value string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(strings.ToUpper(value), ";") {
+		key, _, _ := strings.Cut(part, "=")
+		switch key {
+		case "WKST":
+			continue
+		case "COUNT", "UNTIL":
+			return "", errors.New("recurrences that end (COUNT or UNTIL) are not supported")
+		}
+		parts = append(parts, part)
+	}
+	rule, err := recurrence.Parse(strings.Join(parts, ";"))
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
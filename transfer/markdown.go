@@ -0,0 +1,171 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+)
+
+// Markdown checklists follow GitHub's task list syntax. Subtasks are nested
+// two spaces deeper than their parent, and a task's description follows it
+// as indented lines:
+//
+//	- [ ] Plan trip
+//	  Book before the end of the month.
+//	  - [x] Book flights
+//	  - [ ] Book hotel
+//
+// Other fields have no checklist syntax and are not exported.
+
+var (
+	checklistItem    = regexp.MustCompile(`^([ \t]*)[-*+] \[([ xX])\](?: (.*))?$`)
+	listMarkerEscape = regexp.MustCompile(`^[-*+] `)
+)
+
+// markdownEncoder needs every task before it can nest them, so unlike the
+// other encoders it holds the export in memory and writes it on Close.
+type markdownEncoder struct {
+	w     io.Writer
+	tasks []models.Task
+}
+
+func (e *markdownEncoder) Encode(task models.Task) error {
+	e.tasks = append(e.tasks, task)
+	return nil
+}
+
+func (e *markdownEncoder) Close() error {
+	present := make(map[uuid.UUID]bool, len(e.tasks))
+	for _, task := range e.tasks {
+		present[task.ID] = true
+	}
+	children := map[uuid.UUID][]models.Task{}
+	var roots []models.Task
+	for _, task := range e.tasks {
+		// Subtasks whose parent was filtered out are listed at the top.
+		if task.ParentID != nil && present[*task.ParentID] {
+			children[*task.ParentID] = append(children[*task.ParentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	w := bufio.NewWriter(e.w)
+	var write func(task models.Task, depth int)
+	write = func(task models.Task, depth int) {
+		indent := strings.Repeat("  ", depth)
+		box := "[ ]"
+		if task.Status {
+			box = "[x]"
+		}
+		w.WriteString(indent + "- " + box + " " + strings.Join(strings.Fields(task.Title), " ") + "\n")
+
+		if task.Description != "" {
+			for _, line := range strings.Split(strings.TrimRight(task.Description, "\n"), "\n") {
+				line = strings.TrimRight(line, " \t\r")
+				if line == "" {
+					w.WriteString("\n")
+					continue
+				}
+				if listMarkerEscape.MatchString(line) {
+					line = `\` + line
+				}
+				w.WriteString(indent + "  " + line + "\n")
+			}
+		}
+
+		for _, child := range children[task.ID] {
+			write(child, depth+1)
+		}
+	}
+	for _, task := range roots {
+		write(task, 0)
+	}
+	return w.Flush()
+}
+
+// ReadMarkdown reads the checklist items of a Markdown file, keeping their
+// nesting as ParentLine. Lines outside checklists are ignored.
+func ReadMarkdown(data []byte) ([]Row, []Problem) {
+	type open struct {
+		indent int
+		row    int
+	}
+
+	var rows []Row
+	var stack []open
+	blankLines := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" {
+			blankLines++
+			continue
+		}
+
+		if match := checklistItem.FindStringSubmatch(text); match != nil {
+			indent := indentWidth(match[1])
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			row := Row{Line: line, Title: strings.TrimSpace(match[3]), Status: match[2] != " "}
+			if len(stack) > 0 {
+				row.ParentLine = rows[stack[len(stack)-1].row].Line
+			}
+			rows = append(rows, row)
+			stack = append(stack, open{indent: indent, row: len(rows) - 1})
+			blankLines = 0
+			continue
+		}
+
+		trimmed := strings.TrimLeft(text, " \t")
+		indent := indentWidth(text[:len(text)-len(trimmed)])
+		if len(stack) == 0 || indent <= stack[len(stack)-1].indent {
+			// Prose or a heading ends the list.
+			stack = nil
+			blankLines = 0
+			continue
+		}
+
+		// Keep indentation beyond the item's own, as in code blocks.
+		content := trimmed
+		if strip := stack[len(stack)-1].indent + 2; !strings.ContainsRune(text, '\t') {
+			content = strings.TrimPrefix(text, strings.Repeat(" ", min(strip, len(text)-len(trimmed))))
+		}
+		if strings.HasPrefix(content, `\`) && listMarkerEscape.MatchString(content[1:]) {
+			content = content[1:]
+		}
+
+		row := &rows[stack[len(stack)-1].row]
+		if row.Description != "" {
+			row.Description += strings.Repeat("\n", blankLines+1)
+		}
+		row.Description += content
+		blankLines = 0
+	}
+
+	var problems []Problem
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, Problem{Error: err.Error()})
+	}
+	return rows, problems
+}
+
+func indentWidth(whitespace string) int {
+	width := 0
+	for _, r := range whitespace {
+		if r == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width
+}
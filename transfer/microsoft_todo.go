@@ -0,0 +1,191 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"to_do_api/models"
+)
+
+// Microsoft To Do has no export of its own; the CSV files of tools that
+// export it, and of Outlook's task export, use columns such as:
+//
+//	List, Folder                 project
+//	Title, Subject               title
+//	Notes, Body                  description
+//	Due Date                     due date, ISO or US (MM/DD/YYYY)
+//	Status, Date Completed       done if Completed, or if a date is set
+//	Importance, Priority         High, Normal or Low
+//	Categories                   labels, separated by ; or ,
+//	Steps                        subtasks, one per line or separated by ;
+//	                             and done if they start with [x]
+//
+// Steps are numbered after the file's last line.
+
+var microsoftTodoMapping = map[string]string{
+	"list":       "project",
+	"title":      "title",
+	"notes":      "description",
+	"due date":   "due_date",
+	"status":     "status",
+	"importance": "priority",
+	"categories": "labels",
+	"steps":      "subtask",
+}
+
+var (
+	microsoftTodoTitle    = []string{"title", "subject", "task", "name"}
+	microsoftTodoList     = []string{"list", "list name", "folder"}
+	microsoftTodoNotes    = []string{"notes", "body", "note"}
+	microsoftTodoDue      = []string{"due date", "duedate", "due", "duedatetime"}
+	microsoftTodoStatus   = []string{"status", "completed", "is completed"}
+	microsoftTodoDone     = []string{"date completed", "completed date", "completeddatetime"}
+	microsoftTodoPriority = []string{"importance", "priority"}
+	microsoftTodoLabels   = []string{"categories", "category"}
+	microsoftTodoSteps    = []string{"steps", "checklist", "checklist items"}
+)
+
+// Outlook writes dates in US order.
+var usDateLayouts = []string{"1/2/2006 3:04:05 PM", "1/2/2006 3:04 PM", "1/2/2006 15:04"}
+
+const usDateOnlyLayout = "1/2/2006"
+
+var microsoftTodoPriorities = map[string]int{
+	"":       models.PriorityNone,
+	"normal": models.PriorityNone,
+	"low":    models.PriorityLow,
+	"medium": models.PriorityMedium,
+	"high":   models.PriorityHigh,
+}
+
+type microsoftTodoImporter struct{}
+
+func (microsoftTodoImporter) Format() string { return FormatCSV }
+
+func (microsoftTodoImporter) Read(source AppSource) ([]Row, []Problem, AppReport) {
+	report := newAppReport(microsoftTodoMapping)
+	table, err := ReadTable(FormatCSV, source.Data)
+	if err != nil {
+		return nil, notAnExport("Microsoft To Do", err), report
+	}
+	columns := newColumnIndex(table.Columns)
+	if !columns.has(microsoftTodoTitle...) {
+		return nil, notAnExport("Microsoft To Do", errors.New("no Title or Subject column")), report
+	}
+
+	next := 0
+	for _, record := range table.Records {
+		next = max(next, record.Line)
+	}
+
+	var rows []Row
+	var problems []Problem
+	for _, record := range table.Records {
+		if isBlankRecord(record) {
+			continue
+		}
+		row := Row{
+			Line:        record.Line,
+			Title:       columns.get(record, microsoftTodoTitle...),
+			Description: columns.get(record, microsoftTodoNotes...),
+			Project:     columns.get(record, microsoftTodoList...),
+		}
+
+		ok := true
+		status := strings.ToLower(columns.get(record, microsoftTodoStatus...))
+		switch status {
+		case "completed", "complete", "done", "true", "yes", "1":
+			row.Status = true
+		}
+		if completed := columns.get(record, microsoftTodoDone...); completed != "" && !isZeroUSDate(completed) {
+			row.Status = true
+		}
+
+		priority, known := microsoftTodoPriorities[strings.ToLower(columns.get(record, microsoftTodoPriority...))]
+		if !known {
+			problems = append(problems, Problem{Line: record.Line, Field: "priority", Error: "importance must be High, Normal or Low"})
+			ok = false
+		}
+		row.Priority = priority
+
+		for _, category := range strings.FieldsFunc(columns.get(record, microsoftTodoLabels...), func(r rune) bool { return r == ',' || r == ';' }) {
+			if label := appLabel(category); label != "" {
+				row.Labels = append(row.Labels, label)
+			}
+		}
+
+		due, err := parseUSDate(columns.get(record, microsoftTodoDue...), source.Location)
+		if err != nil {
+			problems = append(problems, Problem{Line: record.Line, Field: "due_date", Error: err.Error()})
+			ok = false
+		}
+		row.DueDate = due
+
+		if !ok {
+			continue
+		}
+		rows = append(rows, row)
+
+		for _, step := range strings.FieldsFunc(columns.get(record, microsoftTodoSteps...), func(r rune) bool { return r == '\n' || r == ';' }) {
+			title, done := readStep(step)
+			if title == "" {
+				continue
+			}
+			next++
+			rows = append(rows, Row{Line: next, ParentLine: row.Line, Title: title, Status: done, Project: row.Project})
+		}
+	}
+
+	report.summarize(rows)
+	return rows, problems, report
+}
+
+func isBlankRecord(record Record) bool {
+	for _, value := range record.Values {
+		if strings.TrimSpace(text(value)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readStep reads a step, which is done if it starts with [x].
+func readStep(step string) (string, bool) {
+	step = strings.TrimSpace(step)
+	lower := strings.ToLower(step)
+	switch {
+	case strings.HasPrefix(lower, "[x]"):
+		return strings.TrimSpace(step[3:]), true
+	case strings.HasPrefix(lower, "[ ]"):
+		return strings.TrimSpace(step[3:]), false
+	}
+	return step, false
+}
+
+// parseUSDate reads the dates ParseDate does and US month/day/year dates.
+func parseUSDate(value string, loc *time.Location) (*time.Time, error) {
+	if t, err := ParseDate(value, loc); err == nil {
+		return t, nil
+	}
+	value = strings.TrimSpace(value)
+	if isZeroUSDate(value) {
+		return nil, nil
+	}
+	for _, layout := range usDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	if day, err := time.ParseInLocation(usDateOnlyLayout, value, loc); err == nil {
+		t := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, loc).UTC()
+		return &t, nil
+	}
+	return nil, fmt.Errorf("cannot read %q as a date; use YYYY-MM-DD or MM/DD/YYYY", value)
+}
+
+// isZeroUSDate reports Outlook's way of writing no date, 0/0/00.
+func isZeroUSDate(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "0/0/")
+}
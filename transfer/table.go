@@ -0,0 +1,205 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Table is a CSV or JSON import before mapping: named columns and one record
+// per row. CSV values are strings; JSON values keep their JSON type, with
+// numbers as json.Number.
+type Table struct {
+	Columns []string
+	Records []Record
+}
+
+type Record struct {
+	Line   int
+	Values map[string]interface{}
+}
+
+// ReadTable parses a CSV file with a header row, or a JSON array of objects.
+func ReadTable(format string, data []byte) (Table, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatJSON:
+		return readJSON(data)
+	}
+	return Table{}, fmt.Errorf("unsupported import format %q", format)
+}
+
+func readCSV(data []byte) (Table, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return Table{}, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return Table{}, fmt.Errorf("invalid CSV: %w", err)
+	}
+	table := Table{}
+	for _, column := range header {
+		table.Columns = append(table.Columns, strings.TrimSpace(column))
+	}
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return Table{}, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]interface{}, len(fields))
+		for i, field := range fields {
+			if i < len(table.Columns) {
+				values[table.Columns[i]] = unescapeFormula(field)
+			}
+		}
+		table.Records = append(table.Records, Record{Line: line, Values: values})
+	}
+}
+
+func readJSON(data []byte) (Table, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return Table{}, fmt.Errorf("invalid JSON: expected an array of objects: %w", err)
+	}
+
+	table := Table{}
+	seen := map[string]bool{}
+	for i, object := range objects {
+		var keys []string
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		table.Columns = append(table.Columns, keys...)
+		table.Records = append(table.Records, Record{Line: i + 1, Values: object})
+	}
+	return table, nil
+}
+
+// Fields are the task fields an import can map onto.
+var Fields = []string{"title", "description", "status", "due_date", "priority", "project", "labels", "recurrence"}
+
+var fieldAliases = map[string][]string{
+	"title":       {"title", "name", "task", "task name", "summary", "subject", "content"},
+	"description": {"description", "notes", "note", "details", "body"},
+	"status":      {"status", "done", "completed", "complete", "is completed"},
+	"due_date":    {"due_date", "due date", "due", "deadline", "due at"},
+	"priority":    {"priority", "importance"},
+	"project":     {"project", "list", "folder"},
+	"labels":      {"labels", "label", "tags", "tag"},
+	"recurrence":  {"recurrence", "repeat", "rrule"},
+}
+
+// SuggestMapping matches columns to fields by name, case-insensitively and
+// ignoring "_" versus " ".
+func SuggestMapping(columns []string) map[string]string {
+	mapping := map[string]string{}
+	for _, field := range Fields {
+		for _, column := range columns {
+			if matchesAlias(field, column) {
+				mapping[field] = column
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+func matchesAlias(field, column string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(column), "_", " "))
+	for _, alias := range fieldAliases[field] {
+		if normalized == strings.ReplaceAll(alias, "_", " ") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMapping verifies that mapping names known fields and existing columns
+// and maps the title.
+func CheckMapping(mapping map[string]string, columns []string) error {
+	known := map[string]bool{}
+	for _, field := range Fields {
+		known[field] = true
+	}
+	present := map[string]bool{}
+	for _, column := range columns {
+		present[column] = true
+	}
+
+	for field, column := range mapping {
+		if !known[field] {
+			return fmt.Errorf("unknown field %q in mapping; fields are %s", field, strings.Join(Fields, ", "))
+		}
+		if !present[column] {
+			return fmt.Errorf("column %q mapped to %s is not in the file", column, field)
+		}
+	}
+	if mapping["title"] == "" {
+		return errors.New("mapping must include a column for title")
+	}
+	return nil
+}
+
+// Convert applies mapping to every record. Dates without a zone are read in
+// loc; dates without a time are due at the end of that day. When the file
+// has both id and parent_id columns, as exports do, parent_id links rows to
+// their parents in the same file.
+func Convert(table Table, mapping map[string]string, loc *time.Location) ([]Row, []Problem) {
+	lines := map[string]int{}
+	for _, record := range table.Records {
+		if id := strings.TrimSpace(text(record.Values["id"])); id != "" {
+			lines[id] = record.Line
+		}
+	}
+
+	var rows []Row
+	var problems []Problem
+	for _, record := range table.Records {
+		row := Row{Line: record.Line}
+		ok := true
+		if parentID := strings.TrimSpace(text(record.Values["parent_id"])); parentID != "" && len(lines) > 0 {
+			if row.ParentLine = lines[parentID]; row.ParentLine == 0 {
+				problems = append(problems, Problem{Line: record.Line, Field: "parent_id", Error: "parent " + parentID + " is not in this import"})
+				ok = false
+			}
+		}
+		for _, field := range Fields {
+			column, mapped := mapping[field]
+			if !mapped {
+				continue
+			}
+			if err := setField(&row, field, record.Values[column], loc); err != nil {
+				problems = append(problems, Problem{Line: record.Line, Field: field, Error: err.Error()})
+				ok = false
+			}
+		}
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, problems
+}
@@ -0,0 +1,175 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+	"to_do_api/quickadd"
+)
+
+// Todoist exports a project as a CSV file named after it, one row per task,
+// section or comment:
+//
+//	TYPE         task, section, note (a comment on the task above) or meta
+//	CONTENT      the title, with @labels in it
+//	DESCRIPTION  description
+//	PRIORITY     1 (p1, the highest) to 4 (none)
+//	INDENT       1 for a task, 2 for its subtasks and so on
+//	DATE         the due date in Todoist's own words, such as "every monday"
+//	DEADLINE     a date, used when there is no DATE
+//	TIMEZONE     the zone of DATE
+//
+// Tasks in a section get it as a label. Completed tasks are not exported by
+// Todoist.
+
+var todoistMapping = map[string]string{
+	"project":  "project",
+	"section":  "labels",
+	"@label":   "labels",
+	"priority": "priority",
+	"indent":   "subtask",
+	"date":     "due_date, recurrence",
+	"deadline": "due_date",
+	"note":     "description",
+}
+
+var todoistLabel = regexp.MustCompile(`(^|\s)@([\p{L}\p{N}_-]+)`)
+
+// Todoist priorities, p1 to p4.
+var todoistPriorities = map[string]int{
+	"1": models.PriorityHigh,
+	"2": models.PriorityMedium,
+	"3": models.PriorityLow,
+	"4": models.PriorityNone,
+}
+
+type todoistImporter struct{}
+
+func (todoistImporter) Format() string { return FormatCSV }
+
+func (todoistImporter) Read(source AppSource) ([]Row, []Problem, AppReport) {
+	report := newAppReport(todoistMapping)
+	table, err := ReadTable(FormatCSV, source.Data)
+	if err != nil {
+		return nil, notAnExport("Todoist", err), report
+	}
+	columns := newColumnIndex(table.Columns)
+	if !columns.has("type") || !columns.has("content") {
+		return nil, notAnExport("Todoist", errors.New("no TYPE and CONTENT columns")), report
+	}
+
+	project := fileProject(source.Filename)
+	var rows []Row
+	var problems []Problem
+	var section string
+	// parents holds the line of the last task at each indent, and comments
+	// the comments of the last task.
+	var parents []int
+	last := -1
+	var comments []appComment
+	flush := func() {
+		if last >= 0 {
+			rows[last].Description = appendComments(rows[last].Description, comments)
+		}
+		comments = nil
+	}
+
+	for _, record := range table.Records {
+		content := columns.get(record, "content")
+		switch strings.ToLower(columns.get(record, "type")) {
+		case "task":
+		case "section":
+			section = appLabel(content)
+			continue
+		case "note":
+			if last < 0 {
+				report.Skipped["comment without a task"]++
+				continue
+			}
+			comments = append(comments, appComment{Author: columns.get(record, "author"), Text: content})
+			report.Comments++
+			continue
+		case "", "meta":
+			continue
+		default:
+			report.Skipped["unknown row type"]++
+			continue
+		}
+		flush()
+
+		row, err := readTodoistTask(record, columns, source)
+		if err != nil {
+			problems = append(problems, Problem{Line: record.Line, Field: "due_date", Error: err.Error()})
+			last = -1
+			continue
+		}
+		row.Line = record.Line
+		row.Project = project
+		if section != "" {
+			row.Labels = append(row.Labels, section)
+		}
+
+		indent, err := strconv.Atoi(columns.get(record, "indent"))
+		if err != nil || indent < 1 {
+			indent = 1
+		}
+		if indent > len(parents)+1 {
+			indent = len(parents) + 1
+		}
+		parents = parents[:indent-1]
+		if len(parents) > 0 {
+			row.ParentLine = parents[len(parents)-1]
+		}
+		parents = append(parents, row.Line)
+
+		rows = append(rows, row)
+		last = len(rows) - 1
+	}
+	flush()
+
+	report.summarize(rows)
+	return rows, problems, report
+}
+
+func readTodoistTask(record Record, columns columnIndex, source AppSource) (Row, error) {
+	row := Row{Description: columns.get(record, "description")}
+
+	title := todoistLabel.ReplaceAllStringFunc(columns.get(record, "content"), func(match string) string {
+		label := todoistLabel.FindStringSubmatch(match)[2]
+		row.Labels = append(row.Labels, label)
+		return ""
+	})
+	row.Title = strings.Join(strings.Fields(title), " ")
+	row.Priority = todoistPriorities[columns.get(record, "priority")]
+
+	loc := source.Location
+	if name := columns.get(record, "timezone"); name != "" {
+		if zone, err := time.LoadLocation(name); err == nil {
+			loc = zone
+		}
+	}
+	value := columns.get(record, "date")
+	if value == "" {
+		value = columns.get(record, "deadline")
+	}
+	if value == "" {
+		return row, nil
+	}
+	if due, err := ParseDate(value, loc); err == nil {
+		row.DueDate = due
+		return row, nil
+	}
+
+	// Todoist writes dates as they were typed, which quick add reads too.
+	result := quickadd.Parse(value, source.Now.In(loc))
+	if strings.TrimSpace(result.Title) != "" || (result.DueDate == nil && result.Recurrence == "") {
+		return Row{}, fmt.Errorf("cannot read date %q", value)
+	}
+	row.DueDate = result.DueDate
+	row.Recurrence = result.Recurrence
+	return row, nil
+}
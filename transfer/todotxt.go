@@ -0,0 +1,217 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+	"to_do_api/recurrence"
+)
+
+// todo.txt (https://github.com/todotxt/todo.txt) maps onto tasks as:
+//
+//	x               status done
+//	(A) (B) (C)     priority high, medium, low; D-Z read as low
+//	pri:A           the priority of a done task, which loses its (A)
+//	+project        the project; further +projects stay in the title
+//	@context        a label
+//	due:2026-10-25  the due date, a day in the export's time zone
+//	rec:2w          a recurrence without BYDAY, as FREQ and INTERVAL
+//
+// Other key:value pairs stay in the title. todo.txt has no descriptions,
+// times of day or subtasks, so those are not exported.
+//
+// todo.txt has no escaping of its own, so a title word that would be read as
+// one of the above, such as "+1", "@bob", "due:tomorrow", or a leading "x",
+// "(A)" or date, is exported with a backslash in front ("\+1"), which
+// ReadTodoTxt removes. Words that start with a backslash get another one.
+
+var (
+	todoPriority   = regexp.MustCompile(`^\(([A-Z])\) `)
+	todoDate       = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `)
+	todoKeyValue   = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):(\S+)$`)
+	todoRecurrence = regexp.MustCompile(`^\+?(\d+)([dwmy])$`)
+	todoLeading    = regexp.MustCompile(`^(x|\([A-Z]\)|\d{4}-\d{2}-\d{2})$`)
+)
+
+var todoPriorityLetters = map[int]string{
+	models.PriorityHigh:   "A",
+	models.PriorityMedium: "B",
+	models.PriorityLow:    "C",
+}
+
+var todoFrequencies = map[string]string{
+	"d": recurrence.Daily,
+	"w": recurrence.Weekly,
+	"m": recurrence.Monthly,
+	"y": recurrence.Yearly,
+}
+
+type todoTxtEncoder struct {
+	w   io.Writer
+	loc *time.Location
+}
+
+func (e *todoTxtEncoder) Encode(task models.Task) error {
+	_, err := io.WriteString(e.w, TodoTxtLine(task, e.loc)+"\n")
+	return err
+}
+
+func (e *todoTxtEncoder) Close() error {
+	return nil
+}
+
+// TodoTxtLine formats one task as a todo.txt line.
+func TodoTxtLine(task models.Task, loc *time.Location) string {
+	var parts []string
+	letter := todoPriorityLetters[task.Priority]
+	switch {
+	case task.Status:
+		parts = append(parts, "x")
+	case letter != "":
+		parts = append(parts, "("+letter+")")
+	}
+
+	title := strings.Fields(task.Title)
+	for i, word := range title {
+		if todoSpecial(word, i == 0) {
+			title[i] = `\` + word
+		}
+	}
+	parts = append(parts, strings.Join(title, " "))
+	if task.Project != "" {
+		parts = append(parts, "+"+strings.Join(strings.Fields(task.Project), "_"))
+	}
+	for _, label := range task.Labels {
+		parts = append(parts, "@"+label)
+	}
+	if task.DueDate != nil {
+		parts = append(parts, "due:"+task.DueDate.In(loc).Format(dateOnlyLayout))
+	}
+	if rule, err := recurrence.Parse(task.Recurrence); err == nil && len(rule.ByDay) == 0 {
+		for unit, freq := range todoFrequencies {
+			if freq == rule.Freq {
+				parts = append(parts, "rec:"+strconv.Itoa(rule.Interval)+unit)
+			}
+		}
+	}
+	if task.Status && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ReadTodoTxt parses a todo.txt file. Due dates are days in loc.
+func ReadTodoTxt(data []byte, loc *time.Location) ([]Row, []Problem) {
+	var rows []Row
+	var problems []Problem
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row, err := parseTodoTxtLine(text, loc)
+		if err != nil {
+			problems = append(problems, Problem{Line: line, Error: err.Error()})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, Problem{Error: err.Error()})
+	}
+	return rows, problems
+}
+
+func parseTodoTxtLine(text string, loc *time.Location) (Row, error) {
+	var row Row
+	if strings.HasPrefix(text, "x ") {
+		row.Status = true
+		text = strings.TrimPrefix(text, "x ")
+		// Completion date, then creation date.
+		for i := 0; i < 2 && todoDate.MatchString(text); i++ {
+			text = text[len("2006-01-02 "):]
+		}
+	}
+	if match := todoPriority.FindStringSubmatch(text); match != nil {
+		row.Priority = priorityFromLetter(match[1])
+		text = text[len(match[0]):]
+	}
+	if todoDate.MatchString(text) {
+		text = text[len("2006-01-02 "):]
+	}
+
+	var title []string
+	for i, word := range strings.Fields(text) {
+		switch {
+		case strings.HasPrefix(word, `\`) && todoSpecial(word[1:], i == 0):
+			title = append(title, word[1:])
+		case len(word) > 1 && word[0] == '+' && row.Project == "":
+			row.Project = word[1:]
+		case len(word) > 1 && word[0] == '@':
+			row.Labels = append(row.Labels, word[1:])
+		case todoKeyValue.MatchString(word):
+			match := todoKeyValue.FindStringSubmatch(word)
+			switch key, value := strings.ToLower(match[1]), match[2]; key {
+			case "due":
+				day, err := time.ParseInLocation(dateOnlyLayout, value, loc)
+				if err != nil {
+					return Row{}, fmt.Errorf("invalid due date %q", value)
+				}
+				due := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, loc).UTC()
+				row.DueDate = &due
+			case "rec":
+				rec := todoRecurrence.FindStringSubmatch(value)
+				if rec == nil {
+					return Row{}, fmt.Errorf("invalid recurrence %q", value)
+				}
+				row.Recurrence = "FREQ=" + todoFrequencies[rec[2]] + ";INTERVAL=" + rec[1]
+			case "pri":
+				if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+					return Row{}, fmt.Errorf("invalid priority %q", value)
+				}
+				row.Priority = priorityFromLetter(value)
+			default:
+				title = append(title, word)
+			}
+		default:
+			title = append(title, word)
+		}
+	}
+
+	row.Title = strings.Join(title, " ")
+	return row, nil
+}
+
+// todoSpecial reports whether word would not be read back as a plain title
+// word, first saying whether it starts the title.
+func todoSpecial(word string, first bool) bool {
+	if strings.HasPrefix(word, `\`) || len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+		return true
+	}
+	if match := todoKeyValue.FindStringSubmatch(word); match != nil {
+		switch strings.ToLower(match[1]) {
+		case "due", "rec", "pri":
+			return true
+		}
+	}
+	return first && todoLeading.MatchString(word)
+}
+
+func priorityFromLetter(letter string) int {
+	for priority, l := range todoPriorityLetters {
+		if l == letter {
+			return priority
+		}
+	}
+	return models.PriorityLow
+}
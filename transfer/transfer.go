@@ -0,0 +1,92 @@
+// Package transfer converts tasks to and from interchange formats. Exports
+// are written one task at a time so they can be streamed. Imports produce
+// Rows, which the caller validates and commits, plus Problems found while
+// reading.
+package transfer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV       = "csv"
+	FormatJSON      = "json"
+	FormatTodoTxt   = "todotxt"
+	FormatMarkdown  = "markdown"
+	FormatICalendar = "ics"
+)
+
+// formatAliases maps file extensions and media subtypes onto formats.
+var formatAliases = map[string]string{
+	"txt":      FormatTodoTxt,
+	"todo.txt": FormatTodoTxt,
+	"plain":    FormatTodoTxt,
+	"md":       FormatMarkdown,
+	"calendar": FormatICalendar,
+	"ical":     FormatICalendar,
+	"ifb":      FormatICalendar,
+}
+
+// Row is one task read from an import, before validation by the caller.
+// Line is the line or record number it came from; ParentLine, if not zero,
+// is the Line of its parent task in the same import.
+type Row struct {
+	Line        int
+	ParentLine  int
+	Title       string
+	Description string
+	Status      bool
+	DueDate     *time.Time
+	Priority    int
+	Project     string
+	Labels      []string
+	Recurrence  string
+}
+
+// Problem is a validation error for one row and, where known, one field.
+type Problem struct {
+	Line  int    `json:"line"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// DetectFormat picks a format from an explicit name, falling back to the
+// uploaded file's extension and then its content type.
+func DetectFormat(explicit, filename, contentType string, formats ...string) (string, error) {
+	candidates := []string{
+		strings.ToLower(explicit),
+		strings.TrimPrefix(strings.ToLower(path.Ext(filename)), "."),
+	}
+	if mediaType, _, _ := strings.Cut(contentType, ";"); mediaType != "" {
+		candidates = append(candidates, strings.TrimPrefix(strings.TrimSpace(mediaType), "text/"), strings.TrimPrefix(strings.TrimSpace(mediaType), "application/"))
+	}
+
+	for i, candidate := range candidates {
+		if alias, ok := formatAliases[candidate]; ok {
+			candidate = alias
+		}
+		for _, format := range formats {
+			if candidate == format {
+				return format, nil
+			}
+		}
+		if i == 0 && candidate != "" {
+			return "", fmt.Errorf("unsupported format %q", explicit)
+		}
+	}
+	return "", fmt.Errorf("cannot tell the format; pass one of %s", strings.Join(formats, ", "))
+}
+
+// Filename is the suggested download name for an export in format.
+func Filename(format string) string {
+	switch format {
+	case FormatTodoTxt:
+		return "todo.txt"
+	case FormatMarkdown:
+		return "tasks.md"
+	}
+	return "tasks." + format
+}
@@ -0,0 +1,169 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// A Trello board export (Menu > Print, export and share > Export as JSON)
+// maps onto tasks as:
+//
+//	list             project
+//	card             task; done if its due date is marked complete
+//	label            a label, named after its colour if it has no name
+//	checklist item   a subtask
+//	comment          appended to the description
+//
+// Archived cards and cards in archived lists are skipped. Trello only
+// exports a board's latest 1000 actions, so older comments can be missing.
+// Rows are numbered in file order, each card followed by its checklist
+// items.
+
+var trelloMapping = map[string]string{
+	"list":           "project",
+	"card":           "task",
+	"desc":           "description",
+	"due":            "due_date",
+	"dueComplete":    "status",
+	"label":          "labels",
+	"checklist item": "subtask",
+	"comment":        "description",
+}
+
+type trelloBoard struct {
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Desc        string `json:"desc"`
+		IDList      string `json:"idList"`
+		Closed      bool   `json:"closed"`
+		Due         string `json:"due"`
+		DueComplete bool   `json:"dueComplete"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Due   string  `json:"due"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string    `json:"type"`
+		Date time.Time `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+		MemberCreator struct {
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
+	} `json:"actions"`
+}
+
+type trelloImporter struct{}
+
+func (trelloImporter) Format() string { return FormatJSON }
+
+func (trelloImporter) Read(source AppSource) ([]Row, []Problem, AppReport) {
+	report := newAppReport(trelloMapping)
+	var board trelloBoard
+	if err := json.NewDecoder(bytes.NewReader(source.Data)).Decode(&board); err != nil {
+		return nil, notAnExport("Trello", err), report
+	}
+
+	lists := map[string]string{}
+	closedLists := map[string]bool{}
+	for _, list := range board.Lists {
+		lists[list.ID] = list.Name
+		closedLists[list.ID] = list.Closed
+	}
+	comments := map[string][]appComment{}
+	for _, action := range board.Actions {
+		if action.Type == "commentCard" {
+			comments[action.Data.Card.ID] = append(comments[action.Data.Card.ID], appComment{
+				Author: action.MemberCreator.FullName,
+				Date:   action.Date,
+				Text:   action.Data.Text,
+			})
+		}
+	}
+	// Exports list checklists and their items in no particular order; pos
+	// is their place on the card.
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	checklists := map[string][]int{}
+	for i, checklist := range board.Checklists {
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], i)
+	}
+
+	var rows []Row
+	var problems []Problem
+	line := 0
+	for _, card := range board.Cards {
+		line++
+		switch {
+		case card.Closed:
+			report.Skipped["archived card"]++
+			continue
+		case closedLists[card.IDList]:
+			report.Skipped["card in an archived list"]++
+			continue
+		}
+
+		row := Row{Line: line, Title: card.Name, Description: card.Desc, Status: card.DueComplete, Project: lists[card.IDList]}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			if name = appLabel(name); name != "" {
+				row.Labels = append(row.Labels, name)
+			}
+		}
+		row.Description = appendComments(row.Description, comments[card.ID])
+		report.Comments += len(comments[card.ID])
+
+		due, err := ParseDate(card.Due, source.Location)
+		if err != nil {
+			problems = append(problems, Problem{Line: line, Field: "due_date", Error: err.Error()})
+		} else {
+			row.DueDate = due
+			rows = append(rows, row)
+		}
+
+		parent := line
+		for _, i := range checklists[card.ID] {
+			for _, item := range board.Checklists[i].CheckItems {
+				line++
+				subtask := Row{Line: line, ParentLine: parent, Title: item.Name, Status: item.State == "complete", Project: row.Project}
+				due, err := ParseDate(item.Due, source.Location)
+				if err != nil {
+					problems = append(problems, Problem{Line: line, Field: "due_date", Error: err.Error()})
+					continue
+				}
+				subtask.DueDate = due
+				rows = append(rows, subtask)
+			}
+		}
+	}
+
+	report.summarize(rows)
+	return rows, problems, report
+}
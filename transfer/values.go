@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"to_do_api/models"
+)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+const dateOnlyLayout = "2006-01-02"
+
+func setField(row *Row, field string, value interface{}, loc *time.Location) error {
+	switch field {
+	case "title":
+		row.Title = strings.TrimSpace(text(value))
+	case "description":
+		row.Description = text(value)
+	case "recurrence":
+		row.Recurrence = strings.TrimSpace(text(value))
+	case "project":
+		row.Project = strings.TrimSpace(text(value))
+	case "status":
+		status, err := parseStatus(value)
+		if err != nil {
+			return err
+		}
+		row.Status = status
+	case "due_date":
+		dueDate, err := ParseDate(text(value), loc)
+		if err != nil {
+			return err
+		}
+		row.DueDate = dueDate
+	case "priority":
+		priority, err := parsePriority(text(value))
+		if err != nil {
+			return err
+		}
+		row.Priority = priority
+	case "labels":
+		labels, err := parseLabels(value)
+		if err != nil {
+			return err
+		}
+		row.Labels = labels
+	}
+	return nil
+}
+
+// text renders a scalar import value as a string; nil is empty.
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func parseStatus(value interface{}) (bool, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(text(value))) {
+	case "", "false", "no", "n", "0", "open", "todo", "pending", "incomplete":
+		return false, nil
+	case "true", "yes", "y", "1", "x", "done", "completed", "complete":
+		return true, nil
+	}
+	return false, fmt.Errorf("cannot read %q as done or not done", text(value))
+}
+
+// ParseDate reads an RFC 3339 time, a date and time without a zone (in loc),
+// or a bare date, which is due at the end of that day in loc. An empty
+// string is no date.
+func ParseDate(value string, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	if day, err := time.ParseInLocation(dateOnlyLayout, value, loc); err == nil {
+		t := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, loc).UTC()
+		return &t, nil
+	}
+	return nil, fmt.Errorf("cannot read %q as a date; use RFC 3339 or YYYY-MM-DD", value)
+}
+
+func parsePriority(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return models.PriorityNone, nil
+	}
+	if priority, ok := models.PriorityNames[value]; ok {
+		return priority, nil
+	}
+	if priority, err := strconv.Atoi(value); err == nil {
+		return priority, nil
+	}
+	return 0, fmt.Errorf("cannot read %q as a priority; use 0-3 or none, low, medium, high", value)
+}
+
+// parseLabels accepts a JSON array of strings or a string of labels separated
+// by commas or semicolons.
+func parseLabels(value interface{}) ([]string, error) {
+	if list, ok := value.([]interface{}); ok {
+		labels := make([]string, 0, len(list))
+		for _, item := range list {
+			label, ok := item.(string)
+			if !ok {
+				return nil, errors.New("labels must be strings")
+			}
+			labels = append(labels, label)
+		}
+		return labels, nil
+	}
+
+	var labels []string
+	for _, label := range strings.FieldsFunc(text(value), func(r rune) bool { return r == ',' || r == ';' }) {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"to_do_api/models"
+	"to_do_api/outbound"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultInterval    = 5 * time.Second
+	defaultLease       = time.Minute
+	defaultMaxAttempts = 8
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 6 * time.Hour
+	batchSize          = 50
+	maxErrorLength     = 500
+)
+
+// Dispatcher drains the webhook outbox. Deliveries are claimed by pushing
+// NextAttemptAt forward with a conditional UPDATE, so several API replicas
+// can run a dispatcher against the same database without sending twice.
+// Endpoints are user-supplied, so the default Client only reaches public
+// addresses and does not follow redirects.
+type Dispatcher struct {
+	DB          *gorm.DB
+	Client      *http.Client
+	Interval    time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+	Now         func() time.Time
+}
+
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		Client:      outbound.NewClient(10 * time.Second),
+		Interval:    defaultInterval,
+		Lease:       defaultLease,
+		MaxAttempts: defaultMaxAttempts,
+		Now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run delivers pending webhooks every Interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			log.Println("Webhook dispatcher:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce attempts every delivery that is currently due and returns how many
+// succeeded.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	now := d.Now()
+
+	var due []models.WebhookDelivery
+	err := d.DB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		result := d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryStatusPending, now).
+			Updates(map[string]interface{}{
+				"next_attempt_at": now.Add(d.Lease),
+				"attempts":        gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return delivered, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		delivery.Attempts++
+
+		status, err := d.send(ctx, delivery)
+		if err := d.record(ctx, delivery, status, err); err != nil {
+			return delivered, err
+		}
+		if err == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	var hook models.Webhook
+	if err := d.DB.WithContext(ctx).First(&hook, "id = ?", delivery.WebhookID).Error; err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := d.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", formatTimestamp(timestamp))
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record stores the outcome of an attempt. It is written even if ctx has
+// been cancelled meanwhile, so that a delivery that succeeded just as
+// shutdown began is not sent again after a restart.
+func (d *Dispatcher) record(ctx context.Context, delivery models.WebhookDelivery, status int, sendErr error) error {
+	now := d.Now()
+	updates := map[string]interface{}{
+		"response_status": status,
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.DeliveryStatusDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	case delivery.Attempts >= d.MaxAttempts:
+		updates["status"] = models.DeliveryStatusFailed
+		updates["last_error"] = truncate(sendErr.Error())
+	default:
+		updates["next_attempt_at"] = now.Add(Backoff(delivery.Attempts))
+		updates["last_error"] = truncate(sendErr.Error())
+	}
+
+	return d.DB.WithContext(context.WithoutCancel(ctx)).Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+}
+
+// Backoff returns the wait before the next attempt after the given number of
+// failed attempts: 30s, 1m, 2m, 4m... capped at six hours.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := baseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
+
+func formatTimestamp(timestamp int64) string {
+	return strconv.FormatInt(timestamp, 10)
+}
+
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	"to_do_api/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Envelope is the JSON body POSTed to webhook endpoints.
+type Envelope struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Enqueue writes one outbox row per active webhook of userID subscribed to
+// eventType. Call it with the transaction that makes the change so the event
+// is recorded if and only if the change commits.
+func Enqueue(tx *gorm.DB, userID uuid.UUID, eventType string, data interface{}) error {
+	var hooks []models.Webhook
+	if err := tx.Where("user_id = ? AND active = ?", userID, true).Find(&hooks).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, hook := range hooks {
+		if !hook.Subscribed(eventType) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     hook.ID,
+			EventType:     eventType,
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+		}
+		payload, err := json.Marshal(Envelope{
+			ID:        delivery.ID,
+			Event:     eventType,
+			CreatedAt: now,
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		delivery.Payload = string(payload)
+
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver returns a new pending delivery of original's event. Its
+// envelope carries the new delivery's ID, matching X-Webhook-Delivery, so
+// receivers that drop IDs they have seen still accept it.
+func Redeliver(original models.WebhookDelivery) (models.WebhookDelivery, error) {
+	var data json.RawMessage
+	envelope := Envelope{Data: &data}
+	if err := json.Unmarshal([]byte(original.Payload), &envelope); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery := models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     original.WebhookID,
+		EventType:     original.EventType,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	envelope.ID = delivery.ID
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery.Payload = string(payload)
+	return delivery, nil
+}
+
+// Sign returns the value of the X-Webhook-Signature header: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret. Receivers should
+// recompute it and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(formatTimestamp(timestamp)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}